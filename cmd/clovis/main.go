@@ -1,110 +1,249 @@
 package main
 
 import (
-	"clovis/codegen"
-	"clovis/lexer"
-	"clovis/parser"
-	"clovis/semantics"
+	"clovis/compiler"
+	"clovis/diagnostics"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 func main() {
-	errOccured := false
+	opts, err := parseArgs(os.Args[1:])
+	if err == errHelp {
+		fmt.Print(usage)
+		return
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n%v", err, usage)
+		os.Exit(2)
+	}
 
-	args := os.Args[1:]
+	os.Exit(execute(opts))
+}
 
-	if len(args) < 1 {
-		fmt.Fprintln(os.Stderr, "Expected source file.")
-		os.Exit(1)
+// Runs the command and returns the exit status.
+// Errors in the program and failures of the build, such as unwritable files or a failing assembler,
+// are written to stderr in the requested format once the command is done.
+func execute(opts options) (status int) {
+	result := &compiler.Result{}
+	failures := []*diagnostics.Diagnostic{}
+	defer func() {
+		if err := writeDiagnostics(opts.diagnosticsFormat, result, failures); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			status = 1
+		}
+	}()
+
+	fail := func(code diagnostics.Code, err error) int {
+		failures = append(failures, asDiagnostic(code, err))
+		return 1
 	}
-	
-	// -- INPUT
-	input, err := os.ReadFile(args[0])
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
+
+	name := strings.TrimSuffix(filepath.Base(opts.source), filepath.Ext(opts.source))
+	if opts.library {
+		name = compiler.LibraryName(opts.source)
 	}
+	output := outputPath(opts, name)
 
-	// -- LEXING
-	lexer := lexer.NewLexer(string(input))
-	err = lexer.Lex()
-	if err != nil {
-		for _, err = range lexer.Errors {
-			fmt.Fprintln(os.Stderr, err.Error())
+	compilerOpts := compiler.Options{
+		Main: opts.source,
+		Output: output,
+		LinkInputs: opts.linkInputs,
+		DebugAlloc: opts.debugAlloc,
+		KeepTemps: opts.keepTemps,
+		DebugLogs: opts.dumpDir != "",
+	}
+
+	if opts.library {
+		compilerOpts.Target = compiler.Library
+	} else if opts.libc {
+		compilerOpts.Target = compiler.LibcExecutable
+	}
+
+	switch opts.emit {
+	case emitTokens:
+		compilerOpts.Emit = compiler.EmitTokens
+	case emitAST:
+		compilerOpts.Emit = compiler.EmitAST
+	case emitASM:
+		compilerOpts.Emit = compiler.EmitASM
+	case emitObj:
+		compilerOpts.Emit = compiler.EmitObject
+	}
+
+	// check stops once the program was checked.
+	if opts.command == "check" {
+		compilerOpts.Emit = compiler.EmitAST
+	}
+
+	// run only keeps the executable when -o was given.
+	if opts.command == "run" && opts.output == "" {
+		runDir, err := os.MkdirTemp("", "clovis-run-")
+		if err != nil {
+			return fail(diagnostics.OutputFailed, err)
 		}
+		defer os.RemoveAll(runDir)
+		compilerOpts.Output = filepath.Join(runDir, name)
 	}
-	
-	// -- PARSING
-	parser := parser.NewParser(lexer.Tokens)
-	err = parser.Parse()
+
+	result, err := compiler.Compile(context.Background(), compilerOpts)
 	if err != nil {
-		errOccured = true
-		for _, err = range parser.Errors {
-			fmt.Fprintln(os.Stderr, err.Error())
+		fail(diagnostics.OutputFailed, err)
+	}
+
+	if result.TempDir != "" {
+		fmt.Fprintf(os.Stderr, "Intermediate files kept in %v\n", result.TempDir)
+	}
+
+	if opts.dumpDir != "" {
+		if err := writeDump(opts.dumpDir, "plog.txt", result.ParserLog); err != nil {
+			return fail(diagnostics.OutputFailed, err)
+		}
+
+		if err := writeDump(opts.dumpDir, "slog.txt", result.SemanticsLog); err != nil {
+			return fail(diagnostics.OutputFailed, err)
 		}
 	}
 
-	parserLogFile, err := os.Create("plog.txt")
-	defer parserLogFile.Close()
+	// Tokens are written even when parsing failed since they help to find out why.
+	if opts.emit == emitTokens {
+		b := strings.Builder{}
+		for _, module := range result.Modules {
+			fmt.Fprintf(&b, "// %v\n", module.Path)
+			for _, token := range module.Tokens {
+				fmt.Fprintf(&b, "%v\n", token)
+			}
+		}
 
-	for _, stmt := range parser.Stmts {
-		parserLogFile.WriteString(fmt.Sprintf("%v\n\n", stmt.Print(0)))
+		if err := writeOutput(output, b.String()); err != nil {
+			return fail(diagnostics.OutputFailed, err)
+		}
 	}
 
-	// -- SEMANTIC ANALYSIS
-	semantics := semantics.NewSemanticChecker()
+	if err != nil || result.Failed() {
+		return 1
+	}
 
-	semanticsLogFile, err := os.Create("slog.txt")
-	defer semanticsLogFile.Close()
+	// Writes text output and returns the exit status.
+	writeOrFail := func(path string, content string) int {
+		if err := writeOutput(path, content); err != nil {
+			return fail(diagnostics.OutputFailed, err)
+		}
 
-	for _, stmt := range parser.Stmts {
-		err := stmt.Semantics(semantics)
-		if err != nil {
-			errOccured = true
-			semanticsLogFile.WriteString(err.Error())
-			fmt.Println(err.Error())
-		} else {
-			log := fmt.Sprintf(
-				"-----------------------------------------------------\n\n%v\n\n%v\n-----------------------------------------------------\n", 
-				stmt.Print(0), 
-				semantics,
-			)
-			semanticsLogFile.WriteString(log)
-			// fmt.Println(log)
+		return 0
+	}
+
+	switch {
+	case opts.command == "check" || opts.emit == emitTokens:
+		return 0
+	case opts.emit == emitAST:
+		b := strings.Builder{}
+		for _, module := range result.Modules {
+			fmt.Fprintf(&b, "// %v\n", module.Path)
+			for _, stmt := range module.Stmts {
+				fmt.Fprintf(&b, "%v\n\n", stmt.Print(0))
+			}
 		}
+		return writeOrFail(output, b.String())
+	case opts.emit == emitASM:
+		return writeOrFail(output, result.ASM)
+	case opts.library:
+		// The header is placed next to the object.
+		return writeOrFail(filepath.Join(filepath.Dir(output), name + ".h"), result.Header)
+	case opts.command != "run":
+		return 0
 	}
 
-	if errOccured {
-		os.Exit(1)
+	// -- RUN
+	exeFile, err := filepath.Abs(compilerOpts.Output)
+	if err != nil {
+		return fail(diagnostics.RunFailed, err)
 	}
 
-	// -- CODE GENERATION
-	emitter := codegen.NewEmitter()
-	for _, stmt := range parser.Stmts {
-		stmt.EmitCode(emitter)
+	runCmd := exec.Command(exeFile, opts.runArgs...)
+	runCmd.Stdin = os.Stdin
+	runCmd.Stdout = os.Stdout
+	runCmd.Stderr = os.Stderr
+
+	var exitErr *exec.ExitError
+	if err := runCmd.Run(); errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	} else if err != nil {
+		return fail(diagnostics.RunFailed, err)
 	}
-	emitter.End()
 
-	// -- COMPILE & LINK
-	asmFile, err := os.Create("out.asm")
-	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
+	return 0
+}
+
+// Returns err as a diagnostic. Errors that are no diagnostic are reported with code.
+func asDiagnostic(code diagnostics.Code, err error) *diagnostics.Diagnostic {
+	if d, isDiagnostic := err.(*diagnostics.Diagnostic); isDiagnostic {
+		return d
 	}
-	defer asmFile.Close()
-	asmFile.WriteString(emitter.Code)
 
-	nasmCmd := exec.Command("nasm", "-f", "elf64", "out.asm", "-o", "out.o")
-	if output, err := nasmCmd.Output(); err != nil {
-		fmt.Printf("Failed during assembling:\n\t%v\n", string(output))
-		os.Exit(1)
+	return diagnostics.New(code, diagnostics.Span{}, err.Error())
+}
+
+// Writes the diagnostics of the program followed by the failures of the build to stderr in
+// the requested format. Machine readable formats are written even without diagnostics so
+// tools always receive a document.
+func writeDiagnostics(format string, result *compiler.Result, failures []*diagnostics.Diagnostic) error {
+	all := append(append([]*diagnostics.Diagnostic{}, result.Diagnostics...), failures...)
+
+	switch format {
+	case "json":
+		return diagnostics.WriteJSON(os.Stderr, all)
+	case "sarif":
+		return diagnostics.WriteSARIF(os.Stderr, all)
 	}
 
-	ldCmd := exec.Command("ld", "out.o", "-o", "out")
-	if output, err := ldCmd.Output(); err != nil {
-		fmt.Printf("Failed during linking:\n\t%v\n", string(output))
-		os.Exit(1)
+	renderer := diagnostics.Renderer{
+		W: os.Stderr,
+		Colour: diagnostics.IsColourTerminal(os.Stderr),
+		Sources: result.Sources,
 	}
+	renderer.RenderAll(all)
+	return nil
+}
+
+// Returns the path of the build output. Without -o it is named after the source file
+// and placed in the current directory, tokens and ast go to stdout.
+func outputPath(opts options, name string) string {
+	if opts.output != "" {
+		return opts.output
+	}
+
+	switch opts.emit {
+	case emitTokens, emitAST:
+		return "-"
+	case emitASM:
+		return name + ".asm"
+	case emitObj:
+		return name + ".o"
+	}
+
+	return name
+}
+
+// Writes text output to path, or to stdout when path is '-'.
+func writeOutput(path string, content string) error {
+	if path == "-" {
+		_, err := os.Stdout.WriteString(content)
+		return err
+	}
+
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// Writes a debug log to the dump directory.
+func writeDump(dir string, file string, content string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, file), []byte(content), 0644)
 }
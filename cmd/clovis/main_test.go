@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs the command line args and returns the exit status and what was written to stdout and stderr.
+func run(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	opts, err := parseArgs(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	dir := t.TempDir()
+	files := []*os.File{}
+	for _, name := range []string{ "stdout", "stderr" } {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+	os.Stdout, os.Stderr = files[0], files[1]
+
+	status := execute(opts)
+
+	out, _ := os.ReadFile(files[0].Name())
+	errOut, _ := os.ReadFile(files[1].Name())
+	return status, string(out), string(errOut)
+}
+
+// Writes a valid program to a temporary directory and returns its path.
+func source(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "main.clv")
+	if err := os.WriteFile(path, []byte("uint64 x = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// Tool failures are written to stderr in the requested format instead of to stdout.
+func TestAssemblerFailureFormats(t *testing.T) {
+	t.Setenv("PATH", "") // No assembler can be found
+	src := source(t)
+	out := filepath.Join(t.TempDir(), "main")
+
+	status, stdout, stderr := run(t, "build", "--diagnostics-format=json", src, "-o", out)
+	if status != 1 || stdout != "" {
+		t.Fatalf("Expected status 1 and no stdout but received %v and %q", status, stdout)
+	}
+
+	var diagnostics []struct{ Code string; Message string }
+	if err := json.Unmarshal([]byte(stderr), &diagnostics); err != nil {
+		t.Fatalf("Expected a JSON document on stderr but received %q: %v", stderr, err)
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Code != "B001" || !strings.Contains(diagnostics[0].Message, "assembling") {
+		t.Errorf("Expected an assembler failure but received %+v", diagnostics)
+	}
+
+	_, _, stderr = run(t, "build", "--diagnostics-format=sarif", src, "-o", out)
+	if !strings.Contains(stderr, `"ruleId": "B001"`) {
+		t.Errorf("Expected a SARIF result for the assembler failure but received %v", stderr)
+	}
+
+	_, _, stderr = run(t, "build", src, "-o", out)
+	if !strings.HasPrefix(stderr, "error[B001]: Failed during assembling") {
+		t.Errorf("Expected a rendered assembler failure but received %q", stderr)
+	}
+}
+
+func TestOutputFailure(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "missing", "main.asm")
+	status, stdout, stderr := run(t, "build", "--emit=asm", "--diagnostics-format=json", source(t), "-o", out)
+	if status != 1 || stdout != "" || !strings.Contains(stderr, `"code": "B003"`) {
+		t.Errorf("Expected an output failure on stderr but received %v, %q and %q", status, stdout, stderr)
+	}
+}
+
+func TestMachineFormatsWithoutDiagnostics(t *testing.T) {
+	status, _, stderr := run(t, "check", "--diagnostics-format=json", source(t))
+	if status != 0 || strings.TrimSpace(stderr) != "[]" {
+		t.Errorf("Expected an empty JSON array but received %v and %q", status, stderr)
+	}
+}
+
+func TestProgramErrorsInJSON(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "main.clv")
+	if err := os.WriteFile(src, []byte("uint64 x = 1;\nuint64 x = 2;\nuint64 z = y;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	status, stdout, stderr := run(t, "check", "--diagnostics-format=json", src)
+
+	var diagnostics []struct{ Code string; File string }
+	if err := json.Unmarshal([]byte(stderr), &diagnostics); err != nil {
+		t.Fatalf("Expected a JSON document on stderr but received %q: %v", stderr, err)
+	}
+	if status != 1 || stdout != "" || len(diagnostics) != 2 || diagnostics[0].Code != "S011" || diagnostics[1].File != src {
+		t.Errorf("Expected the semantic errors of %v but received %v, %q and %+v", src, status, stdout, diagnostics)
+	}
+}
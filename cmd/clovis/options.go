@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const usage = `Usage: clovis <command> [options] <file> [objects...]
+
+Commands:
+  build    Compile the program
+  run      Compile the program and run it, arguments after -- are passed to it
+  check    Check the program for errors without producing any output
+
+Options:
+  -o <path>          Write the output to path, '-' writes tokens, ast and asm to stdout
+  --emit=<kind>      What build produces: tokens, ast, asm, obj or exe (default exe)
+  -c                 Build a library object with an exported entry and a C header
+  --libc             Link against the C library
+  --debug-alloc      Check heap allocations at runtime
+  --keep-temps       Keep the intermediate files in their temporary directory
+  --dump-dir=<dir>   Write the parser and semantics logs to dir
+  --diagnostics-format=<format>
+                     How errors are written to stderr: human, json or sarif (default human)
+  -h, --help         Show this help
+`
+
+// Returned by parseArgs when the usage was asked for.
+var errHelp = errors.New("help requested")
+
+// What a build produces.
+type emitKind string
+const (
+	emitTokens emitKind = "tokens"
+	emitAST    emitKind = "ast"
+	emitASM    emitKind = "asm"
+	emitObj    emitKind = "obj"
+	emitExe    emitKind = "exe"
+)
+
+type options struct {
+	command    string
+	source     string
+	// Object files and static libraries passed on to the linker.
+	linkInputs []string
+	// The arguments run passes to the program.
+	runArgs    []string
+	// The output path. Empty picks a path from the source file's name.
+	output     string
+	emit       emitKind
+	keepTemps  bool
+	// The directory of the parser and semantics logs. Empty writes no logs.
+	dumpDir    string
+	// human, json or sarif.
+	diagnosticsFormat string
+	debugAlloc bool
+	libc       bool
+	library    bool
+}
+
+// Parses the command line arguments following the program name.
+func parseArgs(args []string) (options, error) {
+	opts := options{ diagnosticsFormat: "human" }
+
+	if len(args) == 0 {
+		return opts, fmt.Errorf("Expected a command.")
+	}
+
+	opts.command = args[0]
+	switch opts.command {
+	case "-h", "--help", "help":
+		return opts, errHelp
+	case "build", "run", "check":
+	default:
+		return opts, fmt.Errorf("Unknown command '%v'.", opts.command)
+	}
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "-h" || arg == "--help":
+			return opts, errHelp
+		case arg == "--":
+			opts.runArgs = args[i + 1:]
+			i = len(args)
+		case arg == "-o":
+			if i + 1 == len(args) {
+				return opts, fmt.Errorf("Expected a path after -o.")
+			}
+			i++
+			opts.output = args[i]
+		case strings.HasPrefix(arg, "--emit="):
+			opts.emit = emitKind(strings.TrimPrefix(arg, "--emit="))
+			switch opts.emit {
+			case emitTokens, emitAST, emitASM, emitObj, emitExe:
+			default:
+				return opts, fmt.Errorf("Unknown emit kind '%v'.", opts.emit)
+			}
+		case strings.HasPrefix(arg, "--diagnostics-format="):
+			opts.diagnosticsFormat = strings.TrimPrefix(arg, "--diagnostics-format=")
+			switch opts.diagnosticsFormat {
+			case "human", "json", "sarif":
+			default:
+				return opts, fmt.Errorf("Unknown diagnostics format '%v'.", opts.diagnosticsFormat)
+			}
+		case strings.HasPrefix(arg, "--dump-dir="):
+			opts.dumpDir = strings.TrimPrefix(arg, "--dump-dir=")
+		case arg == "--keep-temps":
+			opts.keepTemps = true
+		case arg == "--debug-alloc":
+			opts.debugAlloc = true
+		case arg == "--libc":
+			opts.libc = true
+		case arg == "-c":
+			opts.library = true
+		case strings.HasSuffix(arg, ".o") || strings.HasSuffix(arg, ".a"):
+			opts.linkInputs = append(opts.linkInputs, arg)
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return opts, fmt.Errorf("Unknown option '%v'.", arg)
+		case opts.source != "":
+			return opts, fmt.Errorf("Expected a single source file but received '%v' and '%v'.", opts.source, arg)
+		default:
+			opts.source = arg
+		}
+	}
+
+	if opts.source == "" {
+		return opts, fmt.Errorf("Expected source file.")
+	}
+
+	return opts, opts.validate()
+}
+
+// Rejects combinations of options that have no meaning.
+func (opts *options) validate() error {
+	if opts.command == "check" && (opts.output != "" || opts.emit != "") {
+		return fmt.Errorf("check produces no output, -o and --emit cannot be used.")
+	}
+
+	if opts.emit == "" {
+		opts.emit = emitExe
+		if opts.library {
+			opts.emit = emitObj
+		}
+	}
+
+	if opts.command == "run" && (opts.emit != emitExe || opts.library) {
+		return fmt.Errorf("run only builds executables.")
+	}
+
+	if opts.library && opts.emit == emitExe {
+		return fmt.Errorf("Libraries cannot be emitted as executables.")
+	}
+
+	if len(opts.linkInputs) != 0 && opts.emit != emitExe {
+		return fmt.Errorf("Objects can only be linked into executables.")
+	}
+
+	if opts.runArgs != nil && opts.command != "run" {
+		return fmt.Errorf("Only run passes arguments to the program.")
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseLinkInputs(t *testing.T) {
+	opts, err := parseArgs([]string{ "build", "--libc", "main.clv", "util.o", "libm.a" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.source != "main.clv" || !opts.libc || !slices.Equal(opts.linkInputs, []string{ "util.o", "libm.a" }) {
+		t.Errorf("Expected main.clv linked with util.o and libm.a against libc but received %+v", opts)
+	}
+}
+
+func TestLinkInputsNeedExecutables(t *testing.T) {
+	for _, args := range [][]string{
+		{ "build", "--emit=asm", "main.clv", "util.o" },
+		{ "build", "-c", "main.clv", "util.o" },
+	} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("Expected %v to be rejected", args)
+		}
+	}
+}
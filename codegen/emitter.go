@@ -6,20 +6,104 @@ import (
 	"fmt"
 )
 
+// What the generated code is linked into.
+type EmitMode int
+const (
+	// A standalone executable entered at _start.
+	Executable EmitMode = iota
+	// An executable linked against the C library and entered at main.
+	LibcExecutable
+	// A relocatable object exposing the program as a callable entry function.
+	Library
+)
+
 // Generates x86_64 assembly code.
 type Emitter struct {
 	Code 	   string
+	// Read only data placed in the .rodata section by End.
+	ROData     string
+	// Initialised writable data placed in the .data section by End.
+	Data       string
+	// Zero initialised data placed in the .bss section by End.
+	BSS        string
 	LabelCount int
+	// Validates frees and reports leaked allocations at exit.
+	DebugAlloc bool
+	Mode       EmitMode
+	// Set when the allocator routines have to be included by End.
+	usesAllocator bool
+	// Set when the read_uint routine has to be included by End.
+	usesReadUint  bool
+	// Set when the thread routines have to be included by End.
+	usesThreads   bool
+	// Set when the process values are read, so End has the entry save argc and argv.
+	usesArgs      bool
+	// Saves argc and argv at the entry. Inserted into Code at argsAt by End when they are used.
+	saveArgs      string
+	argsAt        int
+	// The labels of the messages written by Fail so far.
+	failMessages  map[string]bool
 }
 
+// Saves argc and argv passed in the registers of the first two arguments of a System V call.
+const saveArgRegisters = "mov QWORD [rel __clovis_argc], rdi\nmov QWORD [rel __clovis_argv], rsi\n"
+
 func NewEmitter() *Emitter {
 	b := strings.Builder{}
 	b.WriteString("section .text\n")
 	b.WriteString("global _start\n\n")
 	b.WriteString("_start:\n")
+	argsAt := b.Len()
+	b.WriteString("mov rbp, rsp\n\n")
+	return &Emitter{
+		Code: b.String(),
+		saveArgs: "mov rax, QWORD [rsp]\n" +
+			"mov QWORD [rel __clovis_argc], rax\n" +
+			"lea rax, [rsp + 8]\n" +
+			"mov QWORD [rel __clovis_argv], rax\n",
+		argsAt: argsAt,
+	}
+}
+
+// Creates an emitter for programs linked against the C library.
+// The C runtime calls main, which saves argc and argv when the process values are used,
+// and the program exits through the C library's exit so buffered output is flushed.
+func NewLibcEmitter() *Emitter {
+	b := strings.Builder{}
+	b.WriteString("section .text\n")
+	b.WriteString("global main\n")
+	b.WriteString("extern exit\n\n")
+	b.WriteString("main:\n")
+	argsAt := b.Len()
 	b.WriteString("mov rbp, rsp\n\n")
 	return &Emitter{
 		Code: b.String(),
+		Mode: LibcExecutable,
+		saveArgs: saveArgRegisters,
+		argsAt: argsAt,
+	}
+}
+
+// Creates an emitter for a relocatable object exposing the program as the function entry.
+// The entry takes argc and a null terminated argv like main and runs the program on the
+// caller's stack. Exiting returns the status to the caller instead of ending the process,
+// so do failed checks unless they happen on a thread started by spawn, which only ends that thread.
+func NewLibraryEmitter(entry string) *Emitter {
+	b := strings.Builder{}
+	b.WriteString("section .text\n")
+	fmt.Fprintf(&b, "global %v:function\n\n", entry)
+	fmt.Fprintf(&b, "%v:\n", entry)
+	b.WriteString("push rbp\n")
+	b.WriteString("push rbx\n")
+	argsAt := b.Len()
+	b.WriteString("mov rbp, rsp\n")
+	b.WriteString("mov QWORD [rel __clovis_entry_frame], rbp\n")
+	b.WriteString("mov BYTE [rel __clovis_thread_failed], 0\n\n")
+	return &Emitter{
+		Code: b.String(),
+		Mode: Library,
+		saveArgs: saveArgRegisters,
+		argsAt: argsAt,
 	}
 }
 
@@ -32,16 +116,205 @@ func (e *Emitter) WriteString(code string) {
 	e.Code += code
 }
 
-// Adds a exit syscall to the end of the code.
+// Adds the common exit path to the end of the code followed by the used runtime routines and data.
 func (e *Emitter) End() {
 	b := strings.Builder{}
 	b.WriteString("\n; Emitter.End()\n")
-	b.WriteString("mov rax, 60\n")
 	b.WriteString("mov rdi, 0\n")
-	b.WriteString("syscall\n")
+	fmt.Fprintf(&b, "%v:\n", exitLabel)
+	if e.usesAllocator && e.DebugAlloc {
+		b.WriteString(allocatorLeakCheck())
+	}
+	switch e.Mode {
+	case Executable:
+		b.WriteString("mov rax, 231\n") // exit_group ends every thread
+		b.WriteString("syscall\n")
+	case LibcExecutable:
+		b.WriteString("and rsp, -16\n")
+		b.WriteString("call exit wrt ..plt\n")
+	case Library:
+		b.WriteString(libraryReturn())
+	}
+
+	roData := e.ROData
+	if e.usesAllocator {
+		b.WriteString(allocatorRuntime(e.DebugAlloc, e.usesThreads, e.Mode == Library))
+		roData += allocatorROData(e.DebugAlloc)
+	}
+
+	if e.usesReadUint {
+		b.WriteString(readUintRuntime())
+	}
+
+	if e.usesThreads {
+		b.WriteString(threadRuntime())
+		roData += threadROData()
+	}
+
+	if roData != "" {
+		b.WriteString("\nsection .rodata\n")
+		b.WriteString(roData)
+	}
+
+	if e.Data != "" {
+		b.WriteString("\nsection .data\n")
+		b.WriteString(e.Data)
+	}
+
+	bss := e.BSS
+	if e.usesAllocator {
+		bss += allocatorBSS(e.DebugAlloc, e.usesThreads)
+	}
+	if e.Mode == Library {
+		bss += libraryBSS()
+	}
+	if e.usesArgs {
+		bss += "__clovis_argc: resq 1\n"
+		bss += "__clovis_argv: resq 1\n"
+	}
+
+	if bss != "" {
+		b.WriteString("\nsection .bss\n")
+		b.WriteString(bss)
+	}
+
+	if e.Mode != Executable {
+		// Marks the stack as non executable for the system linker.
+		b.WriteString("\nsection .note.GNU-stack noalloc noexec nowrite progbits\n")
+	}
+
+	if e.usesArgs {
+		e.Code = e.Code[:e.argsAt] + e.saveArgs + e.Code[e.argsAt:]
+	}
 	e.Code += b.String()
 }
 
+// Marks the allocator routines as used so End includes them.
+func (e *Emitter) UseAllocator() {
+	e.usesAllocator = true
+}
+
+// Marks the read_uint routine as used so End includes it.
+func (e *Emitter) UseReadUint() {
+	e.usesReadUint = true
+}
+
+// Marks the thread routines as used so End includes them.
+// Thread stacks are mapped by the allocator's __clovis_mmap, so the allocator is included as well
+// and takes a lock around its routines.
+func (e *Emitter) UseThreads() {
+	e.usesThreads = true
+	e.usesAllocator = true
+}
+
+// Loads argc into rax and the address of the first argument pointer into rbx.
+// Every entry saves both once they are used, so they can be read from any thread.
+func (e *Emitter) LoadArgs() {
+	e.usesArgs = true
+	e.WriteString("mov rax, QWORD [rel __clovis_argc]\n")
+	e.WriteString("mov rbx, QWORD [rel __clovis_argv]\n")
+}
+
+// Exits through the common exit path with the status held in rdi.
+func (e *Emitter) ExitRdi() {
+	fmt.Fprintf(e, "jmp %v\n", exitLabel)
+}
+
+func (e *Emitter) WriteROData(data string) {
+	e.ROData += data
+}
+
+func (e *Emitter) WriteData(data string) {
+	e.Data += data
+}
+
+func (e *Emitter) WriteBSS(data string) {
+	e.BSS += data
+}
+
+// Returns the definition of a label holding the given bytes, for the .rodata or .data section.
+func DataDefinition(label string, align int, bytes []byte) string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "align %v, db 0\n%v:\n", max(align, 1), label)
+
+	for start := 0; start < len(bytes); start += 16 {
+		line := []string{}
+		for _, value := range bytes[start:min(start + 16, len(bytes))] {
+			line = append(line, fmt.Sprintf("%#02x", value))
+		}
+		fmt.Fprintf(&b, "db %v\n", strings.Join(line, ", "))
+	}
+
+	return b.String()
+}
+
+// Loads a value of the given size in bytes from the memory operand src into rax.
+// Values narrower than 32 bits are zero extended so no stale bits are left in rax.
+func (e *Emitter) LoadRax(size int, src string) {
+	switch size {
+	case 1:
+		fmt.Fprintf(e, "movzx eax, BYTE %v\n", src)
+	case 2:
+		fmt.Fprintf(e, "movzx eax, WORD %v\n", src)
+	case 4:
+		fmt.Fprintf(e, "mov eax, DWORD %v\n", src)
+	default:
+		fmt.Fprintf(e, "mov rax, QWORD %v\n", src)
+	}
+}
+
+// Exits the program with the given status. Used by failed runtime checks.
+// Libraries return the status from their entry instead.
+func (e *Emitter) Exit(status int) {
+	if e.Mode == Library {
+		fmt.Fprintf(e, "mov rdi, %v\njmp %v\n", status, abortLabel)
+		return
+	}
+
+	fmt.Fprintf(e, "mov rax, 231\nmov rdi, %v\nsyscall\n", status)
+}
+
+// Writes msg to stderr and exits with status 1. Used by failed runtime checks.
+// The message is stored under label in the read only data once, however often it is used.
+func (e *Emitter) Fail(label string, msg string) {
+	if !e.failMessages[label] {
+		if e.failMessages == nil {
+			e.failMessages = map[string]bool{}
+		}
+		e.failMessages[label] = true
+
+		b := strings.Builder{}
+		writeMessage(&b, label, msg)
+		e.WriteROData(b.String())
+	}
+
+	fmt.Fprintf(e, "lea rsi, [rel %v]\n", label)
+	fmt.Fprintf(e, "mov rdx, %v_len\n", label)
+	e.WriteString("mov rax, 1\n")
+	e.WriteString("mov rdi, 2\n")
+	e.WriteString("syscall\n")
+	e.Exit(1)
+}
+
+// Fails with the allocator's out of memory message when the given conditional jump, e.g. jc, is taken.
+// Used to reject allocation sizes that overflow.
+func (e *Emitter) OutOfMemoryIf(jump string) {
+	e.UseAllocator()
+	fmt.Fprintf(e, "%v %v\n", jump, outOfMemoryLabel)
+}
+
+// Clears every bit of rax above its lowest size bytes.
+func (e *Emitter) ZeroExtend(size int) {
+	switch size {
+	case 1:
+		e.WriteString("movzx eax, al\n")
+	case 2:
+		e.WriteString("movzx eax, ax\n")
+	case 4:
+		e.WriteString("mov eax, eax\n")
+	}
+}
+
 func ASMBinaryOp(op lexer.Token) string {
 	switch op.Value {
 	case "+":
@@ -56,14 +329,15 @@ func ASMBinaryOp(op lexer.Token) string {
 		return "sete"
 	case "!=":
 		return "setne"
+	// Every type is unsigned, so orderings use the conditions of unsigned comparisons.
 	case "<":
-		return "setl"
+		return "setb"
 	case "<=":
-		return "setle"
+		return "setbe"
 	case ">":
-		return "setg"
+		return "seta"
 	case ">=":
-		return "setge"
+		return "setae"
 	}
 
 	return ""
@@ -74,3 +348,10 @@ func (e *Emitter) NextLabel() string {
 	return fmt.Sprintf(".L%02v", e.LabelCount)
 }
 
+// Returns a label that can be referenced from any section.
+// NASM does not attach labels starting with ..@ to the previous non-local label.
+func (e *Emitter) NextUnscopedLabel() string {
+	e.LabelCount++
+	return fmt.Sprintf("..@L%02v", e.LabelCount)
+}
+
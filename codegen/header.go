@@ -0,0 +1,189 @@
+package codegen
+
+import (
+	"clovis/semantics"
+	"fmt"
+	"strings"
+)
+
+// The C signature of the entry function of a library.
+func EntryFunction(entry string) semantics.Function {
+	return semantics.Function{
+		Name: entry,
+		Params: []semantics.Type{ semantics.Uint64{}, semantics.Ptr{ ValueType: semantics.Ptr{ ValueType: semantics.Uint8{} } } },
+		Return: semantics.Uint64{},
+	}
+}
+
+// Generates the C header of a program compiled as a library.
+// It declares the entry function and the C equivalents of the exported types
+// along with the types they are built from.
+func Header(guard string, entry semantics.Function, exports []semantics.Symbol) string {
+	h := header{ declared: map[string]bool{} }
+
+	fmt.Fprintf(&h.b, "#ifndef %v\n", guard)
+	fmt.Fprintf(&h.b, "#define %v\n\n", guard)
+	h.b.WriteString("#include <stdbool.h>\n")
+	h.b.WriteString("#include <stdint.h>\n\n")
+	h.b.WriteString("#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+
+	for _, export := range exports {
+		if name, isNamed := typeName(export.Type); isNamed && name == export.Ident {
+			h.declare(export.Type)
+			continue
+		}
+
+		// Aliases name an existing type.
+		h.declareDeps(export.Type)
+		fmt.Fprintf(&h.b, "typedef %v;\n\n", CDecl(export.Type, export.Ident))
+	}
+
+	params := []string{ "argc", "argv" }
+	for i, param := range entry.Params {
+		params[i] = CDecl(param, params[i])
+	}
+	h.b.WriteString("// Runs the program and returns its exit status.\n")
+	h.b.WriteString("// argv must be null terminated like the arguments of main.\n")
+	fmt.Fprintf(&h.b, "%v(%v);\n\n", CDecl(entry.Return, entry.Name), strings.Join(params, ", "))
+
+	h.b.WriteString("#ifdef __cplusplus\n}\n#endif\n\n")
+	fmt.Fprintf(&h.b, "#endif // %v\n", guard)
+
+	return h.b.String()
+}
+
+type header struct {
+	b        strings.Builder
+	// The names of the user defined types already declared.
+	declared map[string]bool
+}
+
+// Declares a user defined type after the types it is built from.
+func (h *header) declare(t semantics.Type) {
+	name, _ := typeName(t)
+	if h.declared[name] {
+		return
+	}
+	h.declared[name] = true
+
+	switch t := t.(type) {
+	case semantics.Named:
+		h.declareDeps(t.Underlying)
+		fmt.Fprintf(&h.b, "typedef %v;\n\n", CDecl(t.Underlying, t.Name))
+	case semantics.Enum:
+		fmt.Fprintf(&h.b, "typedef %v;\n", CDecl(t.Backing, t.Name))
+		for _, member := range t.Members {
+			fmt.Fprintf(&h.b, "#define %v_%v ((%v)%v)\n", t.Name, member.Ident, t.Name, member.Value)
+		}
+		h.b.WriteString("\n")
+	case semantics.Union:
+		for _, variant := range t.Variants {
+			for _, field := range variant.Fields {
+				h.declareDeps(field)
+			}
+		}
+
+		// The payload of each variant is a struct in a union following the tag,
+		// which matches the layout of semantics.NewUnion.
+		fmt.Fprintf(&h.b, "typedef struct {\n\t%v;\n", CDecl(t.TagType(), "tag"))
+		payload := strings.Builder{}
+		for _, variant := range t.Variants {
+			if len(variant.Fields) == 0 {
+				continue
+			}
+
+			fields := []string{}
+			for i, field := range variant.Fields {
+				fields = append(fields, CDecl(field, fmt.Sprintf("_%v", i)) + ";")
+			}
+			fmt.Fprintf(&payload, "\t\tstruct { %v } %v;\n", strings.Join(fields, " "), variant.Ident)
+		}
+		if payload.Len() != 0 {
+			fmt.Fprintf(&h.b, "\tunion {\n%v\t} payload;\n", payload.String())
+		}
+		fmt.Fprintf(&h.b, "} %v;\n", t.Name)
+
+		for tag, variant := range t.Variants {
+			fmt.Fprintf(&h.b, "#define %v_%v %v\n", t.Name, variant.Ident, tag)
+		}
+		h.b.WriteString("\n")
+	}
+}
+
+// Declares the user defined types a type is built from.
+func (h *header) declareDeps(t semantics.Type) {
+	switch t := t.(type) {
+	case semantics.Named, semantics.Enum, semantics.Union:
+		h.declare(t)
+	case semantics.Ptr:
+		h.declareDeps(t.ValueType)
+	case semantics.Array:
+		h.declareDeps(t.Base)
+	case semantics.Slice:
+		h.declareDeps(t.Base)
+	case semantics.Optional:
+		h.declareDeps(t.Base)
+	}
+}
+
+// Returns the name of a user defined type.
+func typeName(t semantics.Type) (string, bool) {
+	switch t := t.(type) {
+	case semantics.Named:
+		return t.Name, true
+	case semantics.Enum:
+		return t.Name, true
+	case semantics.Union:
+		return t.Name, true
+	}
+
+	return "", false
+}
+
+// Returns the C declaration of name with the given type, e.g. "uint8_t *name[4]".
+// An empty name results in the C type alone.
+func CDecl(t semantics.Type, name string) string {
+	switch t := t.(type) {
+	case semantics.Ptr:
+		// Pointers to arrays need parentheses to bind before the array's brackets.
+		if _, isArray := t.ValueType.(semantics.Array); isArray {
+			return CDecl(t.ValueType, "(*" + name + ")")
+		}
+
+		return CDecl(t.ValueType, "*" + name)
+	case semantics.Array:
+		return CDecl(t.Base, fmt.Sprintf("%v[%v]", name, t.Length))
+	}
+
+	cType := ""
+	switch t := t.(type) {
+	case semantics.Uint64:
+		cType = "uint64_t"
+	case semantics.Uint32:
+		cType = "uint32_t"
+	case semantics.Uint16:
+		cType = "uint16_t"
+	case semantics.Uint8:
+		cType = "uint8_t"
+	case semantics.Bool:
+		cType = "bool"
+	case semantics.Named:
+		cType = t.Name
+	case semantics.Enum:
+		cType = t.Name
+	case semantics.Union:
+		cType = t.Name
+	case semantics.Slice:
+		cType = fmt.Sprintf("struct { %v; uint64_t len; }", CDecl(semantics.Ptr{ ValueType: t.Base }, "ptr"))
+	case semantics.Optional:
+		cType = fmt.Sprintf("struct { bool has_value; %v; }", CDecl(t.Base, "value"))
+	default:
+		cType = "void"
+	}
+
+	if name == "" {
+		return cType
+	}
+
+	return cType + " " + name
+}
@@ -0,0 +1,71 @@
+package codegen_test
+
+import (
+	"clovis/codegen"
+	"clovis/semantics"
+	"strings"
+	"testing"
+)
+
+func TestCDecl(t *testing.T) {
+	bytes := semantics.Ptr{ ValueType: semantics.Uint8{} }
+	tests := []struct {
+		t    semantics.Type
+		name string
+		want string
+	}{
+		{ semantics.Uint64{}, "n", "uint64_t n" },
+		{ semantics.Bool{}, "", "bool" },
+		{ bytes, "s", "uint8_t *s" },
+		{ semantics.Array{ Base: semantics.Uint16{}, Length: 4 }, "a", "uint16_t a[4]" },
+		{ semantics.Ptr{ ValueType: semantics.Array{ Base: semantics.Uint8{}, Length: 2 } }, "p", "uint8_t (*p)[2]" },
+		{ semantics.Slice{ Base: semantics.Uint32{} }, "s", "struct { uint32_t *ptr; uint64_t len; } s" },
+		{ semantics.Optional{ Base: semantics.Uint8{} }, "o", "struct { bool has_value; uint8_t value; } o" },
+	}
+
+	for _, test := range tests {
+		if got := codegen.CDecl(test.t, test.name); got != test.want {
+			t.Errorf("Expected %q for %v but received %q", test.want, test.t.TypeID(), got)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	color := semantics.Enum{
+		Name: "Color",
+		Backing: semantics.Uint8{},
+		Members: []semantics.EnumMember{ { Ident: "Red", Value: 0 }, { Ident: "Blue", Value: 10 } },
+	}
+	shape := semantics.NewUnion("Shape", semantics.Decl{}, []semantics.UnionVariant{
+		{ Ident: "Empty" },
+		{ Ident: "Filled", Fields: []semantics.Type{ color, semantics.Uint32{} } },
+	})
+
+	h := codegen.Header("MAIN_H", codegen.EntryFunction("main_main"), []semantics.Symbol{
+		{ Ident: "Shape", Type: shape, IsType: true },
+		{ Ident: "Paint", Type: color, IsType: true },
+	})
+
+	// Color is declared before the union using it and only once.
+	want := []string{
+		"#ifndef MAIN_H",
+		"typedef uint8_t Color;\n#define Color_Red ((Color)0)\n#define Color_Blue ((Color)10)",
+		"typedef struct {\n\tuint8_t tag;\n\tunion {\n\t\tstruct { Color _0; uint32_t _1; } Filled;\n\t} payload;\n} Shape;",
+		"#define Shape_Empty 0\n#define Shape_Filled 1",
+		"typedef Color Paint;",
+		"uint64_t main_main(uint64_t argc, uint8_t **argv);",
+		"#endif // MAIN_H",
+	}
+	last := 0
+	for _, w := range want {
+		i := strings.Index(h[last:], w)
+		if i == -1 {
+			t.Fatalf("Expected %q after offset %v in the header:\n%v", w, last, h)
+		}
+		last += i + len(w)
+	}
+
+	if strings.Count(h, "typedef uint8_t Color;") != 1 {
+		t.Errorf("Expected Color to be declared once:\n%v", h)
+	}
+}
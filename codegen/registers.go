@@ -0,0 +1,86 @@
+package codegen
+
+// The general purpose registers and the names of their narrower parts.
+var registerParts = map[string][]string{
+	"rax": { "eax", "ax", "al", "ah" },
+	"rbx": { "ebx", "bx", "bl", "bh" },
+	"rcx": { "ecx", "cx", "cl", "ch" },
+	"rdx": { "edx", "dx", "dl", "dh" },
+	"rsi": { "esi", "si", "sil" },
+	"rdi": { "edi", "di", "dil" },
+	"rsp": { "esp", "sp", "spl" },
+	"rbp": { "ebp", "bp", "bpl" },
+	"r8": { "r8d", "r8w", "r8b" },
+	"r9": { "r9d", "r9w", "r9b" },
+	"r10": { "r10d", "r10w", "r10b" },
+	"r11": { "r11d", "r11w", "r11b" },
+	"r12": { "r12d", "r12w", "r12b" },
+	"r13": { "r13d", "r13w", "r13b" },
+	"r14": { "r14d", "r14w", "r14b" },
+	"r15": { "r15d", "r15w", "r15b" },
+}
+
+// Registers the emitted code only uses as scratch within a single statement.
+// Nothing is kept in them between statements so they can be clobbered freely.
+var ScratchRegisters = []string{ "rax", "rbx", "rcx", "rdx", "rsi", "rdi" }
+
+// Registers of the Linux syscall number followed by its up to six arguments.
+var SyscallRegisters = []string{ "rax", "rdi", "rsi", "rdx", "r10", "r8", "r9" }
+
+// Registers of the first six integer arguments of a System V call.
+// Further arguments are passed on the stack.
+var CallRegisters = []string{ "rdi", "rsi", "rdx", "rcx", "r8", "r9" }
+
+// Registers holding the stack frame. They must never be clobbered.
+var FrameRegisters = []string{ "rsp", "rbp" }
+
+// Returns the 64 bit register the given register name is a part of.
+func FullRegister(name string) (string, bool) {
+	for full, parts := range registerParts {
+		if name == full {
+			return full, true
+		}
+
+		for _, part := range parts {
+			if name == part {
+				return full, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Returns the part of a 64 bit register holding its lowest size bytes.
+func SizedRegister(reg string, size int) string {
+	switch size {
+	case 4:
+		return registerParts[reg][0]
+	case 2:
+		return registerParts[reg][1]
+	case 1:
+		return registerParts[reg][2]
+	}
+
+	return reg
+}
+
+func IsScratchRegister(reg string) bool {
+	for _, scratch := range ScratchRegisters {
+		if reg == scratch {
+			return true
+		}
+	}
+
+	return false
+}
+
+func IsFrameRegister(reg string) bool {
+	for _, frame := range FrameRegisters {
+		if reg == frame {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,479 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Allocations up to this size including their header are carved out of shared chunks of this size.
+// Larger ones get their own mapping which is unmapped again when freed.
+const allocChunkSize = 65536
+
+// The size of a memory page. The page holding the handle of a joined thread stays mapped, as does
+// the one holding the header of a freed mapped block in debug mode, so a second join or free can be reported.
+const pageSize = 4096
+
+// Header tags marking the state of a heap block.
+const (
+	tagChunk  = 0x0A110C
+	tagMapped = 0x0A11AA
+	tagFreed  = 0x0F4EED
+)
+
+// The label of the common exit path emitted by End. Expects the exit status in rdi.
+const exitLabel = "__clovis_exit"
+
+// The label of the routine ending a library program after a failed check. Expects the status in rdi.
+const abortLabel = "__clovis_abort"
+
+// The label of the allocator's failure when memory cannot be mapped or a size overflows.
+const outOfMemoryLabel = "__clovis_out_of_memory"
+
+// The size of the stack mapping of every thread started by spawn.
+const threadStackSize = 0x100000
+
+// CLONE_VM | CLONE_FS | CLONE_FILES | CLONE_SIGHAND | CLONE_THREAD | CLONE_SYSVSEM |
+// CLONE_PARENT_SETTID | CLONE_CHILD_CLEARTID
+const threadCloneFlags = 0x3D0F00
+
+// Returns the allocator routines.
+// Every block starts with a 16 byte header holding the payload size and a tag.
+// Freed chunk blocks are kept in a first fit free list linked through their first payload word.
+// In debug mode frees are validated and live allocations are counted for the leak check at exit.
+// Freed mapped blocks then keep their header page, marked as freed, to catch double frees.
+// With threads alloc and free hold a spin lock while they run.
+func allocatorRuntime(debug bool, threads bool, library bool) string {
+	b := strings.Builder{}
+
+	b.WriteString("\n; Allocator runtime\n")
+
+	// __clovis_alloc: allocates rdi bytes and returns a pointer to zeroed memory in rax.
+	writeHeapRoutine(&b, "__clovis_alloc", threads)
+	b.WriteString("add rdi, 15\n")
+	fmt.Fprintf(&b, "jc %v\n", outOfMemoryLabel) // Sizes close to 2^64 wrap when rounded up
+	b.WriteString("and rdi, -16\n")
+	b.WriteString("cmp rdi, 16\n") // Freed blocks need room for the free list link
+	b.WriteString("jae .search_start\n")
+	b.WriteString("mov rdi, 16\n")
+	b.WriteString(".search_start:\n")
+	b.WriteString("lea rcx, [rel __clovis_free_list]\n") // rcx holds the address of the link to the current block
+	b.WriteString(".search:\n")
+	b.WriteString("mov rax, QWORD [rcx]\n")
+	b.WriteString("test rax, rax\n")
+	b.WriteString("jz .fresh\n")
+	b.WriteString("cmp QWORD [rax], rdi\n")
+	b.WriteString("jae .reuse\n")
+	b.WriteString("lea rcx, [rax + 16]\n")
+	b.WriteString("jmp .search\n")
+	b.WriteString(".reuse:\n")
+	b.WriteString("mov rdx, QWORD [rax + 16]\n")
+	b.WriteString("mov QWORD [rcx], rdx\n")
+	b.WriteString("mov rdi, QWORD [rax]\n")
+	b.WriteString("jmp .chunk_block\n")
+	b.WriteString(".fresh:\n")
+	b.WriteString("mov rdx, rdi\n") // rdx holds the block size
+	b.WriteString("add rdx, 16\n")
+	fmt.Fprintf(&b, "jc %v\n", outOfMemoryLabel)
+	fmt.Fprintf(&b, "cmp rdx, %v\n", allocChunkSize)
+	b.WriteString("ja .mapped_block\n")
+	b.WriteString("mov rax, QWORD [rel __clovis_heap_cur]\n")
+	b.WriteString("mov rsi, QWORD [rel __clovis_heap_end]\n")
+	b.WriteString("sub rsi, rax\n")
+	b.WriteString("cmp rsi, rdx\n")
+	b.WriteString("jae .bump\n")
+	b.WriteString("push rdi\n")
+	b.WriteString("push rdx\n")
+	fmt.Fprintf(&b, "mov rsi, %v\n", allocChunkSize)
+	b.WriteString("call __clovis_mmap\n")
+	b.WriteString("pop rdx\n")
+	b.WriteString("pop rdi\n")
+	fmt.Fprintf(&b, "lea rsi, [rax + %v]\n", allocChunkSize)
+	b.WriteString("mov QWORD [rel __clovis_heap_end], rsi\n")
+	b.WriteString(".bump:\n")
+	b.WriteString("lea rsi, [rax + rdx]\n")
+	b.WriteString("mov QWORD [rel __clovis_heap_cur], rsi\n")
+	b.WriteString("mov QWORD [rax], rdi\n")
+	b.WriteString(".chunk_block:\n")
+	fmt.Fprintf(&b, "mov QWORD [rax + 8], %#x\n", tagChunk)
+	b.WriteString("jmp .zero\n")
+	b.WriteString(".mapped_block:\n")
+	b.WriteString("push rdi\n")
+	b.WriteString("mov rsi, rdx\n")
+	b.WriteString("call __clovis_mmap\n")
+	b.WriteString("pop rdi\n")
+	b.WriteString("mov QWORD [rax], rdi\n")
+	fmt.Fprintf(&b, "mov QWORD [rax + 8], %#x\n", tagMapped)
+	b.WriteString(".zero:\n")
+	if debug {
+		b.WriteString("inc QWORD [rel __clovis_live_allocs]\n")
+	}
+	b.WriteString("lea rdx, [rax + 16]\n")
+	b.WriteString("push rdx\n")
+	b.WriteString("mov rcx, rdi\n")
+	b.WriteString("mov rdi, rdx\n")
+	b.WriteString("xor eax, eax\n")
+	b.WriteString("rep stosb\n")
+	b.WriteString("pop rax\n")
+	b.WriteString("ret\n")
+
+	// __clovis_free: frees the block whose payload rdi points to. Freeing a null pointer does nothing.
+	b.WriteString("\n")
+	writeHeapRoutine(&b, "__clovis_free", threads)
+	b.WriteString("test rdi, rdi\n")
+	b.WriteString("jz .done\n")
+	b.WriteString("sub rdi, 16\n")
+	if debug {
+		b.WriteString("mov rax, QWORD [rdi + 8]\n")
+		fmt.Fprintf(&b, "cmp rax, %#x\n", tagFreed)
+		b.WriteString("je .double_free\n")
+		fmt.Fprintf(&b, "cmp rax, %#x\n", tagChunk)
+		b.WriteString("je .valid\n")
+		fmt.Fprintf(&b, "cmp rax, %#x\n", tagMapped)
+		b.WriteString("je .valid\n")
+		b.WriteString("lea rsi, [rel __clovis_msg_invalid_free]\n")
+		b.WriteString("mov rdx, __clovis_msg_invalid_free_len\n")
+		b.WriteString("jmp __clovis_fail\n")
+		b.WriteString(".double_free:\n")
+		b.WriteString("lea rsi, [rel __clovis_msg_double_free]\n")
+		b.WriteString("mov rdx, __clovis_msg_double_free_len\n")
+		b.WriteString("jmp __clovis_fail\n")
+		b.WriteString(".valid:\n")
+		b.WriteString("dec QWORD [rel __clovis_live_allocs]\n")
+	}
+	fmt.Fprintf(&b, "cmp QWORD [rdi + 8], %#x\n", tagMapped)
+	b.WriteString("je .unmap\n")
+	fmt.Fprintf(&b, "mov QWORD [rdi + 8], %#x\n", tagFreed)
+	b.WriteString("mov rax, QWORD [rel __clovis_free_list]\n")
+	b.WriteString("mov QWORD [rdi + 16], rax\n")
+	b.WriteString("mov QWORD [rel __clovis_free_list], rdi\n")
+	b.WriteString(".done:\n")
+	b.WriteString("ret\n")
+	b.WriteString(".unmap:\n")
+	b.WriteString("mov rsi, QWORD [rdi]\n")
+	b.WriteString("add rsi, 16\n")
+	if debug {
+		// Only the pages after the header are unmapped, mapped blocks are larger than a page.
+		fmt.Fprintf(&b, "mov QWORD [rdi + 8], %#x\n", tagFreed)
+		fmt.Fprintf(&b, "add rdi, %v\n", pageSize)
+		fmt.Fprintf(&b, "sub rsi, %v\n", pageSize)
+	}
+	b.WriteString("mov rax, 11\n") // munmap
+	b.WriteString("syscall\n")
+	b.WriteString("ret\n")
+
+	// __clovis_mmap: maps rsi bytes of zeroed memory and returns its address in rax.
+	b.WriteString("\n__clovis_mmap:\n")
+	b.WriteString("mov rax, 9\n")
+	b.WriteString("xor edi, edi\n")
+	b.WriteString("mov rdx, 3\n")     // PROT_READ | PROT_WRITE
+	b.WriteString("mov r10, 0x22\n")  // MAP_PRIVATE | MAP_ANONYMOUS
+	b.WriteString("mov r8, -1\n")
+	b.WriteString("xor r9d, r9d\n")
+	b.WriteString("syscall\n")
+	b.WriteString("cmp rax, -4096\n") // Errors are returned as -errno
+	fmt.Fprintf(&b, "ja %v\n", outOfMemoryLabel)
+	b.WriteString("ret\n")
+	fmt.Fprintf(&b, "%v:\n", outOfMemoryLabel)
+	b.WriteString("lea rsi, [rel __clovis_msg_out_of_memory]\n")
+	b.WriteString("mov rdx, __clovis_msg_out_of_memory_len\n")
+
+	// __clovis_fail: writes the message at rsi of length rdx to stderr and exits with status 1.
+	// Libraries return the status from their entry instead.
+	b.WriteString("\n__clovis_fail:\n")
+	b.WriteString("mov rax, 1\n")
+	b.WriteString("mov rdi, 2\n")
+	b.WriteString("syscall\n")
+	if library {
+		b.WriteString("mov rdi, 1\n")
+		fmt.Fprintf(&b, "jmp %v\n", abortLabel)
+	} else {
+		b.WriteString("mov rax, 231\n") // exit_group
+		b.WriteString("mov rdi, 1\n")
+		b.WriteString("syscall\n")
+	}
+
+	if threads {
+		// __clovis_heap_lock: spins until the heap lock is taken. Clobbers rax.
+		b.WriteString("\n__clovis_heap_lock:\n")
+		b.WriteString("mov eax, 1\n")
+		b.WriteString("xchg eax, DWORD [rel __clovis_heap_locked]\n")
+		b.WriteString("test eax, eax\n")
+		b.WriteString("jz .locked\n")
+		b.WriteString("pause\n")
+		b.WriteString("jmp __clovis_heap_lock\n")
+		b.WriteString(".locked:\n")
+		b.WriteString("ret\n")
+
+		// __clovis_heap_unlock: releases the heap lock.
+		b.WriteString("\n__clovis_heap_unlock:\n")
+		b.WriteString("mov DWORD [rel __clovis_heap_locked], 0\n")
+		b.WriteString("ret\n")
+	}
+
+	return b.String()
+}
+
+// Writes the label of an allocator routine.
+// With threads the routine takes the heap lock around its body, which gets a label of its own.
+func writeHeapRoutine(b *strings.Builder, name string, threads bool) {
+	fmt.Fprintf(b, "%v:\n", name)
+	if !threads {
+		return
+	}
+
+	b.WriteString("call __clovis_heap_lock\n")
+	fmt.Fprintf(b, "call %v_unlocked\n", name)
+	b.WriteString("jmp __clovis_heap_unlock\n")
+	fmt.Fprintf(b, "%v_unlocked:\n", name)
+}
+
+// Returns the read only data of the allocator routines.
+func allocatorROData(debug bool) string {
+	b := strings.Builder{}
+
+	writeMessage(&b, "__clovis_msg_out_of_memory", "clovis: out of memory")
+	if debug {
+		writeMessage(&b, "__clovis_msg_double_free", "clovis: double free detected")
+		writeMessage(&b, "__clovis_msg_invalid_free", "clovis: delete of a pointer not returned by new")
+		writeMessage(&b, "__clovis_msg_leak", "clovis: memory leaked at exit")
+	}
+
+	return b.String()
+}
+
+// Returns the zero initialised data of the allocator routines.
+func allocatorBSS(debug bool, threads bool) string {
+	b := strings.Builder{}
+
+	b.WriteString("__clovis_free_list: resq 1\n")
+	b.WriteString("__clovis_heap_cur: resq 1\n")
+	b.WriteString("__clovis_heap_end: resq 1\n")
+	if debug {
+		b.WriteString("__clovis_live_allocs: resq 1\n")
+	}
+	if threads {
+		b.WriteString("__clovis_heap_locked: resq 1\n")
+	}
+
+	return b.String()
+}
+
+// Reports leaked allocations and exits with status 1 when any are still live.
+// Otherwise falls through with the exit status in rdi untouched.
+func allocatorLeakCheck() string {
+	b := strings.Builder{}
+
+	b.WriteString("cmp QWORD [rel __clovis_live_allocs], 0\n")
+	b.WriteString("je .no_leaks\n")
+	b.WriteString("lea rsi, [rel __clovis_msg_leak]\n")
+	b.WriteString("mov rdx, __clovis_msg_leak_len\n")
+	b.WriteString("jmp __clovis_fail\n")
+	b.WriteString(".no_leaks:\n")
+
+	return b.String()
+}
+
+// Writes a newline terminated string and a constant holding its length.
+func writeMessage(b *strings.Builder, label string, msg string) {
+	fmt.Fprintf(b, "%v: db \"%v\", 10\n", label, msg)
+	fmt.Fprintf(b, "%v_len equ $ - %v\n", label, label)
+}
+
+// Returns the routine parsing an unsigned decimal from stdin.
+// __clovis_read_uint: skips leading whitespace, reads digits up to the first other byte and
+// returns the value in rax and 0 in rdx, or 0 in rax and the error number in rdx.
+// Input without digits is reported as EINVAL and values above 64 bits as ERANGE.
+func readUintRuntime() string {
+	b := strings.Builder{}
+
+	b.WriteString("\n; read_uint runtime\n")
+	b.WriteString("__clovis_read_uint:\n")
+	b.WriteString("sub rsp, 16\n")   // [rsp] holds the byte read
+	b.WriteString("xor r8d, r8d\n")  // r8 holds the value
+	b.WriteString("xor r9d, r9d\n")  // r9 holds the digit count
+	b.WriteString(".next:\n")
+	b.WriteString("mov rax, 0\n")    // read
+	b.WriteString("mov rdi, 0\n")    // stdin
+	b.WriteString("mov rsi, rsp\n")
+	b.WriteString("mov rdx, 1\n")
+	b.WriteString("syscall\n")
+	b.WriteString("cmp rax, -4096\n")
+	b.WriteString("ja .error\n")
+	b.WriteString("test rax, rax\n")
+	b.WriteString("jz .end\n")       // End of input
+	b.WriteString("movzx eax, BYTE [rsp]\n")
+	b.WriteString("sub eax, '0'\n")
+	b.WriteString("cmp eax, 9\n")
+	b.WriteString("ja .not_digit\n")
+	b.WriteString("mov rcx, rax\n")
+	b.WriteString("mov rax, r8\n")
+	b.WriteString("mov rdx, 10\n")
+	b.WriteString("mul rdx\n")
+	b.WriteString("jc .overflow\n")
+	b.WriteString("add rax, rcx\n")
+	b.WriteString("jc .overflow\n")
+	b.WriteString("mov r8, rax\n")
+	b.WriteString("inc r9\n")
+	b.WriteString("jmp .next\n")
+	b.WriteString(".not_digit:\n")
+	b.WriteString("test r9, r9\n")
+	b.WriteString("jnz .end\n")
+	b.WriteString("movzx eax, BYTE [rsp]\n")
+	b.WriteString("cmp eax, ' '\n")
+	b.WriteString("je .next\n")
+	b.WriteString("cmp eax, 9\n")    // '\t' to '\r'
+	b.WriteString("jb .invalid\n")
+	b.WriteString("cmp eax, 13\n")
+	b.WriteString("jbe .next\n")
+	b.WriteString(".invalid:\n")
+	b.WriteString("mov rdx, 22\n")   // EINVAL
+	b.WriteString("jmp .failed\n")
+	b.WriteString(".overflow:\n")
+	b.WriteString("mov rdx, 34\n")   // ERANGE
+	b.WriteString("jmp .failed\n")
+	b.WriteString(".error:\n")
+	b.WriteString("neg rax\n")
+	b.WriteString("mov rdx, rax\n")
+	b.WriteString("jmp .failed\n")
+	b.WriteString(".end:\n")
+	b.WriteString("test r9, r9\n")
+	b.WriteString("jz .invalid\n")
+	b.WriteString("mov rax, r8\n")
+	b.WriteString("xor edx, edx\n")
+	b.WriteString("add rsp, 16\n")
+	b.WriteString("ret\n")
+	b.WriteString(".failed:\n")
+	b.WriteString("xor eax, eax\n")
+	b.WriteString("add rsp, 16\n")
+	b.WriteString("ret\n")
+
+	return b.String()
+}
+
+// Returns the end of the exit path of a library followed by __clovis_abort.
+// The entry returns the exit status in rax, or 1 when a thread failed a check.
+func libraryReturn() string {
+	b := strings.Builder{}
+
+	b.WriteString("cmp BYTE [rel __clovis_thread_failed], 0\n")
+	b.WriteString("je __clovis_return\n")
+	b.WriteString("mov rdi, 1\n")
+	b.WriteString("__clovis_return:\n")
+	// rbp still points at the registers saved by the entry.
+	b.WriteString("mov rax, rdi\n")
+	b.WriteString("mov rsp, rbp\n")
+	b.WriteString("pop rbx\n")
+	b.WriteString("pop rbp\n")
+	b.WriteString("ret\n")
+
+	// __clovis_abort: returns the status in rdi from the entry without the checks of the exit path.
+	// Threads run on frames of their own, so on a thread only the thread ends and the failure is
+	// reported once the entry returns.
+	fmt.Fprintf(&b, "\n%v:\n", abortLabel)
+	b.WriteString("cmp rbp, QWORD [rel __clovis_entry_frame]\n")
+	b.WriteString("je __clovis_return\n")
+	b.WriteString("mov BYTE [rel __clovis_thread_failed], 1\n")
+	b.WriteString("mov rax, 60\n") // exit ends only the calling thread
+	b.WriteString("syscall\n")
+
+	return b.String()
+}
+
+// Returns the zero initialised data of a library's entry.
+func libraryBSS() string {
+	b := strings.Builder{}
+
+	b.WriteString("__clovis_entry_frame: resq 1\n")
+	b.WriteString("__clovis_thread_failed: resb 1\n")
+
+	return b.String()
+}
+
+// Returns the routines starting and joining threads.
+// A thread's mapping holds its thread id in the first dword, whether it was joined in the second
+// and its stack at the top. The kernel clears the thread id and wakes its futex when the thread exits.
+func threadRuntime() string {
+	b := strings.Builder{}
+
+	b.WriteString("\n; Thread runtime\n")
+
+	// __clovis_spawn: starts a thread at the address in rsi with a copy of the rdi bytes of the
+	// stack frame below rbp. The thread runs with rbp pointing at the end of its copy.
+	// Returns the thread handle in rax.
+	b.WriteString("__clovis_spawn:\n")
+	b.WriteString("push rsi\n")
+	b.WriteString("push rdi\n")
+	fmt.Fprintf(&b, "mov rsi, %#x\n", threadStackSize)
+	b.WriteString("call __clovis_mmap\n")
+	b.WriteString("mov rbx, rax\n") // rbx holds the handle
+	fmt.Fprintf(&b, "lea r9, [rax + %#x]\n", threadStackSize) // r9 holds the thread's rbp
+	b.WriteString("pop rcx\n")
+	b.WriteString("mov rsi, rbp\n")
+	b.WriteString("sub rsi, rcx\n")
+	b.WriteString("mov rdi, r9\n")
+	b.WriteString("sub rdi, rcx\n")
+	b.WriteString("lea r8, [rdi - 8]\n") // The thread's stack starts with its entry address
+	b.WriteString("rep movsb\n")
+	b.WriteString("pop rax\n")
+	b.WriteString("mov QWORD [r8], rax\n")
+	b.WriteString("mov rax, 56\n") // clone
+	fmt.Fprintf(&b, "mov rdi, %#x\n", threadCloneFlags)
+	b.WriteString("mov rsi, r8\n")
+	b.WriteString("mov rdx, rbx\n")  // parent_tid
+	b.WriteString("mov r10, rbx\n")  // child_tid
+	b.WriteString("syscall\n")
+	b.WriteString("test rax, rax\n")
+	b.WriteString("jz .thread\n")
+	b.WriteString("cmp rax, -4096\n")
+	b.WriteString("ja .failed\n")
+	b.WriteString("mov rax, rbx\n")
+	b.WriteString("ret\n")
+	b.WriteString(".thread:\n")
+	b.WriteString("mov rbp, r9\n")   // Registers other than rax, rcx and r11 are inherited
+	b.WriteString("ret\n")           // Pops the entry address
+	b.WriteString(".failed:\n")
+	b.WriteString("lea rsi, [rel __clovis_msg_spawn_failed]\n")
+	b.WriteString("mov rdx, __clovis_msg_spawn_failed_len\n")
+	b.WriteString("jmp __clovis_fail\n")
+
+	// __clovis_join: waits for the thread with the handle in rdi to exit and unmaps its stack.
+	// The first page stays mapped and marked as joined so joining the thread again can be reported.
+	b.WriteString("\n__clovis_join:\n")
+	b.WriteString("mov rbx, rdi\n")
+	b.WriteString("mov eax, 1\n")
+	b.WriteString("xchg eax, DWORD [rbx + 4]\n")
+	b.WriteString("test eax, eax\n")
+	b.WriteString("jnz .joined\n")
+	b.WriteString(".wait:\n")
+	b.WriteString("mov edx, DWORD [rbx]\n")
+	b.WriteString("test edx, edx\n")
+	b.WriteString("jz .done\n")
+	b.WriteString("mov rax, 202\n") // futex
+	b.WriteString("mov rdi, rbx\n")
+	b.WriteString("xor esi, esi\n")  // FUTEX_WAIT
+	b.WriteString("xor r10d, r10d\n")
+	b.WriteString("syscall\n")
+	b.WriteString("jmp .wait\n")
+	b.WriteString(".done:\n")
+	b.WriteString("mov rax, 11\n") // munmap
+	fmt.Fprintf(&b, "lea rdi, [rbx + %v]\n", pageSize)
+	fmt.Fprintf(&b, "mov rsi, %#x\n", threadStackSize - pageSize)
+	b.WriteString("syscall\n")
+	b.WriteString("ret\n")
+	b.WriteString(".joined:\n")
+	b.WriteString("lea rsi, [rel __clovis_msg_joined_twice]\n")
+	b.WriteString("mov rdx, __clovis_msg_joined_twice_len\n")
+	b.WriteString("jmp __clovis_fail\n")
+
+	return b.String()
+}
+
+// Returns the read only data of the thread routines.
+func threadROData() string {
+	b := strings.Builder{}
+
+	writeMessage(&b, "__clovis_msg_spawn_failed", "clovis: spawn failed")
+	writeMessage(&b, "__clovis_msg_joined_twice", "clovis: thread joined twice")
+
+	return b.String()
+}
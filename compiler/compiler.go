@@ -0,0 +1,290 @@
+// Package compiler drives the whole compilation of a program, from loading its
+// source files to linking the executable, so tools can compile without spawning clovis.
+package compiler
+
+import (
+	"clovis/codegen"
+	"clovis/diagnostics"
+	"clovis/loader"
+	"clovis/semantics"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// What the program is compiled into.
+type Target int
+const (
+	// A standalone executable entered at _start.
+	Executable Target = iota
+	// An executable linked against the C library and entered at main.
+	LibcExecutable
+	// An object exporting the program as <name>_main along with a C header.
+	Library
+)
+
+// How far compilation goes.
+type Emit int
+const (
+	// Links the program into Options.Output.
+	EmitExecutable Emit = iota
+	// Assembles the program into the object Options.Output.
+	EmitObject
+	// Stops after code generation with the assembly in Result.ASM.
+	EmitASM
+	// Stops after semantic analysis with the checked syntax trees in Result.Modules.
+	EmitAST
+	// Stops after lexing and parsing.
+	EmitTokens
+)
+
+type Options struct {
+	// The path of the program's main file.
+	Main       string
+	// Source files by path. Files missing from Sources are read with ReadFile.
+	Sources    map[string]string
+	// Reads the source files missing from Sources. Defaults to os.ReadFile.
+	// Tools compiling only in-memory sources can return fs.ErrNotExist to keep the disk out.
+	ReadFile   func(path string) ([]byte, error)
+	Target     Target
+	Emit       Emit
+	// The path of the object or executable. Unused by the other emits.
+	Output     string
+	// Object files and static libraries passed on to the linker.
+	LinkInputs []string
+	// Checks heap allocations at runtime.
+	DebugAlloc bool
+	// Keeps the intermediate files, their directory is returned in Result.TempDir.
+	KeepTemps  bool
+	// Collects the parser and semantics logs in Result.
+	DebugLogs  bool
+}
+
+type Result struct {
+	// The problems found in the program. Compilation stops after the phase that found errors.
+	Diagnostics  []*diagnostics.Diagnostic
+	// The source text of the loaded files by path, for rendering the diagnostics.
+	Sources      map[string]string
+	// The loaded modules with their tokens and syntax trees. The main file comes last.
+	Modules      []*loader.Module
+	// The generated assembly. Empty unless code generation ran.
+	ASM          string
+	// The C header of a library. Empty unless the library was assembled.
+	Header       string
+	// The directory of the kept intermediate files.
+	TempDir      string
+	// The parser and semantics logs. Only collected with Options.DebugLogs.
+	ParserLog    string
+	SemanticsLog string
+}
+
+// Reports whether the program has errors.
+func (r *Result) Failed() bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity == diagnostics.Error {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Compiles the program described by opts.
+// Errors in the program are returned as the result's diagnostics. The returned error
+// reports failures of the compilation itself, such as a missing assembler or a cancelled ctx.
+// Failures of the assembler, the linker and intermediate files are *diagnostics.Diagnostic errors.
+func Compile(ctx context.Context, opts Options) (*Result, error) {
+	// Every phase reports to the same collector.
+	collector := diagnostics.NewCollector()
+	result := &Result{ Sources: collector.Sources }
+	defer func() { result.Diagnostics = collector.Diagnostics }()
+
+	if opts.Target == Library && opts.Emit == EmitExecutable {
+		return result, errors.New("Libraries cannot be linked into executables")
+	}
+
+	// -- INPUT, LEXING & PARSING
+	loader := loader.NewLoader()
+	loader.Diagnostics = collector
+	loader.ReadFile = sourceReader(opts.Sources, opts.ReadFile)
+	loader.Load(opts.Main)
+	result.Modules = loader.Modules
+
+	if opts.DebugLogs {
+		log := strings.Builder{}
+		for _, module := range loader.Modules {
+			for _, stmt := range module.Stmts {
+				fmt.Fprintf(&log, "%v\n\n", stmt.Print(0))
+			}
+		}
+		result.ParserLog = log.String()
+	}
+
+	if collector.HasErrors() || opts.Emit == EmitTokens {
+		return result, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	// -- SEMANTIC ANALYSIS
+	checker := semantics.NewSemanticChecker()
+	checker.Diagnostics = collector
+	semanticsLog := strings.Builder{}
+
+	for _, module := range loader.Modules {
+		checker.SetModule(module.Name, module.Path)
+
+		for _, stmt := range module.Stmts {
+			if err := stmt.Semantics(checker); err != nil {
+				fmt.Fprintf(&semanticsLog, "%v\n", err)
+			} else if opts.DebugLogs {
+				fmt.Fprintf(
+					&semanticsLog,
+					"-----------------------------------------------------\n\n%v\n\n%v\n-----------------------------------------------------\n",
+					stmt.Print(0),
+					checker,
+				)
+			}
+		}
+	}
+
+	if opts.DebugLogs {
+		result.SemanticsLog = semanticsLog.String()
+	}
+
+	if collector.HasErrors() || opts.Emit == EmitAST {
+		return result, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	// -- CODE GENERATION
+	// Libraries are named after their main file and export its program as <name>_main.
+	libName := LibraryName(opts.Main)
+	entry := codegen.EntryFunction(libName + "_main")
+
+	emitter := codegen.NewEmitter()
+	switch opts.Target {
+	case Library:
+		emitter = codegen.NewLibraryEmitter(entry.Name)
+	case LibcExecutable:
+		emitter = codegen.NewLibcEmitter()
+	}
+	emitter.DebugAlloc = opts.DebugAlloc
+	for _, module := range loader.Modules {
+		for _, stmt := range module.Stmts {
+			stmt.EmitCode(emitter)
+		}
+	}
+	emitter.End()
+	result.ASM = emitter.Code
+
+	if opts.Emit == EmitASM {
+		return result, nil
+	}
+
+	// -- COMPILE & LINK
+	// Intermediate files get a directory of their own so parallel builds do not clobber each other.
+	tempDir, err := os.MkdirTemp("", "clovis-")
+	if err != nil {
+		return result, diagnostics.New(diagnostics.OutputFailed, diagnostics.Span{}, err.Error())
+	}
+
+	if opts.KeepTemps {
+		result.TempDir = tempDir
+	} else {
+		defer os.RemoveAll(tempDir)
+	}
+
+	asmFile := filepath.Join(tempDir, libName + ".asm")
+	if err := os.WriteFile(asmFile, []byte(result.ASM), 0644); err != nil {
+		return result, diagnostics.New(diagnostics.OutputFailed, diagnostics.Span{}, err.Error())
+	}
+
+	objFile := filepath.Join(tempDir, libName + ".o")
+	if opts.Emit == EmitObject {
+		objFile = opts.Output
+	}
+
+	nasmCmd := exec.CommandContext(ctx, "nasm", "-f", "elf64", asmFile, "-o", objFile)
+	if output, err := nasmCmd.CombinedOutput(); err != nil {
+		return result, toolFailure(diagnostics.AssemblerFailed, "assembling", output, err)
+	}
+
+	if opts.Target == Library {
+		result.Header = codegen.Header(strings.ToUpper(libName) + "_H", entry, checker.ExportedTypes())
+	}
+
+	if opts.Emit == EmitObject {
+		return result, nil
+	}
+
+	// With the C library the system C compiler driver adds the C runtime and libc.
+	linkArgs := append([]string{ objFile }, opts.LinkInputs...)
+	linkArgs = append(linkArgs, "-o", opts.Output)
+	linkCmd := exec.CommandContext(ctx, "ld", linkArgs...)
+	if opts.Target == LibcExecutable {
+		linkCmd = exec.CommandContext(ctx, "cc", linkArgs...)
+	}
+
+	if output, err := linkCmd.CombinedOutput(); err != nil {
+		return result, toolFailure(diagnostics.LinkerFailed, "linking", output, err)
+	}
+
+	return result, nil
+}
+
+// Returns the name of the library built from the main file at path, a valid C identifier.
+func LibraryName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	ident := []rune{}
+	for i, r := range name {
+		if r <= unicode.MaxASCII && (unicode.IsLetter(r) || r == '_' || (i > 0 && unicode.IsDigit(r))) {
+			ident = append(ident, r)
+		} else {
+			ident = append(ident, '_')
+		}
+	}
+
+	return string(ident)
+}
+
+// Returns a reader of source files that prefers the in-memory sources over readFile, or the disk without it.
+func sourceReader(sources map[string]string, readFile func(string) ([]byte, error)) func(string) ([]byte, error) {
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+
+	cleaned := map[string]string{}
+	for path, source := range sources {
+		cleaned[filepath.Clean(path)] = source
+	}
+
+	return func(path string) ([]byte, error) {
+		if source, isInMemory := cleaned[path]; isInMemory {
+			return []byte(source), nil
+		}
+
+		return readFile(path)
+	}
+}
+
+// Reports the failure of an external tool with what it printed, or why it could not run when it printed nothing.
+func toolFailure(code diagnostics.Code, step string, output []byte, err error) *diagnostics.Diagnostic {
+	msg := strings.TrimRight(string(output), "\n")
+	if len(output) == 0 {
+		msg = err.Error()
+	}
+
+	return diagnostics.New(code, diagnostics.Span{}, fmt.Sprintf("Failed during %v:\n\t%v", step, msg))
+}
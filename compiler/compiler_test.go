@@ -0,0 +1,198 @@
+package compiler_test
+
+import (
+	"clovis/compiler"
+	"clovis/diagnostics"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Compiles the in-memory program src as main.clv up to emit.
+func compileSource(t *testing.T, src string, emit compiler.Emit) (*compiler.Result, error) {
+	t.Helper()
+
+	return compiler.Compile(context.Background(), compiler.Options{
+		Main: "main.clv",
+		Sources: map[string]string{ "main.clv": src },
+		Emit: emit,
+	})
+}
+
+func TestEmitStages(t *testing.T) {
+	src := "uint64 x = 1;\n"
+
+	result, err := compileSource(t, src, compiler.EmitTokens)
+	if err != nil || len(result.Modules) != 1 || len(result.Modules[0].Tokens) == 0 {
+		t.Fatalf("Expected the tokens of main.clv but received %+v, %v", result, err)
+	}
+	if result.ASM != "" {
+		t.Errorf("Expected no assembly after parsing")
+	}
+	if result.Sources["main.clv"] != src {
+		t.Errorf("Expected the source of main.clv but received %q", result.Sources["main.clv"])
+	}
+
+	result, err = compileSource(t, src, compiler.EmitAST)
+	if err != nil || len(result.Modules[0].Stmts) != 1 || result.ASM != "" {
+		t.Fatalf("Expected the checked syntax tree without assembly but received %+v, %v", result, err)
+	}
+
+	result, err = compileSource(t, src, compiler.EmitASM)
+	if err != nil || !strings.Contains(result.ASM, "_start:") {
+		t.Fatalf("Expected the assembly of an executable but received %v, %v", result.ASM, err)
+	}
+}
+
+func TestCompileDiagnostics(t *testing.T) {
+	result, err := compileSource(t, "uint64 x = 1;\nuint64 x = 2;\n", compiler.EmitASM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Failed() || len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != diagnostics.Redeclaration {
+		t.Fatalf("Expected a redeclaration but received %v", result.Diagnostics)
+	}
+	if d := result.Diagnostics[0]; d.File != "main.clv" || d.Span.Start.Line != 2 {
+		t.Errorf("Expected the error on line 2 of main.clv but received %v", d)
+	}
+	if result.ASM != "" {
+		t.Errorf("Expected code generation to be skipped after errors")
+	}
+
+	// Parse errors stop compilation before semantics.
+	result, _ = compileSource(t, "uint64 x = ;\nuint64 y = z;\n", compiler.EmitASM)
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != diagnostics.SyntaxError {
+		t.Errorf("Expected only the syntax error but received %v", result.Diagnostics)
+	}
+}
+
+func TestMissingMain(t *testing.T) {
+	result, err := compiler.Compile(context.Background(), compiler.Options{
+		Main: filepath.Join(t.TempDir(), "missing.clv"),
+		Emit: compiler.EmitAST,
+	})
+
+	if err != nil || !result.Failed() {
+		t.Errorf("Expected the missing main file as a diagnostic but received %v, %v", result.Diagnostics, err)
+	}
+}
+
+func TestCancelledCompile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := compiler.Compile(ctx, compiler.Options{
+		Main: "main.clv",
+		Sources: map[string]string{ "main.clv": "uint64 x = 1;" },
+		Emit: compiler.EmitASM,
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancellation but received %v", err)
+	}
+}
+
+// Failures of the assembler are returned as diagnostics.
+func TestAssemblerFailure(t *testing.T) {
+	t.Setenv("PATH", "") // No assembler can be found
+
+	_, err := compiler.Compile(context.Background(), compiler.Options{
+		Main: "main.clv",
+		Sources: map[string]string{ "main.clv": "uint64 x = 1;" },
+		Emit: compiler.EmitObject,
+		Output: filepath.Join(t.TempDir(), "main.o"),
+	})
+
+	d := &diagnostics.Diagnostic{}
+	if !errors.As(err, &d) || d.Code != diagnostics.AssemblerFailed {
+		t.Errorf("Expected an assembler failure but received %v", err)
+	}
+}
+
+// The options are rejected before anything is loaded or generated.
+func TestLibraryCannotLink(t *testing.T) {
+	result, err := compiler.Compile(context.Background(), compiler.Options{
+		Main: "main.clv",
+		Sources: map[string]string{ "main.clv": "uint64 x = true;" },
+		Target: compiler.Library,
+	})
+	if err == nil {
+		t.Errorf("Expected libraries to be rejected as executables")
+	}
+	if len(result.Modules) != 0 || len(result.Diagnostics) != 0 || result.ASM != "" {
+		t.Errorf("Expected nothing to be compiled but received %+v", result)
+	}
+}
+
+// Files missing from Sources are read with Options.ReadFile instead of from disk.
+func TestReadFileHook(t *testing.T) {
+	read := []string{}
+	opts := compiler.Options{
+		Main: "main.clv",
+		Sources: map[string]string{ "main.clv": `import "math.clv"; uint64 x = math.PI;` },
+		Emit: compiler.EmitAST,
+		ReadFile: func(path string) ([]byte, error) {
+			read = append(read, path)
+			return nil, fs.ErrNotExist
+		},
+	}
+
+	result, err := compiler.Compile(context.Background(), opts)
+	if err != nil || len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != diagnostics.UnreadableFile {
+		t.Errorf("Expected math.clv to be unreadable but received %v, %v", result.Diagnostics, err)
+	}
+	if fmt.Sprint(read) != "[math.clv]" {
+		t.Errorf("Expected only math.clv to be read but received %v", read)
+	}
+
+	opts.ReadFile = func(path string) ([]byte, error) {
+		return []byte("uint64 PI = 3;"), nil
+	}
+	result, err = compiler.Compile(context.Background(), opts)
+	if err != nil || result.Failed() {
+		t.Errorf("Expected math.clv to be read by the hook but received %v, %v", result.Diagnostics, err)
+	}
+}
+
+func TestLibraryName(t *testing.T) {
+	tests := map[string]string{
+		"main.clv": "main",
+		"dir/my-lib.clv": "my_lib",
+		"2d.clv": "_d",
+		"vec3.clv": "vec3",
+		"größe.clv": "gr__e",
+	}
+
+	for path, expected := range tests {
+		if name := compiler.LibraryName(path); name != expected {
+			t.Errorf("Expected %v to be named %v but received %v", path, expected, name)
+		}
+	}
+}
+
+// The lexer and parser report into the same diagnostics, each tied to its file and position.
+// Files with lexer errors are not parsed, so their illegal tokens are not reported twice.
+func TestPhasesShareDiagnostics(t *testing.T) {
+	result, _ := compiler.Compile(context.Background(), compiler.Options{
+		Main: "main.clv",
+		Sources: map[string]string{
+			"main.clv": "import \"other.clv\";\nuint64 = 2;\n",
+			"other.clv": "uint64 x = 1 @;\nuint64 = 2;\n",
+		},
+		Emit: compiler.EmitAST,
+	})
+
+	received := []string{}
+	for _, d := range result.Diagnostics {
+		received = append(received, d.Error()[:strings.Index(d.Error(), "]") + 1])
+	}
+
+	expected := "[main.clv:2:8: error[P001] other.clv:1:14: error[L001]]"
+	if fmt.Sprint(received) != expected {
+		t.Errorf("Expected %v but received %v", expected, result.Diagnostics)
+	}
+}
@@ -0,0 +1,65 @@
+package diagnostics
+
+// A stable identifier of a kind of problem. The first letter names the phase that reports it.
+// Codes are never reused once released, so tools can rely on them.
+type Code string
+
+// Lexer
+const (
+	UnknownCharacter   Code = "L001"
+	UnterminatedString Code = "L002"
+	InvalidEncoding    Code = "L003"
+)
+
+// Loader
+const (
+	UnreadableFile      Code = "I001"
+	ImportCycle         Code = "I002"
+	InvalidModuleName   Code = "I003"
+	DuplicateModuleName Code = "I004"
+)
+
+// Parser
+const (
+	SyntaxError Code = "P001"
+)
+
+// Semantic analysis
+const (
+	// A value of one type is used where another type is expected.
+	TypeMismatch         Code = "S001"
+	// An operator, statement or builtin cannot be used with a value of the given type.
+	InvalidOperand       Code = "S002"
+	InvalidCast          Code = "S003"
+	UndeclaredSymbol     Code = "S010"
+	Redeclaration        Code = "S011"
+	UnknownMember        Code = "S012"
+	// A symbol is used as something it is not, e.g. a type as a value.
+	WrongSymbolKind      Code = "S013"
+	ReservedName         Code = "S014"
+	ArgumentCount        Code = "S020"
+	NotAddressable       Code = "S030"
+	NotConstant          Code = "S040"
+	StaticAssertFailed   Code = "S041"
+	// Duplicate, empty or missing arms of switch and match statements.
+	InvalidArms          Code = "S050"
+	InvalidDeclaration   Code = "S060"
+	// A statement or expression is used somewhere it is not allowed.
+	Misplaced            Code = "S070"
+	InvalidRegister      Code = "S080"
+	// A value passed to or returned from a syscall or extern function does not fit in a register.
+	RegisterSize         Code = "S081"
+	// A label defined by an asm block is not local to the surrounding code.
+	InvalidAsmLabel      Code = "S082"
+	ComptimeEvaluation   Code = "S090"
+)
+
+// Building and running
+const (
+	AssemblerFailed Code = "B001"
+	LinkerFailed    Code = "B002"
+	// An output or intermediate file or directory could not be created or written.
+	OutputFailed    Code = "B003"
+	// The built program could not be started.
+	RunFailed       Code = "B004"
+)
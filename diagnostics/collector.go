@@ -0,0 +1,41 @@
+package diagnostics
+
+// Gathers the diagnostics of every phase of a compilation along with the
+// source text of the files they point into.
+type Collector struct {
+	Diagnostics []*Diagnostic
+	// The source text of the loaded files by path.
+	Sources     map[string]string
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		Diagnostics: []*Diagnostic{},
+		Sources: map[string]string{},
+	}
+}
+
+// Records a diagnostic and returns it, so it can be reported and returned as an error at once.
+func (c *Collector) Report(d *Diagnostic) *Diagnostic {
+	c.Diagnostics = append(c.Diagnostics, d)
+	return d
+}
+
+func (c *Collector) AddSource(path string, source string) {
+	c.Sources[path] = source
+}
+
+func (c *Collector) HasErrors() bool {
+	return c.Err() != nil
+}
+
+// Returns the first error reported, or nil when there is none.
+func (c *Collector) Err() error {
+	for _, d := range c.Diagnostics {
+		if d.Severity == Error {
+			return d
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+// Package diagnostics holds the problems every phase of the compiler reports
+// and renders them for terminals.
+package diagnostics
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity int
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Note:
+		return "note"
+	}
+
+	return "error"
+}
+
+// Severities are written by name in machine readable output.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// A position in a source file. Lines and columns start at 1, a zero line means no position.
+type Position struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+// The source text from Start up to but excluding End.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Returns the span of the width columns starting at line and col.
+func NewSpan(line int, col int, width int) Span {
+	return Span{
+		Start: Position{ Line: line, Col: col },
+		End: Position{ Line: line, Col: col + max(width, 1) },
+	}
+}
+
+// Another location that helps to understand a diagnostic, such as an earlier declaration.
+type Related struct {
+	File    string `json:"file"`
+	Span    Span   `json:"span"`
+	Message string `json:"message"`
+}
+
+type Diagnostic struct {
+	Severity Severity  `json:"severity"`
+	Code     Code      `json:"code"`
+	// The file the problem was found in. Empty when it is not tied to a file.
+	File     string    `json:"file"`
+	Span     Span      `json:"span"`
+	Message  string    `json:"message"`
+	Notes    []Related `json:"notes,omitempty"`
+	// Suggestions on how to fix the problem.
+	Hints    []string  `json:"hints,omitempty"`
+}
+
+// Creates an error diagnostic. The file is set by the phase reporting it.
+func New(code Code, span Span, msg string) *Diagnostic {
+	return &Diagnostic{
+		Severity: Error,
+		Code: code,
+		Span: span,
+		Message: msg,
+	}
+}
+
+// Adds a note pointing at another location.
+func (d *Diagnostic) WithNote(file string, span Span, msg string) *Diagnostic {
+	d.Notes = append(d.Notes, Related{ File: file, Span: span, Message: msg })
+	return d
+}
+
+func (d *Diagnostic) WithHint(hint string) *Diagnostic {
+	d.Hints = append(d.Hints, hint)
+	return d
+}
+
+// Formats the diagnostic on a single line, e.g. "main.clv:3:8: error[S011]: Redeclaration of symbol 'x'".
+func (d *Diagnostic) Error() string {
+	b := strings.Builder{}
+
+	if d.File != "" {
+		fmt.Fprintf(&b, "%v:", d.File)
+	}
+	if d.Span.Start.Line != 0 {
+		fmt.Fprintf(&b, "%v:%v:", d.Span.Start.Line, d.Span.Start.Col)
+	}
+	if b.Len() != 0 {
+		b.WriteString(" ")
+	}
+	fmt.Fprintf(&b, "%v[%v]: %v", d.Severity, d.Code, d.Message)
+
+	return b.String()
+}
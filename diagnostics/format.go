@@ -0,0 +1,148 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"slices"
+)
+
+// Writes the diagnostics as a JSON array. Positions without a line mean the diagnostic
+// is not tied to a position in its file.
+func WriteJSON(w io.Writer, diagnostics []*Diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []*Diagnostic{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(diagnostics)
+}
+
+// The subset of the SARIF 2.1.0 format needed to report diagnostics.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	Results    []sarifResult `json:"results"`
+	// How the columns of the regions are counted. Spans count characters, not UTF-16 code units.
+	ColumnKind string        `json:"columnKind"`
+}
+
+type sarifTool struct {
+	Driver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	} `json:"driver"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations,omitempty"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	Properties       *sarifProperties `json:"properties,omitempty"`
+}
+
+type sarifProperties struct {
+	Hints []string `json:"hints"`
+}
+
+type sarifLocation struct {
+	ID               *int          `json:"id,omitempty"`
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region *sarifRegion `json:"region,omitempty"`
+	} `json:"physicalLocation"`
+	Message *sarifMessage `json:"message,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// Writes the diagnostics as a SARIF 2.1.0 log, the format read by code scanning tools.
+// Every code becomes a rule and hints are kept in the properties of the results.
+func WriteSARIF(w io.Writer, diagnostics []*Diagnostic) error {
+	run := sarifRun{ Results: []sarifResult{}, ColumnKind: "unicodeCodePoints" }
+	run.Tool.Driver.Name = "clovis"
+	run.Tool.Driver.Rules = []sarifRule{}
+
+	codes := []string{}
+	for _, d := range diagnostics {
+		if !slices.Contains(codes, string(d.Code)) {
+			codes = append(codes, string(d.Code))
+		}
+
+		result := sarifResult{
+			RuleID: string(d.Code),
+			Level: d.Severity.String(),
+			Message: sarifMessage{ Text: d.Message },
+		}
+
+		if d.File != "" {
+			result.Locations = append(result.Locations, sarifLocationOf(d.File, d.Span))
+		}
+
+		for i, note := range d.Notes {
+			location := sarifLocationOf(note.File, note.Span)
+			location.ID = &i
+			location.Message = &sarifMessage{ Text: note.Message }
+			result.RelatedLocations = append(result.RelatedLocations, location)
+		}
+
+		if len(d.Hints) != 0 {
+			result.Properties = &sarifProperties{ Hints: d.Hints }
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	slices.Sort(codes)
+	for _, code := range codes {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ ID: code })
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema: "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{ run },
+	})
+}
+
+func sarifLocationOf(file string, span Span) sarifLocation {
+	location := sarifLocation{}
+	location.PhysicalLocation.ArtifactLocation.URI = (&url.URL{ Path: filepath.ToSlash(file) }).String()
+
+	if span.Start.Line != 0 {
+		location.PhysicalLocation.Region = &sarifRegion{
+			StartLine: span.Start.Line,
+			StartColumn: span.Start.Col,
+			EndLine: span.End.Line,
+			EndColumn: span.End.Col,
+		}
+	}
+
+	return location
+}
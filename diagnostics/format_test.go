@@ -0,0 +1,158 @@
+package diagnostics_test
+
+import (
+	"bytes"
+	"clovis/diagnostics"
+	"clovis/lexer"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// A redeclaration with a note and a hint, and a failure not tied to a file.
+func sampleDiagnostics() []*diagnostics.Diagnostic {
+	d := diagnostics.New(diagnostics.Redeclaration, diagnostics.NewSpan(2, 8, 1), "Redeclaration of symbol 'x'")
+	d.File = "dir/my main.clv"
+	d.WithNote("dir/my main.clv", diagnostics.NewSpan(1, 8, 1), "'x' was declared here")
+	d.WithHint("Rename one of the symbols")
+
+	return []*diagnostics.Diagnostic{
+		d,
+		diagnostics.New(diagnostics.LinkerFailed, diagnostics.Span{}, "Failed during linking"),
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	out := strings.Builder{}
+	if err := diagnostics.WriteJSON(&out, nil); err != nil || out.String() != "[]\n" {
+		t.Errorf("Expected an empty array but received %q, %v", out.String(), err)
+	}
+
+	out.Reset()
+	if err := diagnostics.WriteJSON(&out, sampleDiagnostics()); err != nil {
+		t.Fatal(err)
+	}
+
+	received := []map[string]any{}
+	if err := json.Unmarshal([]byte(out.String()), &received); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[` +
+		`{"code":"S011","file":"dir/my main.clv","hints":["Rename one of the symbols"],"message":"Redeclaration of symbol 'x'",` +
+		`"notes":[{"file":"dir/my main.clv","message":"'x' was declared here","span":{"end":{"col":9,"line":1},"start":{"col":8,"line":1}}}],` +
+		`"severity":"error","span":{"end":{"col":9,"line":2},"start":{"col":8,"line":2}}},` +
+		`{"code":"B002","file":"","message":"Failed during linking","severity":"error","span":{"end":{"col":0,"line":0},"start":{"col":0,"line":0}}}` +
+		`]`
+	if normalised, _ := json.Marshal(received); string(normalised) != expected {
+		t.Errorf("Expected:\n%v\nbut received:\n%v", expected, string(normalised))
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	out := strings.Builder{}
+	if err := diagnostics.WriteSARIF(&out, sampleDiagnostics()); err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct { Driver struct { Name string; Rules []struct{ ID string } } }
+			Results []struct {
+				RuleID           string
+				Level            string
+				Message          struct{ Text string }
+				Locations        []json.RawMessage
+				RelatedLocations []json.RawMessage
+				Properties       struct{ Hints []string }
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(out.String()), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "clovis" {
+		t.Fatalf("Expected a single clovis run but received %v", out.String())
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "B002" || run.Tool.Driver.Rules[1].ID != "S011" {
+		t.Errorf("Expected the sorted rules B002 and S011 but received %v", run.Tool.Driver.Rules)
+	}
+
+	if len(run.Results) != 2 {
+		t.Fatalf("Expected 2 results but received %v", run.Results)
+	}
+
+	redeclaration := run.Results[0]
+	location := `{"physicalLocation":{"artifactLocation":{"uri":"dir/my%20main.clv"},"region":{"startLine":2,"startColumn":8,"endLine":2,"endColumn":9}}}`
+	if len(redeclaration.Locations) != 1 || compact(redeclaration.Locations[0]) != location {
+		t.Errorf("Expected the location %v but received %s", location, redeclaration.Locations)
+	}
+	note := `{"id":0,"physicalLocation":{"artifactLocation":{"uri":"dir/my%20main.clv"},"region":{"startLine":1,"startColumn":8,"endLine":1,"endColumn":9}},"message":{"text":"'x' was declared here"}}`
+	if len(redeclaration.RelatedLocations) != 1 || compact(redeclaration.RelatedLocations[0]) != note {
+		t.Errorf("Expected the related location %v but received %s", note, redeclaration.RelatedLocations)
+	}
+	if redeclaration.Level != "error" || len(redeclaration.Properties.Hints) != 1 {
+		t.Errorf("Expected an error with a hint but received %+v", redeclaration)
+	}
+
+	// Diagnostics not tied to a file have no locations.
+	if failure := run.Results[1]; failure.RuleID != "B002" || len(failure.Locations) != 0 || failure.Message.Text != "Failed during linking" {
+		t.Errorf("Expected a linker failure without locations but received %+v", failure)
+	}
+}
+
+// Columns count code points, so a character outside the Basic Multilingual Plane,
+// which takes two UTF-16 code units, moves the column by one.
+func TestWriteSARIFColumns(t *testing.T) {
+	l := lexer.NewLexer("\"😀\" @")
+	l.File = "main.clv"
+	l.Lex()
+
+	out := strings.Builder{}
+	if err := diagnostics.WriteSARIF(&out, l.Diagnostics.Diagnostics); err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Runs []struct {
+			ColumnKind string
+			Results    []struct {
+				Locations []struct {
+					PhysicalLocation struct { Region struct { StartColumn int } }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(out.String()), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	run := log.Runs[0]
+	if run.ColumnKind != "unicodeCodePoints" {
+		t.Errorf("Expected the column kind unicodeCodePoints but received %q", run.ColumnKind)
+	}
+	if len(run.Results) != 1 || len(run.Results[0].Locations) != 1 || run.Results[0].Locations[0].PhysicalLocation.Region.StartColumn != 5 {
+		t.Errorf("Expected the unknown character at column 5 but received %v", out.String())
+	}
+}
+
+func TestWriteSARIFWithoutDiagnostics(t *testing.T) {
+	out := strings.Builder{}
+	if err := diagnostics.WriteSARIF(&out, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), `"results": []`) || !strings.Contains(out.String(), `"rules": []`) {
+		t.Errorf("Expected empty results and rules but received %v", out.String())
+	}
+}
+
+func compact(raw json.RawMessage) string {
+	out := bytes.Buffer{}
+	json.Compact(&out, raw)
+	return out.String()
+}
@@ -0,0 +1,150 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	colourReset   = "\x1b[0m"
+	colourBold    = "\x1b[1m"
+	colourError   = "\x1b[1;31m"
+	colourWarning = "\x1b[1;33m"
+	colourNote    = "\x1b[1;36m"
+	colourGutter  = "\x1b[1;34m"
+)
+
+// Reports whether f is a terminal that should receive coloured output.
+// Setting the NO_COLOR environment variable turns colours off.
+func IsColourTerminal(f *os.File) bool {
+	if _, noColour := os.LookupEnv("NO_COLOR"); noColour {
+		return false
+	}
+
+	info, err := f.Stat()
+	return err == nil && info.Mode() & os.ModeCharDevice != 0
+}
+
+// Writes diagnostics the way compilers commonly print them:
+//
+//	error[S011]: Redeclaration of symbol 'x'
+//	 --> main.clv:3:8
+//	  |
+//	3 | uint64 x = 2;
+//	  |        ^
+//	  = hint: ...
+type Renderer struct {
+	W       io.Writer
+	Colour  bool
+	// The source text of the files by path. Files without source are rendered without snippets.
+	Sources map[string]string
+}
+
+func (r Renderer) Render(d *Diagnostic) {
+	severityColour := colourError
+	switch d.Severity {
+	case Warning:
+		severityColour = colourWarning
+	case Note:
+		severityColour = colourNote
+	}
+
+	fmt.Fprintf(
+		r.W,
+		"%v%v[%v]%v: %v%v%v\n",
+		r.paint(severityColour), d.Severity, d.Code, r.paint(colourReset),
+		r.paint(colourBold), d.Message, r.paint(colourReset),
+	)
+
+	gutter := r.gutterWidth(d)
+	r.snippet(d.File, d.Span, gutter, severityColour)
+
+	for _, note := range d.Notes {
+		fmt.Fprintf(r.W, "%v%v= note:%v %v\n", strings.Repeat(" ", gutter + 1), r.paint(colourGutter), r.paint(colourReset), note.Message)
+		r.snippet(note.File, note.Span, gutter, colourNote)
+	}
+
+	for _, hint := range d.Hints {
+		fmt.Fprintf(r.W, "%v%v= hint:%v %v\n", strings.Repeat(" ", gutter + 1), r.paint(colourGutter), r.paint(colourReset), hint)
+	}
+}
+
+// Renders every diagnostic followed by a blank line.
+func (r Renderer) RenderAll(diagnostics []*Diagnostic) {
+	for _, d := range diagnostics {
+		r.Render(d)
+		fmt.Fprintln(r.W)
+	}
+}
+
+// Writes the location of a span, and the source line it starts on underlined from the
+// start of the span to its end, or to the end of the line for spans covering several lines.
+func (r Renderer) snippet(file string, span Span, gutter int, underlineColour string) {
+	if file == "" {
+		return
+	}
+
+	pad := strings.Repeat(" ", gutter)
+	if span.Start.Line == 0 {
+		fmt.Fprintf(r.W, "%v%v-->%v %v\n", pad, r.paint(colourGutter), r.paint(colourReset), file)
+		return
+	}
+	fmt.Fprintf(r.W, "%v%v-->%v %v:%v:%v\n", pad, r.paint(colourGutter), r.paint(colourReset), file, span.Start.Line, span.Start.Col)
+
+	lines := strings.Split(r.Sources[file], "\n")
+	if span.Start.Line > len(lines) {
+		return
+	}
+	line := []rune(strings.TrimRight(lines[span.Start.Line - 1], "\r"))
+
+	start := min(max(span.Start.Col, 1), len(line) + 1)
+	end := len(line) + 1
+	if span.End.Line == span.Start.Line && span.End.Col > start {
+		end = min(span.End.Col, end)
+	}
+	width := max(end - start, 1)
+
+	// Tabs before the span are kept so the underline lines up with the source.
+	indent := []rune{}
+	for _, c := range line[:start - 1] {
+		if c == '\t' {
+			indent = append(indent, '\t')
+		} else {
+			indent = append(indent, ' ')
+		}
+	}
+
+	bar := fmt.Sprintf("%v|%v", r.paint(colourGutter), r.paint(colourReset))
+	fmt.Fprintf(r.W, "%v %v\n", pad, bar)
+	fmt.Fprintf(
+		r.W,
+		"%v%*v |%v %v\n",
+		r.paint(colourGutter), gutter, span.Start.Line, r.paint(colourReset), string(line),
+	)
+	fmt.Fprintf(
+		r.W,
+		"%v %v %v%v^%v%v\n",
+		pad, bar, string(indent),
+		r.paint(underlineColour), strings.Repeat("~", width - 1), r.paint(colourReset),
+	)
+}
+
+// Returns the width of the line numbers shown for a diagnostic.
+func (r Renderer) gutterWidth(d *Diagnostic) int {
+	maxLine := d.Span.Start.Line
+	for _, note := range d.Notes {
+		maxLine = max(maxLine, note.Span.Start.Line)
+	}
+
+	return len(fmt.Sprint(maxLine))
+}
+
+func (r Renderer) paint(colour string) string {
+	if r.Colour {
+		return colour
+	}
+
+	return ""
+}
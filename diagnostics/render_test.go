@@ -0,0 +1,124 @@
+package diagnostics_test
+
+import (
+	"clovis/diagnostics"
+	"strings"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	d := diagnostics.New(diagnostics.Redeclaration, diagnostics.NewSpan(3, 8, 1), "Redeclaration of symbol 'x'")
+	if received := d.Error(); received != "3:8: error[S011]: Redeclaration of symbol 'x'" {
+		t.Errorf("Unexpected error without a file: %q", received)
+	}
+
+	d.File = "main.clv"
+	if received := d.Error(); received != "main.clv:3:8: error[S011]: Redeclaration of symbol 'x'" {
+		t.Errorf("Unexpected error with a file: %q", received)
+	}
+
+	d.Span = diagnostics.Span{}
+	if received := d.Error(); received != "main.clv: error[S011]: Redeclaration of symbol 'x'" {
+		t.Errorf("Unexpected error without a position: %q", received)
+	}
+
+	d.File = ""
+	if received := d.Error(); received != "error[S011]: Redeclaration of symbol 'x'" {
+		t.Errorf("Unexpected error without a file or position: %q", received)
+	}
+}
+
+// Renders d without colours and fails unless the output matches expected.
+func expectRender(t *testing.T, d *diagnostics.Diagnostic, sources map[string]string, expected string) {
+	t.Helper()
+
+	out := strings.Builder{}
+	diagnostics.Renderer{ W: &out, Sources: sources }.Render(d)
+
+	if out.String() != expected {
+		t.Errorf("Expected:\n%v\nbut received:\n%v", expected, out.String())
+	}
+}
+
+func TestRenderSnippet(t *testing.T) {
+	sources := map[string]string{ "main.clv": "uint64 x = 1;\n\tuint64 x = 2;\r\n" }
+
+	d := diagnostics.New(diagnostics.Redeclaration, diagnostics.NewSpan(2, 9, 1), "Redeclaration of symbol 'x'")
+	d.File = "main.clv"
+	d.WithNote("main.clv", diagnostics.NewSpan(1, 1, 6), "'x' was declared here")
+	d.WithHint("Rename one of the symbols")
+
+	expectRender(t, d, sources, "" +
+		"error[S011]: Redeclaration of symbol 'x'\n" +
+		" --> main.clv:2:9\n" +
+		"  |\n" +
+		"2 | \tuint64 x = 2;\n" +
+		"  | \t       ^\n" +
+		"  = note: 'x' was declared here\n" +
+		" --> main.clv:1:1\n" +
+		"  |\n" +
+		"1 | uint64 x = 1;\n" +
+		"  | ^~~~~~\n" +
+		"  = hint: Rename one of the symbols\n",
+	)
+}
+
+// Spans covering several lines are underlined to the end of their first line.
+func TestRenderMultilineSpan(t *testing.T) {
+	d := diagnostics.New(diagnostics.SyntaxError, diagnostics.Span{
+		Start: diagnostics.Position{ Line: 10, Col: 5 },
+		End: diagnostics.Position{ Line: 11, Col: 2 },
+	}, "Expected ';'")
+	d.File = "main.clv"
+
+	expectRender(t, d, map[string]string{ "main.clv": strings.Repeat("\n", 9) + "x = äbc" }, "" +
+		"error[P001]: Expected ';'\n" +
+		"  --> main.clv:10:5\n" +
+		"   |\n" +
+		"10 | x = äbc\n" +
+		"   |     ^~~\n",
+	)
+}
+
+func TestRenderWithoutSource(t *testing.T) {
+	d := diagnostics.New(diagnostics.AssemblerFailed, diagnostics.Span{}, "Failed during assembling")
+	expectRender(t, d, nil, "error[B001]: Failed during assembling\n")
+
+	d.File = "main.clv"
+	expectRender(t, d, nil, "error[B001]: Failed during assembling\n --> main.clv\n")
+
+	// The location is still shown when the source is unknown.
+	d.Span = diagnostics.NewSpan(4, 2, 3)
+	expectRender(t, d, nil, "error[B001]: Failed during assembling\n --> main.clv:4:2\n")
+}
+
+func TestRenderColour(t *testing.T) {
+	d := diagnostics.New(diagnostics.TypeMismatch, diagnostics.Span{}, "Mismatch")
+	d.Severity = diagnostics.Warning
+
+	out := strings.Builder{}
+	diagnostics.Renderer{ W: &out, Colour: true }.Render(d)
+
+	if !strings.HasPrefix(out.String(), "\x1b[1;33mwarning[S001]\x1b[0m: \x1b[1mMismatch\x1b[0m") {
+		t.Errorf("Expected a yellow warning but received %q", out.String())
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := diagnostics.NewCollector()
+	if c.HasErrors() || c.Err() != nil {
+		t.Fatalf("Expected an empty collector to have no errors")
+	}
+
+	warning := c.Report(diagnostics.New(diagnostics.TypeMismatch, diagnostics.Span{}, "warning"))
+	warning.Severity = diagnostics.Warning
+	if c.HasErrors() {
+		t.Errorf("Expected warnings not to count as errors")
+	}
+
+	first := c.Report(diagnostics.New(diagnostics.TypeMismatch, diagnostics.Span{}, "first"))
+	c.Report(diagnostics.New(diagnostics.TypeMismatch, diagnostics.Span{}, "second"))
+	if c.Err() != first || len(c.Diagnostics) != 3 {
+		t.Errorf("Expected the first error out of 3 diagnostics but received %v of %v", c.Err(), c.Diagnostics)
+	}
+}
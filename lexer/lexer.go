@@ -1,38 +1,33 @@
 package lexer
 
 import (
+	"clovis/diagnostics"
 	"fmt"
 	"unicode"
+	"unicode/utf8"
 )
 
-type LexerError struct {
-	line int
-	col  int
-	val  string
-}
-
-func NewLexerError(val string, line int, col int) *LexerError {
-	return &LexerError{ val: val, line: line, col: col }
-}
-
-func (e *LexerError) Error() string {
-	return fmt.Sprintf("Unrecognized token '%v' at line %v at col %v", e.val, e.line, e.col)
-}
-
 type Lexer struct {
-	Tokens []Token
-	Errors []*LexerError
-	input  string
-	line   int
-	col    int
-	idx	   int
-	buffer string
+	Tokens      []Token
+	// The path of the lexed file, set on the reported diagnostics.
+	File        string
+	Diagnostics *diagnostics.Collector
+	input       string
+	line        int
+	// Counted in characters, idx is the byte offset in input.
+	col         int
+	idx         int
+	buffer      string
+	// Set after the asm keyword so the next '{' starts raw assembly text.
+	inAsm       bool
+	// The first error reported for this input.
+	err         error
 }
 
 func NewLexer(input string) *Lexer {
 	return &Lexer{
 		Tokens: []Token{},
-		Errors: []*LexerError{},
+		Diagnostics: diagnostics.NewCollector(),
 		input: input,
 		line: 1,
 		col: 1,
@@ -47,8 +42,7 @@ func (l *Lexer) Lex() error {
 			l.col = 1
 			l.idx++
 		} else if unicode.IsSpace(l.peek()) {
-			l.col++
-			l.idx++
+			l.skip()
 		} else if l.peek() == ';' {
 			l.consume()
 			l.emitToken(SEMI, l.col - 1)
@@ -61,6 +55,11 @@ func (l *Lexer) Lex() error {
 		} else if l.peek() == '{' {
 			l.consume()
 			l.emitToken(OPEN_CURLY, l.col - 1)
+
+			if l.inAsm {
+				l.inAsm = false
+				l.lexAsmText()
+			}
 		} else if l.peek() == '}' {
 			l.consume()
 			l.emitToken(CLOSE_CURLY, l.col - 1)
@@ -70,6 +69,28 @@ func (l *Lexer) Lex() error {
 		} else if l.peek() == ']' {
 			l.consume()
 			l.emitToken(CLOSE_BRACKET, l.col - 1)
+		} else if l.peek() == '.' {
+			l.consume()
+			if l.peek() == '.' {
+				l.consume()
+				if l.peek() == '.' {
+					l.consume()
+					l.emitToken(ELLIPSIS, l.col - 3)
+				} else {
+					l.emitToken(DOT_DOT, l.col - 2)
+				}
+			} else {
+				l.emitToken(DOT, l.col - 1)
+			}
+		} else if l.peek() == ':' {
+			l.consume()
+			l.emitToken(COLON, l.col - 1)
+		} else if l.peek() == ',' {
+			l.consume()
+			l.emitToken(COMMA, l.col - 1)
+		} else if l.peek() == '?' {
+			l.consume()
+			l.emitToken(QUESTION, l.col - 1)
 		} else if l.peek() == '&' {
 			l.consume()
 			l.emitToken(AMPERSAND, l.col - 1)
@@ -92,6 +113,7 @@ func (l *Lexer) Lex() error {
 		} else if l.peek() == '<' {
 			l.consume()
 			if l.peek() == '=' {
+				l.consume()
 				l.emitToken(LESS_EQ_THAN, l.col - 2)
 			} else {
 				l.emitToken(LESS_THAN, l.col - 1)
@@ -99,6 +121,7 @@ func (l *Lexer) Lex() error {
 		} else if l.peek() == '>' {
 			l.consume()
 			if l.peek() == '=' {
+				l.consume()
 				l.emitToken(GREATER_EQ_THAN, l.col - 2)
 			} else {
 				l.emitToken(GREATER_THAN, l.col - 1)
@@ -107,7 +130,7 @@ func (l *Lexer) Lex() error {
 			l.consume()
 			if l.peek() == '+' {
 				l.consume()
-				l.emitToken(PLUS_PLUS, l.col - 1)
+				l.emitToken(PLUS_PLUS, l.col - 2)
 			} else {
 				l.emitToken(PLUS, l.col - 1)
 			}
@@ -115,7 +138,7 @@ func (l *Lexer) Lex() error {
 			l.consume()
 			if l.peek() == '-' {
 				l.consume()
-				l.emitToken(MINUS_MINUS, l.col - 1)
+				l.emitToken(MINUS_MINUS, l.col - 2)
 			} else {
 				l.emitToken(MINUS, l.col - 1)
 			}
@@ -125,11 +148,13 @@ func (l *Lexer) Lex() error {
 		} else if l.peek() == '/' {
 			l.consume()
 			l.emitToken(F_SLASH, l.col - 1)
-		} else if unicode.IsDigit(l.peek()) {
+		} else if l.peek() == '"' {
+			l.lexString()
+		} else if isDigit(l.peek()) {
 			startCol := l.col
-			l.consume()	
+			l.consume()
 
-			for l.idx < len(l.input) && unicode.IsDigit(l.peek()) {
+			for l.idx < len(l.input) && isDigit(l.peek()) {
 				l.consume()
 			}
 
@@ -148,20 +173,27 @@ func (l *Lexer) Lex() error {
 			} else {
 				l.emitToken(IDENT, startCol)
 			}
+		} else {
+			l.lexIllegal()
 		}
 	}
 
-	l.emitToken(EOF, 0)
+	l.emitToken(EOF, l.col)
 
-	if errsLen := len(l.Errors); errsLen != 0 {
-		return l.Errors[errsLen - 1]
-	} else {
-		return nil
+	return l.err
+}
+
+func (l *Lexer) report(d *diagnostics.Diagnostic) {
+	d.File = l.File
+	l.Diagnostics.Report(d)
+	if l.err == nil && d.Severity == diagnostics.Error {
+		l.err = d
 	}
 }
 
+// Emits the consumed text as a token.
 func (l *Lexer) emitToken(tokenType TokenType, startCol int) {
-	l.Tokens = append(l.Tokens, *NewToken(tokenType, l.buffer, l.line, startCol))
+	l.Tokens = append(l.Tokens, *NewToken(tokenType, l.buffer, l.line, startCol, l.idx - len(l.buffer)))
 	l.buffer = ""
 }
 
@@ -203,21 +235,215 @@ func (l *Lexer) isKeyword(startCol int) bool {
 	case "assert":
 		l.emitToken(ASSERT, startCol)
 		return true
+	case "static_assert":
+		l.emitToken(STATIC_ASSERT, startCol)
+		return true
+	case "comptime":
+		l.emitToken(COMPTIME, startCol)
+		return true
+	case "sizeof":
+		l.emitToken(SIZEOF, startCol)
+		return true
+	case "alignof":
+		l.emitToken(ALIGNOF, startCol)
+		return true
+	case "typeof":
+		l.emitToken(TYPEOF, startCol)
+		return true
+	case "enum":
+		l.emitToken(ENUM, startCol)
+		return true
+	case "type":
+		l.emitToken(TYPE, startCol)
+		return true
+	case "union":
+		l.emitToken(UNION, startCol)
+		return true
+	case "match":
+		l.emitToken(MATCH, startCol)
+		return true
+	case "let":
+		l.emitToken(LET, startCol)
+		return true
+	case "defer":
+		l.emitToken(DEFER, startCol)
+		return true
+	case "syscall":
+		l.emitToken(SYSCALL, startCol)
+		return true
+	case "exit":
+		l.emitToken(EXIT, startCol)
+		return true
+	case "argc":
+		l.emitToken(ARGC, startCol)
+		return true
+	case "args":
+		l.emitToken(ARGS, startCol)
+		return true
+	case "envp":
+		l.emitToken(ENVP, startCol)
+		return true
+	case "new":
+		l.emitToken(NEW, startCol)
+		return true
+	case "delete":
+		l.emitToken(DELETE, startCol)
+		return true
+	case "asm":
+		l.emitToken(ASM, startCol)
+		l.inAsm = true
+		return true
+	case "none":
+		l.emitToken(NONE_LIT, startCol)
+		return true
+	case "as":
+		l.emitToken(AS, startCol)
+		return true
+	case "switch":
+		l.emitToken(SWITCH, startCol)
+		return true
+	case "case":
+		l.emitToken(CASE, startCol)
+		return true
+	case "default":
+		l.emitToken(DEFAULT, startCol)
+		return true
+	case "import":
+		l.emitToken(IMPORT, startCol)
+		return true
+	case "extern":
+		l.emitToken(EXTERN, startCol)
+		return true
+	case "spawn":
+		l.emitToken(SPAWN, startCol)
+		return true
+	case "join":
+		l.emitToken(JOIN, startCol)
+		return true
+	case "shared":
+		l.emitToken(SHARED, startCol)
+		return true
+	case "atomic_load":
+		l.emitToken(ATOMIC_LOAD, startCol)
+		return true
+	case "atomic_store":
+		l.emitToken(ATOMIC_STORE, startCol)
+		return true
+	case "atomic_add":
+		l.emitToken(ATOMIC_ADD, startCol)
+		return true
+	case "atomic_cas":
+		l.emitToken(ATOMIC_CAS, startCol)
+		return true
 	}
 
 	return false
 }
 
+// Consumes the raw text of an asm block up to its closing '}'.
+// Balanced braces such as the ones of {x} placeholders are part of the text.
+func (l *Lexer) lexAsmText() {
+	startLine, startCol, start := l.line, l.col, l.idx
+	depth := 0
+
+	for l.idx < len(l.input) {
+		if l.peek() == '}' && depth == 0 {
+			break
+		}
+
+		if l.peek() == '{' {
+			depth++
+		} else if l.peek() == '}' {
+			depth--
+		}
+
+		if l.peek() == '\n' {
+			l.consume()
+			l.line++
+			l.col = 1
+		} else {
+			l.consume()
+		}
+	}
+
+	l.Tokens = append(l.Tokens, *NewToken(ASM_TEXT, l.buffer, startLine, startCol, start))
+	l.buffer = ""
+}
+
+// Lexes a double quoted string. Strings cannot span multiple lines.
+func (l *Lexer) lexString() {
+	startCol, start := l.col, l.idx
+	l.consume() // '"'
+
+	for l.idx < len(l.input) && l.peek() != '"' && l.peek() != '\n' {
+		l.consume()
+	}
+
+	if l.peek() != '"' {
+		l.report(diagnostics.New(
+			diagnostics.UnterminatedString,
+			diagnostics.NewSpan(l.line, startCol, l.col - startCol),
+			"Unterminated string literal",
+		).WithHint("Strings cannot span multiple lines"))
+		l.emitToken(ILLEGAL, startCol)
+		return
+	}
+	l.skip() // '"'
+
+	l.Tokens = append(l.Tokens, *NewToken(STRING_LIT, l.buffer[1:], l.line, startCol, start))
+	l.buffer = ""
+}
+
+// Reports the character at idx, which starts no token, and emits it as an ILLEGAL token
+// so lexing goes on and every bad character of the file is reported.
+func (l *Lexer) lexIllegal() {
+	startCol := l.col
+	span := diagnostics.NewSpan(l.line, startCol, 1)
+
+	if r, size := utf8.DecodeRuneInString(l.input[l.idx:]); r == utf8.RuneError && size == 1 {
+		l.report(diagnostics.New(
+			diagnostics.InvalidEncoding,
+			span,
+			fmt.Sprintf("Invalid UTF-8 byte 0x%02X at offset %v", l.input[l.idx], l.idx),
+		).WithHint("Source files must be encoded in UTF-8"))
+	} else {
+		l.report(diagnostics.New(
+			diagnostics.UnknownCharacter,
+			span,
+			fmt.Sprintf("Unknown character '%c' (U+%04X) at offset %v", r, r, l.idx),
+		))
+	}
+
+	l.consume()
+	l.emitToken(ILLEGAL, startCol)
+}
+
+// Appends the character at idx to the buffer.
 func (l *Lexer) consume() {
-	l.buffer += string(l.input[l.idx])
+	_, size := utf8.DecodeRuneInString(l.input[l.idx:])
+	l.buffer += l.input[l.idx:l.idx + size]
 	l.col++
-	l.idx++
+	l.idx += size
 }
 
+// Moves past the character at idx without buffering it.
+func (l *Lexer) skip() {
+	_, size := utf8.DecodeRuneInString(l.input[l.idx:])
+	l.col++
+	l.idx += size
+}
+
+// Returns the character at idx, utf8.RuneError for bytes that are not valid UTF-8.
 func (l *Lexer) peek() rune {
 	if l.idx == len(l.input) {
 		return 0
 	}
 
-	return rune(l.input[l.idx])
+	r, _ := utf8.DecodeRuneInString(l.input[l.idx:])
+	return r
+}
+
+// Only ASCII digits start numbers, other digits can only appear in identifiers.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
 }
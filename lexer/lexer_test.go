@@ -0,0 +1,118 @@
+package lexer_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/lexer"
+	"fmt"
+	"testing"
+)
+
+// Lexes src and fails unless it reports exactly the given codes in order.
+func lex(t *testing.T, src string, codes ...diagnostics.Code) []lexer.Token {
+	t.Helper()
+
+	l := lexer.NewLexer(src)
+	l.File = "main.clv"
+	l.Lex()
+
+	received := []diagnostics.Code{}
+	for _, d := range l.Diagnostics.Diagnostics {
+		received = append(received, d.Code)
+		if d.File != "main.clv" {
+			t.Errorf("Expected the diagnostic to be in main.clv but received %v", d)
+		}
+	}
+	if fmt.Sprint(received) != fmt.Sprint(codes) {
+		t.Fatalf("Expected codes %v but received %v", codes, l.Diagnostics.Diagnostics)
+	}
+
+	return l.Tokens
+}
+
+// Fails unless the tokens have the given types.
+func expectTypes(t *testing.T, tokens []lexer.Token, types ...lexer.TokenType) {
+	t.Helper()
+
+	received := []lexer.TokenType{}
+	for _, token := range tokens {
+		received = append(received, token.Type)
+	}
+
+	if fmt.Sprint(received) != fmt.Sprint(types) {
+		t.Errorf("Expected tokens %v but received %v", types, received)
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	tokens := lex(t, "uint64 größe = 1;\nuint8 x٣_ü = größe;")
+	expectTypes(t, tokens,
+		lexer.UINT_64, lexer.IDENT, lexer.ASSIGN, lexer.UINT_64_LIT, lexer.SEMI,
+		lexer.UINT_8, lexer.IDENT, lexer.ASSIGN, lexer.IDENT, lexer.SEMI, lexer.EOF,
+	)
+
+	// Columns count characters while offsets count bytes.
+	size := tokens[1]
+	if size.Value != "größe" || size.Line != 1 || size.Col != 8 || size.Offset != 7 {
+		t.Errorf("Unexpected identifier %+v", size)
+	}
+	assign := tokens[2]
+	if assign.Col != 14 || assign.Offset != 15 {
+		t.Errorf("Expected '=' at column 14 and offset 15 but received %+v", assign)
+	}
+	if second := tokens[6]; second.Value != "x٣_ü" || second.Line != 2 || second.Col != 7 {
+		t.Errorf("Unexpected identifier %+v", second)
+	}
+}
+
+func TestOperators(t *testing.T) {
+	expectTypes(t, lex(t, "a <= b >= c < d > e == f != g ++ -- ... .."),
+		lexer.IDENT, lexer.LESS_EQ_THAN, lexer.IDENT, lexer.GREATER_EQ_THAN, lexer.IDENT,
+		lexer.LESS_THAN, lexer.IDENT, lexer.GREATER_THAN, lexer.IDENT, lexer.EQ, lexer.IDENT,
+		lexer.NEQ, lexer.IDENT, lexer.PLUS_PLUS, lexer.MINUS_MINUS, lexer.ELLIPSIS, lexer.DOT_DOT, lexer.EOF,
+	)
+}
+
+// Unknown characters become ILLEGAL tokens and lexing goes on, so every one of them is reported.
+func TestUnknownCharacters(t *testing.T) {
+	tokens := lex(t, "x % y | z @ é€",
+		diagnostics.UnknownCharacter, diagnostics.UnknownCharacter, diagnostics.UnknownCharacter, diagnostics.UnknownCharacter,
+	)
+	expectTypes(t, tokens,
+		lexer.IDENT, lexer.ILLEGAL, lexer.IDENT, lexer.ILLEGAL, lexer.IDENT, lexer.ILLEGAL, lexer.IDENT, lexer.ILLEGAL, lexer.EOF,
+	)
+
+	l := lexer.NewLexer("é€")
+	if err := l.Lex(); err == nil || err.Error() != "1:2: error[L001]: Unknown character '€' (U+20AC) at offset 2" {
+		t.Errorf("Expected the euro sign at offset 2 but received %v", err)
+	}
+}
+
+func TestInvalidEncoding(t *testing.T) {
+	tokens := lex(t, "x\xff\xfey", diagnostics.InvalidEncoding, diagnostics.InvalidEncoding)
+	expectTypes(t, tokens, lexer.IDENT, lexer.ILLEGAL, lexer.ILLEGAL, lexer.IDENT, lexer.EOF)
+
+	if tokens[2].Offset != 2 || tokens[3].Col != 4 {
+		t.Errorf("Expected every invalid byte to take one column but received %+v", tokens)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tokens := lex(t, `"héllo" "open`+"\n"+`x`, diagnostics.UnterminatedString)
+	expectTypes(t, tokens, lexer.STRING_LIT, lexer.ILLEGAL, lexer.IDENT, lexer.EOF)
+
+	if tokens[0].Value != "héllo" || tokens[2].Line != 2 {
+		t.Errorf("Unexpected tokens %+v", tokens)
+	}
+}
+
+func TestAsmText(t *testing.T) {
+	tokens := lex(t, "asm (rax) { mov rax, {ü} }\nx")
+	expectTypes(t, tokens,
+		lexer.ASM, lexer.OPEN_PAREN, lexer.IDENT, lexer.CLOSE_PAREN, lexer.OPEN_CURLY,
+		lexer.ASM_TEXT, lexer.CLOSE_CURLY, lexer.IDENT, lexer.EOF,
+	)
+
+	if text := tokens[5]; text.Value != " mov rax, {ü} " || text.Col != 12 {
+		t.Errorf("Expected the assembly between the braces but received %+v", text)
+	}
+}
@@ -1,6 +1,10 @@
 package lexer
 
-import "fmt"
+import (
+	"clovis/diagnostics"
+	"fmt"
+	"unicode/utf8"
+)
 
 type TokenType string
 const (
@@ -17,11 +21,50 @@ const (
 	UINT_8 = "UINT_8"
 	BOOL = "BOOL"
 	ASSERT = "ASSERT"
+	STATIC_ASSERT = "STATIC_ASSERT"
+	COMPTIME = "COMPTIME"
+	SIZEOF = "SIZEOF"
+	ALIGNOF = "ALIGNOF"
+	TYPEOF = "TYPEOF"
+	ENUM = "ENUM"
+	TYPE = "TYPE"
+	UNION = "UNION"
+	MATCH = "MATCH"
+	LET = "LET"
+	DEFER = "DEFER"
+	ASM = "ASM"
+	SYSCALL = "SYSCALL"
+	EXIT = "EXIT"
+	ARGC = "ARGC"
+	ARGS = "ARGS"
+	ENVP = "ENVP"
+	NEW = "NEW"
+	DELETE = "DELETE"
+	AS = "AS"
+	SWITCH = "SWITCH"
+	CASE = "CASE"
+	DEFAULT = "DEFAULT"
+	IMPORT = "IMPORT"
+	EXTERN = "EXTERN"
+	SPAWN = "SPAWN"
+	JOIN = "JOIN"
+	SHARED = "SHARED"
+	ATOMIC_LOAD = "ATOMIC_LOAD"
+	ATOMIC_STORE = "ATOMIC_STORE"
+	ATOMIC_ADD = "ATOMIC_ADD"
+	ATOMIC_CAS = "ATOMIC_CAS"
 
 	UINT_64_LIT = "UINT_64_LIT"
 	TRUE_LIT = "TRUE_LIT"
 	FALSE_LIT = "FALSE_LIT"
+	NONE_LIT = "NONE_LIT"
+	// The contents of a double quoted string without the quotes.
+	STRING_LIT = "STRING_LIT"
 	IDENT = "IDENT"
+	// The raw text of an asm block.
+	ASM_TEXT = "ASM_TEXT"
+	// Input the lexer reported as invalid, such as an unknown character or an unterminated string.
+	ILLEGAL = "ILLEGAL"
 
 	OPEN_PAREN = "OPEN_PAREN"
 	CLOSE_PAREN = "CLOSE_PAREN"
@@ -44,24 +87,44 @@ const (
 	F_SLASH = "F_SLASH"
 	ASSIGN = "ASSIGN"
 	AMPERSAND = "AMPERSAND"
+	DOT = "DOT"
+	DOT_DOT = "DOT_DOT"
+	ELLIPSIS = "ELLIPSIS"
+	COLON = "COLON"
+	COMMA = "COMMA"
+	QUESTION = "QUESTION"
 )
 
 type Token struct {
-	Type  TokenType
-	Value string
-	Line  int
-	Col   int
+	Type   TokenType
+	Value  string
+	Line   int
+	// Counted in characters from 1.
+	Col    int
+	// The byte offset of the token's start in the file.
+	Offset int
 }
 
-func NewToken(tokenType TokenType, value string, line int, col int) *Token {
+func NewToken(tokenType TokenType, value string, line int, col int, offset int) *Token {
 	return &Token{
 		Type: tokenType,
 		Value: value,
 		Line: line,
 		Col: col,
+		Offset: offset,
 	}
 }
 
 func (t Token) String() string {
 	return fmt.Sprintf("%v %v Line: %v Col: %v", t.Type, t.Value, t.Line, t.Col)
 }
+
+// Returns the source text the token was lexed from.
+func (t Token) Span() diagnostics.Span {
+	width := utf8.RuneCountInString(t.Value)
+	if t.Type == STRING_LIT {
+		width += 2 // The quotes
+	}
+
+	return diagnostics.NewSpan(t.Line, t.Col, width)
+}
@@ -0,0 +1,164 @@
+package loader
+
+import (
+	"clovis/diagnostics"
+	"clovis/lexer"
+	"clovis/parser"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// A parsed source file.
+type Module struct {
+	// The namespace of the module's top-level symbols. Empty for the main file.
+	Name   string
+	Path   string
+	// The tokens the module was parsed from.
+	Tokens []lexer.Token
+	Stmts  []parser.Statement
+}
+
+// The Loader lexes and parses a program's main file and every file it imports.
+type Loader struct {
+	// The loaded modules with every module placed after the modules it imports.
+	// The main file comes last.
+	Modules     []*Module
+	// Shared with the lexers and parsers of the loaded files.
+	Diagnostics *diagnostics.Collector
+	// Reads the source file at a cleaned path. Defaults to os.ReadFile.
+	ReadFile    func(path string) ([]byte, error)
+	// The modules by their cleaned path.
+	loaded      map[string]*Module
+	// The chain of imports currently being loaded. Used to detect import cycles.
+	stack       []string
+}
+
+func NewLoader() *Loader {
+	return &Loader{
+		Modules: []*Module{},
+		Diagnostics: diagnostics.NewCollector(),
+		ReadFile: os.ReadFile,
+		loaded: map[string]*Module{},
+	}
+}
+
+// Loads the main file at path along with all of its imports.
+func (l *Loader) Load(path string) error {
+	path = filepath.Clean(path)
+	if input, err := l.ReadFile(path); err != nil {
+		d := diagnostics.New(diagnostics.UnreadableFile, diagnostics.Span{}, err.Error())
+		d.File = path
+		l.Diagnostics.Report(d)
+	} else {
+		l.load(path, "", input)
+	}
+
+	return l.Diagnostics.Err()
+}
+
+// Reports an error at the path of an import in the importing file.
+func (l *Loader) report(importer *Module, stmt *parser.ImportStmt, code diagnostics.Code, msg string) *diagnostics.Diagnostic {
+	d := diagnostics.New(code, stmt.Path.Span(), msg)
+	d.File = importer.Path
+	return l.Diagnostics.Report(d)
+}
+
+func (l *Loader) load(path string, name string, input []byte) *Module {
+	l.Diagnostics.AddSource(path, string(input))
+
+	fileLexer := lexer.NewLexer(string(input))
+	fileLexer.File = path
+	fileLexer.Diagnostics = l.Diagnostics
+	lexErr := fileLexer.Lex()
+
+	module := &Module{ Name: name, Path: path, Tokens: fileLexer.Tokens, Stmts: []parser.Statement{} }
+	// The parser would only report the illegal tokens again, so files with lexer errors are not parsed.
+	if lexErr == nil {
+		fileParser := parser.NewParser(fileLexer.Tokens)
+		fileParser.File = path
+		fileParser.Diagnostics = l.Diagnostics
+		fileParser.Parse()
+		module.Stmts = fileParser.Stmts
+	}
+	l.loaded[path] = module
+	l.stack = append(l.stack, path)
+
+	for _, stmt := range module.Stmts {
+		if importStmt, isImport := stmt.(*parser.ImportStmt); isImport {
+			l.resolveImport(module, importStmt)
+		}
+	}
+
+	l.stack = l.stack[:len(l.stack) - 1]
+	l.Modules = append(l.Modules, module)
+
+	return module
+}
+
+// Loads the file an import refers to unless it was loaded before
+// and sets the namespace the import makes visible.
+func (l *Loader) resolveImport(importer *Module, stmt *parser.ImportStmt) {
+	path := filepath.Join(filepath.Dir(importer.Path), stmt.Path.Value)
+
+	for i, loading := range l.stack {
+		if loading == path {
+			cycle := append(append([]string{}, l.stack[i:]...), path)
+			l.report(
+				importer,
+				stmt,
+				diagnostics.ImportCycle,
+				fmt.Sprintf("Import cycle %v", strings.Join(cycle, " -> ")),
+			)
+			return
+		}
+	}
+
+	module, isLoaded := l.loaded[path]
+	if !isLoaded {
+		input, err := l.ReadFile(path)
+		if err != nil {
+			l.report(importer, stmt, diagnostics.UnreadableFile, err.Error())
+			return
+		}
+
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if !isIdent(name) {
+			l.report(
+				importer,
+				stmt,
+				diagnostics.InvalidModuleName,
+				fmt.Sprintf("Module name '%v' of %v is not a valid identifier", name, path),
+			).WithHint("Module names are taken from the file name, which must be a valid identifier")
+			return
+		}
+
+		for _, other := range l.loaded {
+			if other.Name == name {
+				l.report(
+					importer,
+					stmt,
+					diagnostics.DuplicateModuleName,
+					fmt.Sprintf("Modules %v and %v share the name '%v'", other.Path, path, name),
+				).WithHint("Rename one of the files")
+				return
+			}
+		}
+
+		module = l.load(path, name, input)
+	}
+
+	stmt.Module = module.Name
+}
+
+func isIdent(name string) bool {
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || r == '_' || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+
+	return name != ""
+}
@@ -0,0 +1,126 @@
+package loader_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/loader"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// Returns a loader reading the given files instead of the disk.
+func newLoader(files map[string]string) *loader.Loader {
+	l := loader.NewLoader()
+	l.ReadFile = func(path string) ([]byte, error) {
+		if src, exists := files[path]; exists {
+			return []byte(src), nil
+		}
+
+		return nil, fmt.Errorf("open %v: no such file or directory", path)
+	}
+
+	return l
+}
+
+// Loads main.clv from files and fails unless exactly the given codes are reported in order.
+func expectCodes(t *testing.T, files map[string]string, codes ...diagnostics.Code) *loader.Loader {
+	t.Helper()
+
+	l := newLoader(files)
+	l.Load("main.clv")
+
+	received := []diagnostics.Code{}
+	for _, d := range l.Diagnostics.Diagnostics {
+		received = append(received, d.Code)
+	}
+
+	if fmt.Sprint(received) != fmt.Sprint(codes) {
+		t.Fatalf("Expected codes %v but received %v", codes, l.Diagnostics.Diagnostics)
+	}
+
+	return l
+}
+
+func TestLoadOrder(t *testing.T) {
+	l := expectCodes(t, map[string]string{
+		"main.clv": `import "lib/math.clv"; import "io.clv";`,
+		"lib/math.clv": `import "vec.clv"; uint64 PI = 3;`,
+		"lib/vec.clv": `uint64 X = 1;`,
+		"io.clv": `import "lib/vec.clv";`,
+	})
+
+	want := []struct{ name string; path string }{
+		{ "vec", filepath.Join("lib", "vec.clv") },
+		{ "math", filepath.Join("lib", "math.clv") },
+		{ "io", "io.clv" },
+		{ "", "main.clv" },
+	}
+	if len(l.Modules) != len(want) {
+		t.Fatalf("Expected %v modules but received %v", len(want), len(l.Modules))
+	}
+
+	for i, module := range l.Modules {
+		if module.Name != want[i].name || module.Path != want[i].path {
+			t.Errorf("Expected module %v at %v but received %v at %v", want[i].name, want[i].path, module.Name, module.Path)
+		}
+	}
+}
+
+func TestImportCycle(t *testing.T) {
+	l := expectCodes(t, map[string]string{
+		"main.clv": `import "a.clv";`,
+		"a.clv": `import "b.clv";`,
+		"b.clv": `import "a.clv";`,
+	}, diagnostics.ImportCycle)
+
+	if d := l.Diagnostics.Diagnostics[0]; d.File != "b.clv" {
+		t.Errorf("Expected the cycle to be reported in b.clv but received %v", d.File)
+	}
+}
+
+func TestSelfImport(t *testing.T) {
+	expectCodes(t, map[string]string{ "main.clv": `import "main.clv";` }, diagnostics.ImportCycle)
+}
+
+func TestImportErrors(t *testing.T) {
+	expectCodes(t, map[string]string{ "main.clv": `import "missing.clv";` }, diagnostics.UnreadableFile)
+	expectCodes(t, map[string]string{
+		"main.clv": `import "my-lib.clv";`,
+		"my-lib.clv": ``,
+	}, diagnostics.InvalidModuleName)
+	expectCodes(t, map[string]string{
+		"main.clv": `import "a/math.clv"; import "b/math.clv";`,
+		"a/math.clv": ``,
+		"b/math.clv": ``,
+	}, diagnostics.DuplicateModuleName)
+}
+
+func TestUnicodeModuleName(t *testing.T) {
+	l := expectCodes(t, map[string]string{
+		"main.clv": `import "größe.clv";`,
+		"größe.clv": `uint64 X = 1;`,
+	})
+
+	if l.Modules[0].Name != "größe" {
+		t.Errorf("Expected the module größe but received %v", l.Modules[0].Name)
+	}
+}
+
+func TestUnreadableMain(t *testing.T) {
+	l := newLoader(map[string]string{})
+	if err := l.Load("main.clv"); err == nil {
+		t.Fatalf("Expected an error for a missing main file")
+	}
+
+	if d := l.Diagnostics.Diagnostics[0]; d.Code != diagnostics.UnreadableFile || d.File != "main.clv" {
+		t.Errorf("Expected %v in main.clv but received %v", diagnostics.UnreadableFile, d)
+	}
+}
+
+func TestImportSyntaxErrors(t *testing.T) {
+	expectCodes(t, map[string]string{ "main.clv": `import;` }, diagnostics.SyntaxError)
+	expectCodes(t, map[string]string{
+		"main.clv": `import "a.clv";`,
+		"a.clv": `uint64 = ;`,
+	}, diagnostics.SyntaxError)
+}
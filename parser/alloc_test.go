@@ -0,0 +1,52 @@
+package parser_test
+
+import (
+	"clovis/compiler"
+	"strings"
+	"testing"
+)
+
+func debugAlloc(opts *compiler.Options) {
+	opts.DebugAlloc = true
+}
+
+func TestNewSyntax(t *testing.T) {
+	expectSyntaxError(t, `uint32* p = new;`)
+	expectSyntaxError(t, `uint32* p = new uint32[1;`)
+	expectSyntaxError(t, `delete;`)
+}
+
+func TestAllocatorRuntime(t *testing.T) {
+	asm := golden(t, "alloc")
+	expectLines(t, asm, "call __clovis_alloc", "call __clovis_free", "__clovis_mmap:")
+
+	// The allocator is only linked in when the program allocates.
+	if strings.Contains(emit(t, `uint64 x = 1;`), "__clovis_alloc") {
+		t.Errorf("Expected no allocator in a program that does not allocate")
+	}
+}
+
+func TestDebugAllocatorKeepsFreedHeaders(t *testing.T) {
+	asm := golden(t, "alloc_debug", debugAlloc)
+	expectLines(t, asm, "je .double_free", "lea rsi, [rel __clovis_msg_double_free]")
+
+	// A freed mapped block keeps its header page, marked as freed, so a second free
+	// reads the freed tag instead of unmapped memory.
+	unmap := between(t, asm, ".unmap:", "syscall")
+	expectLines(t, unmap, "mov QWORD [rdi + 8], 0xf4eed", "add rdi, 4096", "sub rsi, 4096")
+}
+
+// Sizes that overflow while they are computed or rounded up fail as out of memory
+// instead of allocating a wrapped around, smaller block.
+func TestAllocationSizeOverflow(t *testing.T) {
+	asm := emit(t, `uint64 n = 3; uint32* p = new uint32[n];`)
+	expectLines(t, asm, "mov rcx, 4", "mul rcx", "jc __clovis_out_of_memory", "mov rdi, rax", "call __clovis_alloc")
+
+	alloc := between(t, asm, "__clovis_alloc:", ".search_start:")
+	expectLines(t, alloc, "add rdi, 15", "jc __clovis_out_of_memory", "and rdi, -16")
+
+	fresh := between(t, asm, ".fresh:", ".bump:")
+	expectLines(t, fresh, "add rdx, 16", "jc __clovis_out_of_memory")
+
+	expectLines(t, asm, "__clovis_out_of_memory:", "lea rsi, [rel __clovis_msg_out_of_memory]", "__clovis_fail:")
+}
@@ -0,0 +1,49 @@
+package parser_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"strings"
+	"testing"
+)
+
+// Placeholders name identifiers the way the lexer reads them, Unicode letters and digits included.
+func TestAsmUnicodePlaceholders(t *testing.T) {
+	src := `
+		uint64 ü = 1;
+		uint64 x٣ = 2;
+		asm (rax) {
+			mov rax, {ü}
+			add rax, { x٣ }
+		}
+	`
+	stmts := check(t, src)
+
+	stmt := stmts[2].(*parser.AsmStmt)
+	if len(stmt.Symbols) != 2 || stmt.Symbols["ü"].Ident != "ü" || stmt.Symbols["x٣"].Ident != "x٣" {
+		t.Fatalf("Expected the symbols of ü and x٣ but received %v", stmt.Symbols)
+	}
+
+	asm := emit(t, src)
+	expectLines(t, asm, "mov rax, QWORD [rbp - 8]", "add rax, QWORD [rbp - 16]")
+	if strings.Contains(asm, "{") {
+		t.Errorf("Expected every placeholder to be substituted but received:\n%v", asm)
+	}
+
+	expectCodes(t, `asm (rax) { mov rax, {größe} }`, diagnostics.UndeclaredSymbol)
+}
+
+func TestAsmSyntax(t *testing.T) {
+	expectSyntaxError(t, `asm (1) { nop }`)
+	expectSyntaxError(t, `asm (rax { nop }`)
+}
+
+func TestAsmSavesCalleeSavedRegisters(t *testing.T) {
+	asm := golden(t, "asm")
+	expectLines(t, asm,
+		"push r12",
+		"mov r12, QWORD [rbp - 8]",
+		"mov QWORD [rbp - 8], r12",
+		"pop r12",
+	)
+}
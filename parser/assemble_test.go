@@ -0,0 +1,48 @@
+package parser_test
+
+import (
+	"clovis/compiler"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The options the testdata programs are compiled with, by name. Unlisted programs use the defaults.
+var testdataOptions = map[string][]func(*compiler.Options){
+	"alloc_debug": { debugAlloc },
+	"library": { library, debugAlloc },
+}
+
+// Runs nasm on the assembly of every testdata program, so the emitted code is at least valid assembly.
+func TestTestdataAssembles(t *testing.T) {
+	if _, err := exec.LookPath("nasm"); err != nil {
+		t.Skip("nasm is not installed")
+	}
+
+	sources, err := filepath.Glob(filepath.Join("testdata", "*.clv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range sources {
+		name := strings.TrimSuffix(filepath.Base(path), ".clv")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			asmFile := filepath.Join(t.TempDir(), name + ".asm")
+			if err := os.WriteFile(asmFile, []byte(emit(t, string(src), testdataOptions[name]...)), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			nasm := exec.Command("nasm", "-f", "elf64", asmFile, "-o", filepath.Join(t.TempDir(), name + ".o"))
+			if output, err := nasm.CombinedOutput(); err != nil {
+				t.Errorf("nasm rejected the assembly of %v: %v\n%s", path, err, output)
+			}
+		})
+	}
+}
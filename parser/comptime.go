@@ -0,0 +1,757 @@
+package parser
+
+import (
+	"clovis/diagnostics"
+	"clovis/lexer"
+	"clovis/semantics"
+	"clovis/utils"
+	"fmt"
+)
+
+// The number of statements and loop iterations a compile-time evaluation may run.
+const comptimeStepLimit = 10000000
+
+// The size of the simulated stack of compile-time evaluations.
+const comptimeStackSize = 1 << 16
+
+// A value as the emitted code holds it in registers.
+// Numbers are held in Scalar, aggregates as their address in Scalar
+// and slices as their pointer in Scalar and their length in Len.
+type comptimeValue struct {
+	Scalar uint64
+	Len    uint64
+}
+
+// The interpreter runs checked statements and expressions during semantic analysis.
+// Variables of comptime blocks live at the address of their label in the checker's comptime memory.
+// Stack variables live in a simulated frame that mirrors the rbp offsets of the emitted code.
+type interpreter struct {
+	s          *semantics.SemanticChecker
+	mem        *semantics.Memory
+	// The address rbp stands for.
+	frame      uint64
+	// Stack variables at or below this offset belong to the runtime frame and have no value.
+	frameStart int
+	steps      int
+	// The innermost running loop. Runaway evaluations are reported at it.
+	loop       utils.Optional[lexer.Token]
+	// Errors without a more precise position are reported at this token.
+	token      lexer.Token
+}
+
+func newInterpreter(s *semantics.SemanticChecker, token lexer.Token, frameStart int) *interpreter {
+	mem := s.ComptimeMemory()
+	if mem.Stack == 0 {
+		mem.Stack = mem.Alloc(comptimeStackSize, 16)
+	}
+
+	return &interpreter{
+		s: s,
+		mem: mem,
+		frame: mem.Stack + comptimeStackSize + uint64(frameStart),
+		frameStart: frameStart,
+		token: token,
+	}
+}
+
+func (in *interpreter) fail(token lexer.Token, msg string) *diagnostics.Diagnostic {
+	return in.s.AddError(diagnostics.ComptimeEvaluation, msg, token)
+}
+
+// Counts a step of the evaluation and fails once the step limit is exceeded.
+func (in *interpreter) step() error {
+	in.steps++
+	if in.steps <= comptimeStepLimit {
+		return nil
+	}
+
+	token := in.token
+	if in.loop.HasVal() {
+		token = in.loop.Value()
+	}
+
+	return in.fail(
+		token,
+		fmt.Sprintf("Compile-time evaluation did not finish within %v steps", comptimeStepLimit),
+	)
+}
+
+// Runs a statement.
+func (in *interpreter) exec(stmt Statement) error {
+	if err := in.step(); err != nil {
+		return err
+	}
+
+	switch stmt := stmt.(type) {
+	case *VarDeclStmt:
+		return in.execVarDecl(stmt)
+	case *VarDefinitionStmt:
+		addr, err := in.address(stmt.Left)
+		if err != nil {
+			return err
+		}
+
+		return in.assign(addr, stmt.Left.ExprType(), stmt.Right)
+	case *BlockStmt:
+		for _, innerStmt := range stmt.Statements {
+			if err := in.exec(innerStmt); err != nil {
+				return err
+			}
+		}
+
+		for i := len(stmt.Deferred) - 1; i >= 0; i-- {
+			if err := in.exec(stmt.Deferred[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case *IfStmt:
+		condition, err := in.eval(stmt.Condition)
+		if err != nil {
+			return err
+		}
+
+		if condition.Scalar != 0 {
+			return in.exec(stmt.Stmt)
+		} else if stmt.ElseStmt.HasVal() {
+			return in.exec(stmt.ElseStmt.Value())
+		}
+
+		return nil
+	case *IfLetStmt:
+		return in.execIfLet(stmt)
+	case *WhileStmt:
+		return in.execWhile(stmt)
+	case *SwitchStmt:
+		return in.execSwitch(stmt)
+	case *MatchStmt:
+		return in.execMatch(stmt)
+	case *AssertStmt:
+		condition, err := in.eval(stmt.Expr)
+		if err != nil {
+			return err
+		}
+
+		if condition.Scalar == 0 {
+			return in.fail(stmt.AssertToken, "Assertion failed during compile-time evaluation")
+		}
+
+		return nil
+	case *ExpressionStmt:
+		_, err := in.eval(stmt.Expr)
+		return err
+	case *DeferStmt:
+		// Run by the enclosing block.
+		return nil
+	case *StaticAssertStmt, *EnumDeclStmt, *TypeDeclStmt, *UnionDeclStmt, *ExternDeclStmt, *ImportStmt:
+		// Fully handled during semantics.
+		return nil
+	case *ComptimeBlockStmt:
+		// Already ran during its own semantics.
+		return nil
+	case *AsmStmt:
+		return in.fail(stmt.AsmToken, "asm blocks cannot run at compile time")
+	case *ExitStmt:
+		return in.fail(stmt.ExitToken, "exit cannot run at compile time")
+	case *DeleteStmt:
+		return in.fail(stmt.DeleteToken, "delete cannot run at compile time")
+	case *JoinStmt:
+		return in.fail(stmt.JoinToken, "join cannot run at compile time")
+	}
+
+	return in.fail(in.token, fmt.Sprintf("%T cannot run at compile time", stmt))
+}
+
+func (in *interpreter) execVarDecl(stmt *VarDeclStmt) error {
+	if stmt.Shared {
+		return in.fail(stmt.Ident, "Shared variables cannot be declared at compile time")
+	}
+
+	var addr uint64
+	if stmt.Symbol.Comptime {
+		addr = in.mem.Alloc(stmt.Type.Size(), stmt.Type.Align())
+		in.mem.Labels[stmt.Symbol.Label] = addr
+	} else {
+		var err error
+		if addr, err = in.variable(stmt.Symbol, stmt.Ident); err != nil {
+			return err
+		}
+
+		if err := in.mem.Clear(addr, stmt.Type.Size()); err != nil {
+			return in.fail(stmt.Ident, err.Error())
+		}
+	}
+
+	if !stmt.Right.HasVal() {
+		return nil
+	}
+
+	return in.assign(addr, stmt.Type, stmt.Right.Value())
+}
+
+func (in *interpreter) execIfLet(stmt *IfLetStmt) error {
+	subject, err := in.eval(stmt.Subject)
+	if err != nil {
+		return err
+	}
+
+	hasValue, err := in.mem.Load(subject.Scalar, 1)
+	if err != nil {
+		return in.fail(stmt.IfToken, err.Error())
+	}
+
+	if hasValue == 0 {
+		if stmt.ElseStmt.HasVal() {
+			return in.exec(stmt.ElseStmt.Value())
+		}
+		return nil
+	}
+
+	optional := semantics.Underlying(stmt.Subject.ExprType()).(semantics.Optional)
+	addr, err := in.variable(stmt.Symbol, stmt.Ident)
+	if err != nil {
+		return err
+	}
+
+	if err := in.mem.Copy(addr, subject.Scalar + uint64(optional.ValueOffset()), optional.Base.Size()); err != nil {
+		return in.fail(stmt.IfToken, err.Error())
+	}
+
+	return in.exec(stmt.Stmt)
+}
+
+func (in *interpreter) execWhile(stmt *WhileStmt) error {
+	outerLoop := in.loop
+	in.loop = *utils.Some(stmt.WhileToken)
+	defer func() { in.loop = outerLoop }()
+
+	for {
+		if err := in.step(); err != nil {
+			return err
+		}
+
+		condition, err := in.eval(stmt.Condition)
+		if err != nil {
+			return err
+		}
+
+		if condition.Scalar == 0 {
+			return nil
+		}
+
+		if err := in.exec(stmt.Stmt); err != nil {
+			return err
+		}
+	}
+}
+
+func (in *interpreter) execSwitch(stmt *SwitchStmt) error {
+	subject, err := in.eval(stmt.Subject)
+	if err != nil {
+		return err
+	}
+
+	var fallback Statement
+	for _, arm := range stmt.Arms {
+		if arm.IsDefault {
+			fallback = arm.Stmt
+			continue
+		}
+
+		for _, c := range arm.Cases {
+			if c.LowValue <= subject.Scalar && subject.Scalar <= c.HighValue {
+				return in.exec(arm.Stmt)
+			}
+		}
+	}
+
+	if fallback != nil {
+		return in.exec(fallback)
+	}
+
+	return nil
+}
+
+func (in *interpreter) execMatch(stmt *MatchStmt) error {
+	union := semantics.Underlying(stmt.Subject.ExprType()).(semantics.Union)
+	subject, err := in.eval(stmt.Subject)
+	if err != nil {
+		return err
+	}
+
+	tag, err := in.mem.Load(subject.Scalar, union.TagType().Size())
+	if err != nil {
+		return in.fail(stmt.MatchToken, err.Error())
+	}
+
+	for _, arm := range stmt.Arms {
+		if !arm.IsDefault && uint64(arm.Tag) != tag {
+			continue
+		}
+
+		for i, symbol := range arm.Symbols {
+			fieldOffset := union.Variants[arm.Tag].Offsets[arm.Fields[i]]
+			addr, err := in.variable(symbol, symbol.Token)
+			if err != nil {
+				return err
+			}
+
+			if err := in.mem.Copy(addr, subject.Scalar + uint64(fieldOffset), symbol.Size); err != nil {
+				return in.fail(symbol.Token, err.Error())
+			}
+		}
+
+		return in.exec(arm.Stmt)
+	}
+
+	return nil
+}
+
+// Returns the address of a variable.
+func (in *interpreter) variable(symbol semantics.Symbol, token lexer.Token) (uint64, error) {
+	if symbol.Label != "" {
+		addr, hasValue := in.mem.Labels[symbol.Label]
+		if !hasValue {
+			return 0, in.fail(token, fmt.Sprintf("'%v' has no value at compile time", symbol.Ident))
+		}
+
+		return addr, nil
+	}
+
+	if symbol.Offset <= in.frameStart {
+		return 0, in.fail(
+			token,
+			fmt.Sprintf("'%v' is a runtime variable and has no value at compile time", symbol.Ident),
+		).WithHint("Declare it inside a comptime block to compute it during compilation")
+	}
+
+	if symbol.Offset - in.frameStart > comptimeStackSize {
+		return 0, in.fail(token, "Compile-time evaluation ran out of stack")
+	}
+
+	return in.frame - uint64(symbol.Offset), nil
+}
+
+// Evaluates expr into the destination at addr of type t.
+// Union constructors and optionals are built in place like in the emitted code.
+func (in *interpreter) assign(addr uint64, t semantics.Type, expr Expression) error {
+	switch expr := expr.(type) {
+	case *UnionConstructorExpression:
+		return in.construct(addr, expr)
+	case *OptionalExpression:
+		return in.wrap(addr, expr)
+	}
+
+	value, err := in.eval(expr)
+	if err != nil {
+		return err
+	}
+
+	return in.store(addr, t, value, exprToken(expr, in.token))
+}
+
+func (in *interpreter) construct(addr uint64, exp *UnionConstructorExpression) error {
+	union := exp.Type.(semantics.Union)
+	variant := union.Variants[exp.Tag]
+
+	for i, arg := range exp.Args {
+		if err := in.assign(addr + uint64(variant.Offsets[i]), variant.Fields[i], arg); err != nil {
+			return err
+		}
+	}
+
+	if err := in.mem.Store(addr, union.TagType().Size(), uint64(exp.Tag)); err != nil {
+		return in.fail(exp.OpenParen, err.Error())
+	}
+
+	return nil
+}
+
+func (in *interpreter) wrap(addr uint64, exp *OptionalExpression) error {
+	hasValue := uint64(0)
+	if exp.Value.ExprType().TypeID() != semantics.NONE_LIT {
+		hasValue = 1
+		if err := in.assign(addr + uint64(exp.Type.ValueOffset()), exp.Type.Base, exp.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := in.mem.Store(addr, 1, hasValue); err != nil {
+		return in.fail(exprToken(exp.Value, in.token), err.Error())
+	}
+
+	return nil
+}
+
+// Loads a value of the given type from addr.
+func (in *interpreter) load(addr uint64, t semantics.Type, token lexer.Token) (comptimeValue, error) {
+	var value comptimeValue
+	var err error
+
+	switch semantics.Underlying(t).(type) {
+	case semantics.Array, semantics.Union, semantics.Optional:
+		value.Scalar = addr
+		_, err = in.mem.Read(addr, t.Size())
+	case semantics.Slice:
+		if value.Scalar, err = in.mem.Load(addr, 8); err == nil {
+			value.Len, err = in.mem.Load(addr + 8, 8)
+		}
+	default:
+		value.Scalar, err = in.mem.Load(addr, t.Size())
+	}
+
+	if err != nil {
+		return value, in.fail(token, err.Error())
+	}
+
+	return value, nil
+}
+
+// Stores a value of the given type to addr. Aggregates are copied from the address they evaluated to.
+func (in *interpreter) store(addr uint64, t semantics.Type, value comptimeValue, token lexer.Token) error {
+	var err error
+
+	switch semantics.Underlying(t).(type) {
+	case semantics.Array, semantics.Union, semantics.Optional:
+		err = in.mem.Copy(addr, value.Scalar, t.Size())
+	case semantics.Slice:
+		if err = in.mem.Store(addr, 8, value.Scalar); err == nil {
+			err = in.mem.Store(addr + 8, 8, value.Len)
+		}
+	default:
+		err = in.mem.Store(addr, t.Size(), value.Scalar)
+	}
+
+	if err != nil {
+		return in.fail(token, err.Error())
+	}
+
+	return nil
+}
+
+// Evaluates an expression.
+func (in *interpreter) eval(expr Expression) (comptimeValue, error) {
+	if value, isConst := constantValue(expr); isConst {
+		return comptimeValue{ Scalar: value }, nil
+	}
+
+	switch expr := expr.(type) {
+	case *IdentExpression:
+		addr, err := in.variable(expr.Symbol, expr.Ident)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+
+		return in.load(addr, expr.Type, expr.Ident)
+	case *GroupExpression:
+		return in.eval(expr.Expr)
+	case *BinaryExpression:
+		if optional, ok := expr.NoneOperand(); ok {
+			addr, err := in.eval(optional)
+			if err != nil {
+				return comptimeValue{}, err
+			}
+
+			hasValue, err := in.mem.Load(addr.Scalar, 1)
+			if err != nil {
+				return comptimeValue{}, in.fail(expr.Op, err.Error())
+			}
+
+			if expr.Op.Value == "==" {
+				return comptimeValue{ Scalar: boolValue(hasValue == 0) }, nil
+			}
+			return comptimeValue{ Scalar: boolValue(hasValue != 0) }, nil
+		}
+
+		left, err := in.eval(expr.Left)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+
+		right, err := in.eval(expr.Right)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+
+		value, isDefined := foldBinary(expr.Op.Value, left.Scalar, right.Scalar, expr.Type)
+		if !isDefined {
+			return comptimeValue{}, in.fail(expr.Op, "Division by zero during compile-time evaluation")
+		}
+
+		return comptimeValue{ Scalar: value }, nil
+	case *ConditionalExpression:
+		condition, err := in.eval(expr.Condition)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+
+		if condition.Scalar != 0 {
+			return in.eval(expr.Then)
+		}
+		return in.eval(expr.Else)
+	case *CastExpression:
+		value, err := in.eval(expr.Left)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+
+		value.Scalar &= semantics.MaxUint(min(expr.Left.ExprType().Size(), expr.Type.Size()))
+		return value, nil
+	case *MemberExpression:
+		if expr.Qualified != nil {
+			return in.eval(expr.Qualified)
+		}
+
+		if expr.Variant {
+			addr := in.mem.Alloc(expr.Type.Size(), expr.Type.Align())
+			copy(in.mem.Bytes[addr:], variantBytes(expr.Type.(semantics.Union), expr.Value))
+			return comptimeValue{ Scalar: addr }, nil
+		}
+
+		// Enum constants and array lengths are constants, so this is the length of a slice.
+		slice, err := in.eval(expr.Left)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+
+		return comptimeValue{ Scalar: slice.Len }, nil
+	case *ArrayAccessExpression:
+		addr, err := in.address(expr)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+
+		return in.load(addr, expr.Type, expr.OpenBracket)
+	case *DerefExpression:
+		addr, err := in.address(expr)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+
+		return in.load(addr, expr.Type, expr.Op)
+	case *ReferenceExpression:
+		addr, err := in.address(expr.Right)
+		return comptimeValue{ Scalar: addr }, err
+	case *SliceExpression:
+		return in.slice(expr)
+	case *UnionConstructorExpression:
+		addr := in.mem.Alloc(expr.Type.Size(), expr.Type.Align())
+		return comptimeValue{ Scalar: addr }, in.construct(addr, expr)
+	case *OptionalExpression:
+		addr := in.mem.Alloc(expr.Type.Size(), expr.Type.Align())
+		return comptimeValue{ Scalar: addr }, in.wrap(addr, expr)
+	case *ComptimeExpression:
+		addr := in.mem.Alloc(len(expr.Data), expr.Type.Align())
+		copy(in.mem.Bytes[addr:], expr.Data)
+		return comptimeValue{ Scalar: addr }, nil
+	case *AtomicExpression:
+		return in.atomic(expr)
+	}
+
+	token := exprToken(expr, in.token)
+	return comptimeValue{}, in.fail(token, fmt.Sprintf("'%v' cannot be evaluated at compile time", token.Value))
+}
+
+// Returns the address of an addressable expression.
+func (in *interpreter) address(expr Expression) (uint64, error) {
+	switch expr := expr.(type) {
+	case *IdentExpression:
+		return in.variable(expr.Symbol, expr.Ident)
+	case *GroupExpression:
+		return in.address(expr.Expr)
+	case *MemberExpression:
+		if expr.Qualified != nil {
+			return in.address(expr.Qualified)
+		}
+	case *ArrayAccessExpression:
+		base, length, err := in.elements(expr.Left)
+		if err != nil {
+			return 0, err
+		}
+
+		index, err := in.eval(expr.IndexExpr)
+		if err != nil {
+			return 0, err
+		}
+
+		if index.Scalar >= length {
+			return 0, in.fail(
+				expr.OpenBracket,
+				fmt.Sprintf("Index %v is out of range for length %v", index.Scalar, length),
+			)
+		}
+
+		return base + index.Scalar * uint64(expr.Type.Size()), nil
+	case *DerefExpression:
+		ptr, err := in.eval(expr.Right)
+		if err != nil {
+			return 0, err
+		}
+
+		if ptr.Scalar == 0 {
+			return 0, in.fail(expr.Op, "Null pointer dereference during compile-time evaluation")
+		}
+
+		return ptr.Scalar, nil
+	}
+
+	return 0, in.fail(exprToken(expr, in.token), "Expression has no address at compile time")
+}
+
+// Returns the address of the first element and the length of an array or slice.
+func (in *interpreter) elements(expr Expression) (uint64, uint64, error) {
+	if array, isArray := semantics.Underlying(expr.ExprType()).(semantics.Array); isArray {
+		addr, err := in.address(expr)
+		return addr, uint64(array.Length), err
+	}
+
+	slice, err := in.eval(expr)
+	return slice.Scalar, slice.Len, err
+}
+
+func (in *interpreter) slice(expr *SliceExpression) (comptimeValue, error) {
+	base, length, err := in.elements(expr.Left)
+	if err != nil {
+		return comptimeValue{}, err
+	}
+
+	low, high := uint64(0), length
+	if expr.Low.HasVal() {
+		value, err := in.eval(expr.Low.Value())
+		if err != nil {
+			return comptimeValue{}, err
+		}
+		low = value.Scalar
+	}
+
+	if expr.High.HasVal() {
+		value, err := in.eval(expr.High.Value())
+		if err != nil {
+			return comptimeValue{}, err
+		}
+		high = value.Scalar
+	}
+
+	if low > high || high > length {
+		return comptimeValue{}, in.fail(
+			expr.OpenBracket,
+			fmt.Sprintf("Slice bounds %v..%v are out of range for length %v", low, high, length),
+		)
+	}
+
+	elementSize := uint64(semantics.Underlying(expr.Type).(semantics.Slice).Base.Size())
+	return comptimeValue{ Scalar: base + low * elementSize, Len: high - low }, nil
+}
+
+// There is a single thread at compile time, so atomics are plain loads and stores.
+func (in *interpreter) atomic(expr *AtomicExpression) (comptimeValue, error) {
+	args := []uint64{}
+	for _, arg := range expr.Args {
+		value, err := in.eval(arg)
+		if err != nil {
+			return comptimeValue{}, err
+		}
+		args = append(args, value.Scalar)
+	}
+
+	valueType := semantics.Underlying(expr.Args[0].ExprType()).(semantics.Ptr).ValueType
+	size := valueType.Size()
+
+	current, err := in.mem.Load(args[0], size)
+	if err != nil {
+		return comptimeValue{}, in.fail(expr.Op, err.Error())
+	}
+
+	var result, stored uint64
+	switch expr.Op.Type {
+	case lexer.ATOMIC_LOAD:
+		return comptimeValue{ Scalar: current }, nil
+	case lexer.ATOMIC_STORE:
+		stored = args[1]
+	case lexer.ATOMIC_ADD:
+		result, stored = current, current + args[1]
+	case lexer.ATOMIC_CAS:
+		if current != args[1] {
+			return comptimeValue{ Scalar: 0 }, nil
+		}
+		result, stored = 1, args[2]
+	}
+
+	if err := in.mem.Store(args[0], size, stored); err != nil {
+		return comptimeValue{}, in.fail(expr.Op, err.Error())
+	}
+
+	return comptimeValue{ Scalar: result }, nil
+}
+
+// Returns the token that best locates an expression in the source, or fallback when it has none.
+func exprToken(expr Expression, fallback lexer.Token) lexer.Token {
+	switch expr := expr.(type) {
+	case *IdentExpression:
+		return expr.Ident
+	case *LiteralExpression:
+		return expr.Value
+	case *GroupExpression:
+		return exprToken(expr.Expr, fallback)
+	case *BinaryExpression:
+		return expr.Op
+	case *ConditionalExpression:
+		return expr.Question
+	case *DerefExpression:
+		return expr.Op
+	case *ReferenceExpression:
+		return expr.Op
+	case *ArrayAccessExpression:
+		return expr.OpenBracket
+	case *SliceExpression:
+		return expr.OpenBracket
+	case *MemberExpression:
+		return expr.Member
+	case *CastExpression:
+		return expr.As
+	case *CallExpression:
+		return expr.Callee.Ident
+	case *UnionConstructorExpression:
+		return expr.OpenParen
+	case *SyscallExpression:
+		return expr.SyscallToken
+	case *NewExpression:
+		return expr.NewToken
+	case *SpawnExpression:
+		return expr.SpawnToken
+	case *AtomicExpression:
+		return expr.Op
+	case *ProcessValueExpression:
+		return expr.Token
+	case *TypeInfoExpression:
+		return expr.Op
+	case *ComptimeExpression:
+		return expr.ComptimeToken
+	}
+
+	return fallback
+}
+
+// Reports whether values of the type hold addresses.
+// Addresses of the interpreter's memory have no meaning in the emitted program.
+func holdsAddress(t semantics.Type) bool {
+	switch t := semantics.Underlying(t).(type) {
+	case semantics.Ptr, semantics.Slice:
+		return true
+	case semantics.Array:
+		return holdsAddress(t.Base)
+	case semantics.Optional:
+		return holdsAddress(t.Base)
+	case semantics.Union:
+		for _, variant := range t.Variants {
+			for _, field := range variant.Fields {
+				if holdsAddress(field) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
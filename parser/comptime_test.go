@@ -0,0 +1,15 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestComptimeSyntax(t *testing.T) {
+	expectSyntaxError(t, `comptime uint64 x = 1;`)
+	expectSyntaxError(t, `comptime { uint64 x = 1; `)
+}
+
+func TestComptimeData(t *testing.T) {
+	asm := golden(t, "comptime")
+	expectLines(t, asm, "section .data", "db 0x00, 0x01, 0x04, 0x09", "mov rax, 42")
+}
@@ -0,0 +1,15 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestConditionalSyntax(t *testing.T) {
+	expectSyntaxError(t, `uint8 y = true ? 1;`)
+	expectSyntaxError(t, `uint8 y = true ? : 2;`)
+}
+
+func TestConditionalEmitsBothBranches(t *testing.T) {
+	asm := golden(t, "conditional")
+	expectLines(t, asm, "mov rax, 10", "mov rax, 20")
+}
@@ -0,0 +1,26 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeferSyntax(t *testing.T) {
+	expectSyntaxError(t, `{ defer; }`)
+}
+
+func TestDeferRunsInReverseOrder(t *testing.T) {
+	asm := golden(t, "defer")
+
+	first := strings.Index(asm, "mov rax, 111")
+	second := strings.Index(asm, "mov rax, 222")
+	body := strings.Index(asm, "mov rax, 333")
+	if !(body < second && second < first) {
+		t.Errorf("Expected the body, then the second and the first deferred statement:\n%v", asm)
+	}
+
+	// The deferred statements run before the block releases its locals.
+	if strings.Index(asm, "add rsp, 8") < first {
+		t.Errorf("Expected the block's locals to be released after the deferred statements:\n%v", asm)
+	}
+}
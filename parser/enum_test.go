@@ -0,0 +1,15 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestEnumSyntax(t *testing.T) {
+	expectSyntaxError(t, `enum E { A }`)
+	expectSyntaxError(t, `enum E : uint8 { A = B }`)
+}
+
+func TestEnumEmitsBackingValues(t *testing.T) {
+	asm := golden(t, "enum")
+	expectLines(t, asm, "mov rax, 10", "mov BYTE [rbp - 1], al")
+}
@@ -0,0 +1,22 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestExternSyntax(t *testing.T) {
+	expectSyntaxError(t, `extern uint64 f(uint64`)
+	expectSyntaxError(t, `extern f(..., uint64 x);`)
+	expectSyntaxError(t, `extern f()`)
+	expectSyntaxError(t, `extern (uint64 x);`)
+}
+
+func TestExternCall(t *testing.T) {
+	asm := golden(t, "extern")
+	expectLines(t, asm, "extern $printf", "and rsp, -16", "xor eax, eax", "call $printf wrt ..plt", "mov rsp, QWORD [rsp]")
+
+	// Seven arguments leave one on the stack, it is padded so the call stays aligned and
+	// released together with the padding afterwards.
+	call := between(t, asm, "; CallExpression: seven", "mov rsp, QWORD [rsp]")
+	expectLines(t, call, "sub rsp, 8", "pop r9", "add rsp, 16")
+}
@@ -0,0 +1,15 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestIoBuiltinSyntax(t *testing.T) {
+	expectSyntaxError(t, `IoResult r = close(1;`)
+	expectSyntaxError(t, `uint8[4] buf; IoResult r = write(1 buf);`)
+}
+
+func TestIoBuiltinSyscalls(t *testing.T) {
+	asm := golden(t, "io")
+	expectLines(t, asm, "call __clovis_read_uint", "mov rax, 0", "mov rax, 1", "mov rax, 3", "cmp rax, -4096", "neg rax")
+}
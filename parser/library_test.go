@@ -0,0 +1,52 @@
+package parser_test
+
+import (
+	"clovis/compiler"
+	"strings"
+	"testing"
+)
+
+func library(opts *compiler.Options) {
+	opts.Target = compiler.Library
+}
+
+func TestLibraryEntry(t *testing.T) {
+	asm := emit(t, `exit(3);`, library)
+	expectLines(t, asm, "global main_main:function", "main_main:", "push rbp", "push rbx", "ret")
+
+	if strings.Contains(asm, "_start") {
+		t.Errorf("Expected no _start in a library:\n%v", asm)
+	}
+}
+
+// Failed checks return from the entry instead of ending the host process.
+func TestLibraryFailures(t *testing.T) {
+	asm := testdata(t, "library", library, debugAlloc)
+	if strings.Contains(asm, "mov rax, 231") {
+		t.Errorf("Expected no exit_group in a library:\n%v", asm)
+	}
+
+	// Every failure path, including the allocator's and a thread's, leaves through __clovis_abort.
+	expectLines(t, asm, "call __clovis_alloc", "call __clovis_free", "call __clovis_spawn", "call __clovis_join")
+	if strings.Count(asm, "jmp __clovis_abort") < 4 {
+		t.Errorf("Expected the assert, slice, allocator and thread failures to abort:\n%v", asm)
+	}
+
+	assert := between(t, asm, "AssertStmt", "jmp __clovis_abort")
+	expectLines(t, assert, "mov rdi, 1")
+	expectLines(t, between(t, asm, "__clovis_fail:", "jmp __clovis_abort"), "mov rdi, 1")
+	expectLines(t, between(t, asm, "SliceExpression", "jmp __clovis_abort"), "mov rdi, 1")
+
+	// On the entry's frame the status is returned, threads only end themselves.
+	abort := between(t, asm, "__clovis_abort:", "syscall")
+	expectLines(t, abort, "cmp rbp, QWORD [rel __clovis_entry_frame]", "je __clovis_return", "mov rax, 60")
+	expectLines(t, between(t, asm, "__clovis_return:", "pop rbp"), "mov rax, rdi", "mov rsp, rbp")
+}
+
+func TestExecutableFailures(t *testing.T) {
+	asm := emit(t, `assert 1 == 2;`)
+	expectLines(t, asm, "mov rax, 231")
+	if strings.Contains(asm, "__clovis_abort") {
+		t.Errorf("Expected executables to exit on failed checks:\n%v", asm)
+	}
+}
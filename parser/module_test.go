@@ -0,0 +1,31 @@
+package parser_test
+
+import (
+	"clovis/compiler"
+	"testing"
+)
+
+// Adds the imported files to the program.
+func withFiles(files map[string]string) func(*compiler.Options) {
+	return func(opts *compiler.Options) {
+		for path, src := range files {
+			opts.Sources[path] = src
+		}
+	}
+}
+
+var mathModule = withFiles(map[string]string{
+	"math.clv": `
+		uint64 PI = 3;
+		type Meters = uint64;
+		enum Axis : uint8 { X, Y }
+	`,
+})
+
+func TestModuleCode(t *testing.T) {
+	// Imported modules are emitted first so their globals are initialized before main reads them.
+	asm := emit(t, `import "math.clv"; uint64 pi = math.PI; exit(pi);`, mathModule)
+	main := between(t, asm, "ImportStmt: math.clv", "jmp __clovis_exit")
+	expectLines(t, asm, "mov QWORD [rbp - 8], rax")
+	expectLines(t, main, "mov rax, QWORD [rbp - 8]", "mov QWORD [rbp - 16], rax")
+}
@@ -2,17 +2,125 @@ package parser
 
 import (
 	"clovis/codegen"
+	"clovis/diagnostics"
 	"clovis/lexer"
 	"clovis/semantics"
 	"clovis/utils"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 func indentStr(n int) string {
 	return strings.Repeat("  ", n)
 }
 
+// Returns whether values of the type evaluate to their address and are copied byte by byte.
+func isAggregate(t semantics.Type) bool {
+	switch semantics.Underlying(t).(type) {
+	case semantics.Array, semantics.Union, semantics.Optional:
+		return true
+	}
+
+	return false
+}
+
+// Loads a value of the given type from the address expression addr into rax.
+// Aggregates evaluate to their address and slices to their pointer in rax and length in rdx.
+func emitLoad(e *codegen.Emitter, t semantics.Type, addr string) {
+	switch semantics.Underlying(t).(type) {
+	case semantics.Array, semantics.Union, semantics.Optional:
+		fmt.Fprintf(e, "lea rax, [%v]\n", addr)
+	case semantics.Slice:
+		fmt.Fprintf(e, "mov rdx, QWORD [%v + 8]\n", addr)
+		fmt.Fprintf(e, "mov rax, QWORD [%v]\n", addr)
+	default:
+		e.LoadRax(t.Size(), fmt.Sprintf("[%v]", addr))
+	}
+}
+
+// Stores a value of the given type held in rax (and rdx for slices) to the address expression addr.
+// Aggregates are copied with rep movsb by the callers.
+func emitStore(e *codegen.Emitter, t semantics.Type, addr string) {
+	if _, isSlice := semantics.Underlying(t).(semantics.Slice); isSlice {
+		fmt.Fprintf(e, "mov QWORD [%v], rax\n", addr)
+		fmt.Fprintf(e, "mov QWORD [%v + 8], rdx\n", addr)
+		return
+	}
+
+	fmt.Fprintf(e, "mov %v [%v], %v\n", t.ASMSize(), addr, t.Register())
+}
+
+// Evaluates expr into the destination whose address is at [rsp], offset bytes in.
+// t is the type of the destination.
+func emitInPlaceStore(e *codegen.Emitter, expr Expression, t semantics.Type, offset int) {
+	if inPlace, isInPlace := expr.(InPlaceExpression); isInPlace {
+		fmt.Fprintf(e, "mov rax, QWORD [rsp]\n")
+		fmt.Fprintf(e, "add rax, %v\n", offset)
+		inPlace.EmitCode(e)
+	} else if isAggregate(t) {
+		expr.EmitCode(e)
+		fmt.Fprintf(e, "mov rsi, rax\n")
+		fmt.Fprintf(e, "mov rdi, QWORD [rsp]\n")
+		fmt.Fprintf(e, "add rdi, %v\n", offset)
+		fmt.Fprintf(e, "mov rcx, %v\n", t.Size())
+		fmt.Fprintf(e, "rep movsb\n")
+	} else {
+		expr.EmitCode(e)
+		fmt.Fprintf(e, "mov rbx, QWORD [rsp]\n")
+		emitStore(e, t, fmt.Sprintf("rbx + %v", offset))
+	}
+}
+
+// Returns the module an identifier refers to when it names an imported module.
+func moduleName(s *semantics.SemanticChecker, expr Expression) (lexer.Token, bool) {
+	ident, isIdent := expr.(*IdentExpression)
+	if !isIdent || !s.LookupModule(ident.Ident.Value) {
+		return lexer.Token{}, false
+	}
+
+	return ident.Ident, true
+}
+
+// Returns the user defined type an identifier or a module qualified name such as math.Vec refers to.
+func lookupTypeName(s *semantics.SemanticChecker, expr Expression) (semantics.Type, bool) {
+	if ident, isIdent := expr.(*IdentExpression); isIdent {
+		return s.LookupType(ident.Ident.Value)
+	}
+
+	member, isMember := expr.(*MemberExpression)
+	if !isMember {
+		return semantics.Undefined{}, false
+	}
+
+	module, isModule := moduleName(s, member.Left)
+	if !isModule {
+		return semantics.Undefined{}, false
+	}
+
+	symbol, exists := s.LookupQualified(module.Value, member.Member.Value)
+	if !exists || !symbol.IsType {
+		return semantics.Undefined{}, false
+	}
+
+	return symbol.Type, true
+}
+
+// Wraps expr in an OptionalExpression when it is used where the optional type t is expected.
+func wrapOptional(expr Expression, t semantics.Type) Expression {
+	if !semantics.WrapsInto(expr.ExprType(), t) {
+		return expr
+	}
+
+	return &OptionalExpression{
+		Type: t.(semantics.Optional),
+		Value: expr,
+		InPlace: true,
+	}
+}
+
 // This interface represents a statement in the language
 // and holds the needed functions for semantic analysis and code generation.
 type Statement interface {
@@ -26,22 +134,46 @@ type Statement interface {
 }
 
 // Variable declaration statement.
+// Example:
+//  uint32 x = 1;
+//  shared uint64 counter = 0; // Lives in static storage and is seen by every thread
+// Shared variables are assigned their value whenever the declaration runs.
 type VarDeclStmt struct {
 	Type   semantics.Type
 	Ident  lexer.Token
 	Right  utils.Optional[Expression]
 	Symbol semantics.Symbol
+	Shared bool
+	// Set by the enclosing ComptimeBlockStmt, the variable then lives in the .data section.
+	Comptime bool
 }
 
 func (stmt *VarDeclStmt) Semantics(s *semantics.SemanticChecker) error {
+	return s.FailDeclaration(stmt.Ident, stmt.declare(s))
+}
+
+func (stmt *VarDeclStmt) declare(s *semantics.SemanticChecker) error {
+	declType, err := s.ResolveType(stmt.Type)
+	if err != nil {
+		return err
+	}
+	stmt.Type = declType
+
 	if stmt.Right.HasVal() {
 		right := stmt.Right.Value()
+		if inPlace, isInPlace := right.(InPlaceExpression); isInPlace {
+			inPlace.SetInPlace()
+		}
+
 		if err := right.Semantics(s); err != nil {
 			return err
 		}
+		right = wrapOptional(right, stmt.Type)
+		stmt.Right.SetVal(right)
 
 		if !stmt.Type.Equals(right.ExprType()) {
 			return s.AddError(
+				diagnostics.TypeMismatch,
 				fmt.Sprintf(
 					"Variable type %v and right side type %v do not match",
 					stmt.Type.TypeID(),
@@ -52,7 +184,13 @@ func (stmt *VarDeclStmt) Semantics(s *semantics.SemanticChecker) error {
 		}
 	}
 
-	if err := s.PushSymbol(stmt.Ident.Value, stmt.Type, stmt.Ident); err != nil {
+	push := s.PushSymbol
+	if stmt.Comptime {
+		push = s.PushComptimeSymbol
+	} else if stmt.Shared {
+		push = s.PushSharedSymbol
+	}
+	if err := push(stmt.Ident.Value, stmt.Type, stmt.Ident); err != nil {
 		return err
 	}
 	stmt.Symbol, _ = s.TopSymbol()
@@ -72,25 +210,29 @@ func (s VarDeclStmt) EmitCode(e *codegen.Emitter) {
 	)
 
 	size := s.Type.Size()
-	fmt.Fprintf(e, "sub rsp, %v\n", size)
+	if s.Shared {
+		e.WriteBSS(fmt.Sprintf("alignb %v\n%v: resb %v\n", max(s.Type.Align(), 1), s.Symbol.Label, size))
+	} else {
+		fmt.Fprintf(e, "sub rsp, %v\n", size + s.Symbol.Padding)
+	}
 
 	if !s.Right.HasVal() {
 		return
 	}
 
 	right := s.Right.Value()
-	_, isArray := right.ExprType().(semantics.Array)
-	if isArray {
+	if inPlace, isInPlace := right.(InPlaceExpression); isInPlace {
+		fmt.Fprintf(e, "lea rax, [%v]\n", s.Symbol.Addr())
+		inPlace.EmitCode(e)
+	} else if isAggregate(right.ExprType()) {
 		right.EmitCode(e)
 		fmt.Fprintf(e, "mov rcx, %v\n", size) // Amount of bytes to move
 		fmt.Fprintf(e, "mov rsi, rax\n") // rsi holds the source
-		fmt.Fprintf(e, "lea rdi, [rbp - %v]\n", s.Symbol.Offset) // rdi holds the destination
+		fmt.Fprintf(e, "lea rdi, [%v]\n", s.Symbol.Addr()) // rdi holds the destination
 		fmt.Fprintf(e, "rep movsb\n")
 	} else {
 		right.EmitCode(e)
-		reg := s.Type.Register()
-		asmSize := s.Type.ASMSize()
-		fmt.Fprintf(e, "mov %v [rbp - %v], %v\n", asmSize, s.Symbol.Offset, reg)
+		emitStore(e, s.Type, s.Symbol.Addr())
 	}
 }
 
@@ -110,13 +252,19 @@ func (stmt *VarDefinitionStmt) Semantics(s *semantics.SemanticChecker) error {
 		return err
 	}
 
+	if inPlace, isInPlace := stmt.Right.(InPlaceExpression); isInPlace {
+		inPlace.SetInPlace()
+	}
+
 	if err := stmt.Right.Semantics(s); err != nil {
 		return err
 	}
+	stmt.Right = wrapOptional(stmt.Right, stmt.Left.ExprType())
 
 	_, isAddr := stmt.Left.(AddressableExpression)
-	if !isAddr {
+	if !isAddr || !stmt.Left.IsAddressable() {
 		return s.AddError(
+			diagnostics.NotAddressable,
 			"Left side of assignment only accepts addressable expressions",
 			stmt.Op,
 		)
@@ -124,6 +272,7 @@ func (stmt *VarDefinitionStmt) Semantics(s *semantics.SemanticChecker) error {
 
 	if l, _ := stmt.Left.ExprType().CanUseOperator("=", stmt.Right.ExprType()); !l {
 		return s.AddError(
+			diagnostics.TypeMismatch,
 			fmt.Sprintf(
 				"Cannot assign type %v to address with type %v", 
 				stmt.Right.ExprType().TypeID(),
@@ -141,10 +290,14 @@ func (stmt VarDefinitionStmt) EmitCode(e *codegen.Emitter) {
 
 	addr, _ := stmt.Left.(AddressableExpression)
 	addr.EmitAddressCode(e)
+
+	if inPlace, isInPlace := stmt.Right.(InPlaceExpression); isInPlace {
+		inPlace.EmitCode(e)
+		return
+	}
 	fmt.Fprintf(e, "push rax\n")
 
-	_, isArray := stmt.Right.ExprType().(semantics.Array)
-	if isArray {
+	if isAggregate(stmt.Right.ExprType()) {
 		stmt.Right.EmitCode(e)
 		size := stmt.Right.ExprType().Size()
 		fmt.Fprintf(e, "mov rcx, %v\n", size)
@@ -154,12 +307,7 @@ func (stmt VarDefinitionStmt) EmitCode(e *codegen.Emitter) {
 	} else {
 		stmt.Right.EmitCode(e)
 		fmt.Fprintf(e, "pop rbx\n")
-		fmt.Fprintf(
-			e, 
-			"mov %v [rbx], %v\n",
-			addr.ExprType().ASMSize(),
-			addr.ExprType().Register(),
-		)
+		emitStore(e, addr.ExprType(), "rbx")
 	}
 }
 
@@ -172,18 +320,28 @@ type BlockStmt struct {
 	Statements []Statement
 	// The size of the symbols declared inside this block.
 	BlockSize  int
+	// Statements deferred to the end of the block in the order they were registered.
+	Deferred   []Statement
 }
 
 func (stmt *BlockStmt) Semantics(s *semantics.SemanticChecker) error {
 	s.PushBlock()
 	
+	var firstErr error
 	for _, innerStmt := range stmt.Statements {
+		if deferStmt, isDefer := innerStmt.(*DeferStmt); isDefer {
+			deferStmt.Registered = true
+			stmt.Deferred = append(stmt.Deferred, deferStmt.Stmt)
+		}
+
 		// TODO: Implement better logging here
-		innerStmt.Semantics(s)
+		if err := innerStmt.Semantics(s); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
 	
 	stmt.BlockSize = s.PopBlock()
-	return nil
+	return firstErr
 }
 
 func (stmt BlockStmt) EmitCode(e *codegen.Emitter) {
@@ -192,6 +350,12 @@ func (stmt BlockStmt) EmitCode(e *codegen.Emitter) {
 		innerStmt.EmitCode(e)
 	}
 
+	// Deferred statements run in reverse order while the block's symbols are still on the stack.
+	for i := len(stmt.Deferred) - 1; i >= 0; i-- {
+		fmt.Fprintf(e, "; deferred statement %v\n", i)
+		stmt.Deferred[i].EmitCode(e)
+	}
+
 	fmt.Fprintf(e, "add rsp, %v\n", stmt.BlockSize)
 }
 
@@ -228,6 +392,7 @@ func (stmt *IfStmt) Semantics(s *semantics.SemanticChecker) error {
 
 	if stmt.Condition.ExprType().TypeID() != semantics.BOOL {
 		return s.AddError(
+			diagnostics.InvalidOperand,
 			fmt.Sprintf(
 				"If statement condition must be of type BOOL received %v",
 				stmt.Condition.ExprType().TypeID(),
@@ -280,399 +445,3879 @@ func (stmt IfStmt) Print(indent int) string {
 	return b.String()
 }
 
-// Assert statement.
-type AssertStmt struct {
-	// The assert token. Used for error handling information.
-	AssertToken lexer.Token
-	Expr        Expression
+// While loop.
+// Example:
+//  while i < 10 { i = i + 1; }
+type WhileStmt struct {
+	// The while token. Used for error handling.
+	WhileToken lexer.Token
+	Condition  Expression
+	Stmt       Statement
 }
 
-func (stmt *AssertStmt) Semantics(s *semantics.SemanticChecker) error {
-	if err := stmt.Expr.Semantics(s); err != nil {
+func (stmt *WhileStmt) Semantics(s *semantics.SemanticChecker) error {
+	if err := stmt.Condition.Semantics(s); err != nil {
 		return err
 	}
 
-	if stmt.Expr.ExprType().TypeID() != semantics.BOOL {
+	if stmt.Condition.ExprType().TypeID() != semantics.BOOL {
 		return s.AddError(
-			"Assert statement expects a boolean expression",
-			stmt.AssertToken,
+			diagnostics.InvalidOperand,
+			fmt.Sprintf(
+				"While statement condition must be of type BOOL received %v",
+				stmt.Condition.ExprType().TypeID(),
+			),
+			stmt.WhileToken,
 		)
 	}
 
-	return nil
+	if _, isDecl := stmt.Stmt.(*VarDeclStmt); isDecl {
+		return s.AddError(diagnostics.Misplaced, "The body of a while loop cannot be a variable declaration", stmt.WhileToken)
+	}
+
+	return stmt.Stmt.Semantics(s)
 }
 
-func (stmt AssertStmt) EmitCode(e *codegen.Emitter) {
-	fmt.Fprintf(e, "; ------------------------- AssertStmt ------------------------- \n")
-	stmt.Expr.EmitCode(e)
-	fmt.Fprintf(e, "cmp al, 1\n")
+func (stmt WhileStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- WhileStmt ------------------------- \n")
+	startLabel := e.NextLabel()
 	endLabel := e.NextLabel()
-	fmt.Fprintf(e, "je %v\n", endLabel)
-	fmt.Fprintf(e, "mov rax, 60\nmov rdi, 1\nsyscall\n")
+	fmt.Fprintf(e, "%v:\n", startLabel)
+	stmt.Condition.EmitCode(e)
+	fmt.Fprintf(e, "cmp al, 1\n")
+	fmt.Fprintf(e, "jne %v\n", endLabel)
+	stmt.Stmt.EmitCode(e)
+	fmt.Fprintf(e, "jmp %v\n", startLabel)
 	fmt.Fprintf(e, "%v:\n", endLabel)
 }
 
-func (stmt AssertStmt) Print(indent int) string {
+func (stmt WhileStmt) Print(indent int) string {
 	b := strings.Builder{}
-	
-	fmt.Fprintf(&b, "\n%vAssertStmt\n%v{\n", indentStr(indent), indentStr(indent))
-	fmt.Fprintf(&b, "%v\n", stmt.Expr.Print(indent + 1))
+
+	fmt.Fprintf(&b, "\n%vWhileStmt\n%v{", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Condition.Print(indent + 1))
+	fmt.Fprintf(&b, "%v\n", stmt.Stmt.Print(indent + 1))
 	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
 
 	return b.String()
 }
 
-// Expression statement.
-type ExpressionStmt struct {
-	Expr Expression
+// Conditionally unwraps an optional value.
+// Example:
+//  if let v = opt { ... } else { ... }
+// The statement runs with the unwrapped value bound in its own scope when the optional holds a value.
+type IfLetStmt struct {
+	// The if token. Used for error handling.
+	IfToken   lexer.Token
+	Ident     lexer.Token
+	Subject   Expression
+	Stmt      Statement
+	ElseStmt  utils.Optional[Statement]
+	// The symbol of the unwrapped value. Set during semantics.
+	Symbol    semantics.Symbol
+	// The size of the scope holding the unwrapped value.
+	Size      int
 }
 
-func (stmt *ExpressionStmt) Semantics(s *semantics.SemanticChecker) error {
-	return stmt.Expr.Semantics(s)
-}
+func (stmt *IfLetStmt) Semantics(s *semantics.SemanticChecker) error {
+	if err := stmt.Subject.Semantics(s); err != nil {
+		return err
+	}
 
-func (stmt ExpressionStmt) EmitCode(e *codegen.Emitter) {
-	stmt.Expr.EmitCode(e)
-}
+	optional, isOptional := semantics.Underlying(stmt.Subject.ExprType()).(semantics.Optional)
+	if !isOptional {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf(
+				"If let expects an optional value but received %v",
+				stmt.Subject.ExprType().TypeID(),
+			),
+			stmt.IfToken,
+		)
+	}
 
-func (stmt ExpressionStmt) Print(indent int) string {
-	b := strings.Builder{}
+	s.PushBlock()
+	err := stmt.checkStmt(s, optional)
+	stmt.Size = s.PopBlock()
+	if err != nil {
+		return err
+	}
 
-	fmt.Fprintf(&b, "\n%vExpressionStmt\n%v{\n", indentStr(indent), indentStr(indent))
-	fmt.Fprintf(&b, "%v\n", stmt.Expr.Print(indent + 1))
-	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+	if !stmt.ElseStmt.HasVal() {
+		return nil
+	}
 
-	return b.String()
-}
+	if err := stmt.ElseStmt.Value().Semantics(s); err != nil {
+		return err
+	}
 
-// This interface represents an expression in the language.
-// and holds the needed functions for semantic analysis and code generation.
-// All expressions must have a type that can be check with the ExprType() function.
-type Expression interface {
-	ExprType() semantics.Type
-	// This checks whether the expression is semantically correct.
-	// Also sets some extra information that is used by the emitter.
-	Semantics(s *semantics.SemanticChecker) error
-	// Using codegen.Emitter this emits the assembly code for the expression.
-	// All expressions are evaluated in the rax register.
-	EmitCode(e *codegen.Emitter)
-	// Returns whether the expression is addressable.
-	IsAddressable() bool
-	// Pretty prints the expression.
-	Print(indent int) string
+	return nil
 }
 
-// An AddressableExpression implements everything that a Expression implements
-// it just represents expressions that have a memory location or point to one.
-type AddressableExpression interface {
-	Expression
-	// Moves the address of the expression into the rax register.
-	EmitAddressCode(e *codegen.Emitter)
-}
+// Binds the unwrapped value and checks the statement in the current scope.
+func (stmt *IfLetStmt) checkStmt(s *semantics.SemanticChecker, optional semantics.Optional) error {
+	if err := s.PushSymbol(stmt.Ident.Value, optional.Base, stmt.Ident); err != nil {
+		return err
+	}
+	stmt.Symbol, _ = s.TopSymbol()
 
-// A binary expression holds a left value and a right value and an operator.
-type BinaryExpression struct {
-	Type  semantics.Type
-	Left  Expression
-	Op	  lexer.Token
-	Right Expression
+	return stmt.Stmt.Semantics(s)
 }
 
-func (exp BinaryExpression) ExprType() semantics.Type {
-	return exp.Type
-}
+func (stmt IfLetStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- IfLetStmt ------------------------- \n")
+	optional := semantics.Underlying(stmt.Subject.ExprType()).(semantics.Optional)
+	base := optional.Base
+	valueAddr := fmt.Sprintf("rbx + %v", optional.ValueOffset())
+	bindingAddr := fmt.Sprintf("rbp - %v", stmt.Symbol.Offset)
 
-func (exp *BinaryExpression) Semantics(s *semantics.SemanticChecker) error {
-	if err := exp.Left.Semantics(s); err != nil {
-		return err
-	}
+	stmt.Subject.EmitCode(e)
+	fmt.Fprintf(e, "mov rbx, rax\n")
+	fmt.Fprintf(e, "cmp BYTE [rbx], 0\n")
+	falseLabel := e.NextLabel()
+	fmt.Fprintf(e, "je %v\n", falseLabel)
 
-	if err := exp.Right.Semantics(s); err != nil {
-		return err
-	}
-		
-	l, t := exp.Left.ExprType().CanUseOperator(exp.Op.Value, exp.Right.ExprType())
-	if !l {
-		return s.AddError(
-			fmt.Sprintf(
-				"Cannot use operator '%v' between types %v and %v", 
-				exp.Op.Value,
-				exp.Left.ExprType().TypeID(), 
-				exp.Right.ExprType().TypeID(),
-			),
-			exp.Op,
-		)
+	// The statement reserves its own locals, the scope is released as a whole afterwards.
+	fmt.Fprintf(e, "; binding %v\n", stmt.Ident.Value)
+	fmt.Fprintf(e, "sub rsp, %v\n", stmt.Symbol.Size + stmt.Symbol.Padding)
+	if isAggregate(base) {
+		fmt.Fprintf(e, "lea rsi, [%v]\n", valueAddr)
+		fmt.Fprintf(e, "lea rdi, [%v]\n", bindingAddr)
+		fmt.Fprintf(e, "mov rcx, %v\n", base.Size())
+		fmt.Fprintf(e, "rep movsb\n")
+	} else {
+		emitLoad(e, base, valueAddr)
+		emitStore(e, base, bindingAddr)
 	}
-	exp.Type = t
-	
-	return nil
-}
 
-// Binary expressions are evaluated in the rax register.
-func (exp BinaryExpression) EmitCode(e *codegen.Emitter) {
-	fmt.Fprintf(e, "; BinaryExpression: type = %v op = %v\n", exp.Type.TypeID(), exp.Op.Value)
-	exp.Right.EmitCode(e)
-	e.WriteString("push rax\n")
-	exp.Left.EmitCode(e)
-	e.WriteString("pop rbx\n")
-	
-	// TODO: Clean up the binary operation logic
-	binOp := codegen.ASMBinaryOp(exp.Op)
-	if binOp == "add" || binOp == "sub" {
-		fmt.Fprintf(e, "%v rax, rbx\n", binOp)
-	} else if binOp == "mul" || binOp == "div" {
-		fmt.Fprintf(e, "%v rbx\n", binOp)
-	} else if exp.Type.TypeID() == semantics.BOOL {
-		fmt.Fprintf(e, "cmp rax, rbx\n")
-		fmt.Fprintf(e, "%v al\n", binOp)
+	stmt.Stmt.EmitCode(e)
+	fmt.Fprintf(e, "add rsp, %v\n", stmt.Size)
+	endLabel := e.NextLabel()
+	fmt.Fprintf(e, "jmp %v\n", endLabel)
+	fmt.Fprintf(e, "%v:\n", falseLabel)
+
+	if stmt.ElseStmt.HasVal() {
+		stmt.ElseStmt.Value().EmitCode(e)
 	}
-}
 
-func (_ BinaryExpression) IsAddressable() bool {
-	return false
+	fmt.Fprintf(e, "%v:\n", endLabel)
 }
 
-func (exp BinaryExpression) Print(indent int) string {
-	result := fmt.Sprintf("BinaryExpression\n%v{\n", indentStr(indent))
-	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type)
-	result += fmt.Sprintf("%v\n", exp.Left.Print(indent + 1))
-	result += fmt.Sprintf("%vOp: %v\n", indentStr(indent + 1), exp.Op)
-	result += fmt.Sprintf("%v", exp.Right.Print(indent + 1))
-	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
-}
+func (stmt IfLetStmt) Print(indent int) string {
+	b := strings.Builder{}
 
-// A prefix expression holds a unary operator and a right value.
-type PrefixExpression struct {
-	Type        semantics.Type
-	Op    	    lexer.Token
-	Right 	    Expression
-	Addressable bool
-}
+	fmt.Fprintf(&b, "\n%vIfLetStmt\n%v{", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "\n%vIdent: %v", indentStr(indent + 1), stmt.Ident.Value)
+	fmt.Fprintf(&b, "%v\n", stmt.Subject.Print(indent + 1))
+	fmt.Fprintf(&b, "%v\n", stmt.Stmt.Print(indent + 1))
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
 
-func (exp PrefixExpression) ExprType() semantics.Type {
-	return exp.Type
+	return b.String()
 }
 
-// TODO: PrefixExpression.Semantics for "-" and "!"
-func (exp *PrefixExpression) Semantics(s *semantics.SemanticChecker) error {
-	return nil
+// Defers a statement to the end of the enclosing block.
+// Example:
+//  { uint8 fd = 3; defer close(fd); ... }
+// The statement is checked where it appears and emitted by the enclosing BlockStmt.
+type DeferStmt struct {
+	// The defer token. Used for error handling.
+	DeferToken lexer.Token
+	Stmt       Statement
+	// Set by the enclosing BlockStmt that runs the statement.
+	Registered bool
 }
 
-// TODO: PrefixExpression.EmitCode for "-" and "!"
-func (exp PrefixExpression) EmitCode(e *codegen.Emitter) {
+func (stmt *DeferStmt) Semantics(s *semantics.SemanticChecker) error {
+	if !stmt.Registered {
+		return s.AddError(diagnostics.Misplaced, "Defer statements must be placed directly inside a block", stmt.DeferToken)
+	}
+
+	switch stmt.Stmt.(type) {
+	case *VarDeclStmt, *EnumDeclStmt, *TypeDeclStmt, *UnionDeclStmt:
+		return s.AddError(diagnostics.Misplaced, "Declarations cannot be deferred", stmt.DeferToken)
+	}
 
+	return stmt.Stmt.Semantics(s)
 }
 
-func (exp PrefixExpression) IsAddressable() bool {
-	return exp.Addressable
+// Emits nothing, the enclosing BlockStmt emits the deferred statement when the block ends.
+func (stmt DeferStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- DeferStmt ------------------------- \n")
 }
 
-func (exp PrefixExpression) Print(indent int) string {
+func (stmt DeferStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vDeferStmt\n%v{", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Stmt.Print(indent + 1))
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Matches {x} placeholders in inline assembly. Names follow the lexer's rule for identifiers.
+var asmPlaceholder = regexp.MustCompile(`\{\s*([\p{L}_][\p{L}\p{Nd}_]*)\s*\}`)
+
+// Matches words that could name a register in inline assembly.
+var asmWord = regexp.MustCompile(`[\p{L}_][\p{L}\p{Nd}_]*`)
+
+// Matches a label defined at the start of a line of inline assembly.
+var asmLabel = regexp.MustCompile(`^\s*([^\s:;,\[\]]+)\s*:`)
+
+// Inline assembly copied into the output.
+// Example:
+//  asm (r12) { mov r12, {x}
+//              add r12, 1
+//              mov {x}, r12 }
+// {x} placeholders are replaced by the memory operand of the variable x.
+// Every register the text uses has to be listed as clobbered. rsp and rbp hold the stack frame
+// and cannot be used at all. Labels defined by the text have to be local, starting with '.',
+// so they do not split the surrounding code's local labels.
+// Clobbered registers the emitted code does not treat as scratch are saved around the block.
+type AsmStmt struct {
+	// The asm token. Used for error handling.
+	AsmToken lexer.Token
+	Clobbers []lexer.Token
+	Text     lexer.Token
+	// The symbols of the placeholders by name. Set during semantics.
+	Symbols  map[string]semantics.Symbol
+}
+
+func (stmt *AsmStmt) Semantics(s *semantics.SemanticChecker) error {
+	clobbered := map[string]bool{}
+	for _, clobber := range stmt.Clobbers {
+		reg, isReg := codegen.FullRegister(clobber.Value)
+		if !isReg || reg != clobber.Value {
+			return s.AddError(
+				diagnostics.InvalidRegister,
+				fmt.Sprintf("'%v' is not a 64 bit general purpose register", clobber.Value),
+				clobber,
+			)
+		}
+
+		if codegen.IsFrameRegister(reg) {
+			return s.AddError(
+				diagnostics.InvalidRegister,
+				fmt.Sprintf("Register %v holds the stack frame and cannot be clobbered", reg),
+				clobber,
+			)
+		}
+
+		if clobbered[reg] {
+			return s.AddError(diagnostics.InvalidRegister, fmt.Sprintf("Register %v is clobbered more than once", reg), clobber)
+		}
+		clobbered[reg] = true
+	}
+
+	stmt.Symbols = map[string]semantics.Symbol{}
+	for _, match := range asmPlaceholder.FindAllStringSubmatchIndex(stmt.Text.Value, -1) {
+		ident := stmt.textToken(match[2], stmt.Text.Value[match[2]:match[3]])
+
+		symbol, err := s.GetSymbol(ident)
+		if err != nil {
+			return err
+		}
+
+		if symbol.IsType {
+			return s.AddError(
+				diagnostics.WrongSymbolKind,
+				fmt.Sprintf("'%v' is a type and cannot be used as an asm operand", ident.Value),
+				ident,
+			)
+		}
+		stmt.Symbols[ident.Value] = *symbol
+	}
+
+	// Placeholders are blanked out instead of removed so offsets into code are offsets into the text.
+	code := asmPlaceholder.ReplaceAllStringFunc(stmt.Text.Value, func(placeholder string) string {
+		return strings.Repeat(" ", len(placeholder))
+	})
+
+	lineStart := 0
+	for _, line := range strings.Split(code, "\n") {
+		start := lineStart
+		lineStart += len(line) + 1
+		line, _, _ = strings.Cut(line, ";") // Comments
+
+		if match := asmLabel.FindStringSubmatchIndex(line); match != nil && !strings.HasPrefix(line[match[2]:match[3]], ".") {
+			return s.AddError(
+				diagnostics.InvalidAsmLabel,
+				fmt.Sprintf("Label %v in an asm block must be local, for example .%v", line[match[2]:match[3]], line[match[2]:match[3]]),
+				stmt.textToken(start + match[2], line[match[2]:match[3]]),
+			)
+		}
+
+		for _, match := range asmWord.FindAllStringIndex(line, -1) {
+			word := line[match[0]:match[1]]
+			reg, isReg := codegen.FullRegister(strings.ToLower(word))
+			if !isReg || clobbered[reg] {
+				continue
+			}
+
+			if codegen.IsFrameRegister(reg) {
+				return s.AddError(
+					diagnostics.InvalidRegister,
+					fmt.Sprintf("Register %v holds the stack frame and cannot be used in an asm block", word),
+					stmt.textToken(start + match[0], word),
+				)
+			}
+
+			return s.AddError(
+				diagnostics.InvalidRegister,
+				fmt.Sprintf("Register %v is used in the asm block but not declared as clobbered", word),
+				stmt.textToken(start + match[0], word),
+			)
+		}
+	}
+
+	return nil
+}
+
+// Returns a token for the part of the asm text starting at the given byte offset.
+func (stmt AsmStmt) textToken(offset int, value string) lexer.Token {
+	token := stmt.Text
+	token.Value = value
+	token.Offset += offset
+
+	for _, r := range stmt.Text.Value[:offset] {
+		if r == '\n' {
+			token.Line++
+			token.Col = 1
+		} else {
+			token.Col++
+		}
+	}
+
+	return token
+}
+
+// Replaces a {x} placeholder with the memory operand of x.
+// Aggregates are left without a size so their address can be taken with lea.
+func (stmt AsmStmt) operand(placeholder string) string {
+	ident := asmPlaceholder.FindStringSubmatch(placeholder)[1]
+	symbol := stmt.Symbols[ident]
+
+	if isAggregate(symbol.Type) {
+		return fmt.Sprintf("[%v]", symbol.Addr())
+	}
+
+	return fmt.Sprintf("%v [%v]", symbol.Type.ASMSize(), symbol.Addr())
+}
+
+func (stmt AsmStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- AsmStmt ------------------------- \n")
+
+	saved := []string{}
+	for _, clobber := range stmt.Clobbers {
+		if !codegen.IsScratchRegister(clobber.Value) {
+			saved = append(saved, clobber.Value)
+		}
+	}
+
+	for _, reg := range saved {
+		fmt.Fprintf(e, "push %v\n", reg)
+	}
+
+	code := asmPlaceholder.ReplaceAllStringFunc(stmt.Text.Value, stmt.operand)
+	for _, line := range strings.Split(code, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			fmt.Fprintf(e, "%v\n", line)
+		}
+	}
+
+	for i := len(saved) - 1; i >= 0; i-- {
+		fmt.Fprintf(e, "pop %v\n", saved[i])
+	}
+}
+
+func (stmt AsmStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vAsmStmt\n%v{", indentStr(indent), indentStr(indent))
+	for _, clobber := range stmt.Clobbers {
+		fmt.Fprintf(&b, "\n%vClobber: %v", indentStr(indent + 1), clobber.Value)
+	}
+	fmt.Fprintf(&b, "\n%vText: %q", indentStr(indent + 1), stmt.Text.Value)
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Exits the process with the given status.
+// Deferred statements of the enclosing blocks are not run.
+type ExitStmt struct {
+	// The exit token. Used for error handling.
+	ExitToken lexer.Token
+	Code      Expression
+}
+
+func (stmt *ExitStmt) Semantics(s *semantics.SemanticChecker) error {
+	if s.InSpawn() {
+		return s.AddError(diagnostics.Misplaced, "exit cannot be used inside a spawn block, the thread ends with its block", stmt.ExitToken)
+	}
+
+	if err := stmt.Code.Semantics(s); err != nil {
+		return err
+	}
+
+	if !semantics.IsNumber(stmt.Code.ExprType()) {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("Exit code must be an unsigned integer but received %v", stmt.Code.ExprType().TypeID()),
+			stmt.ExitToken,
+		)
+	}
+
+	// The kernel only passes the lowest byte of the status on, so larger codes would be truncated.
+	if code, isConst := constantValue(stmt.Code); isConst && code > 255 {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("Exit code %v does not fit in the exit status, which ranges from 0 to 255", code),
+			exprToken(stmt.Code, stmt.ExitToken),
+		)
+	}
+
+	return nil
+}
+
+func (stmt ExitStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- ExitStmt ------------------------- \n")
+	stmt.Code.EmitCode(e)
+	fmt.Fprintf(e, "mov rdi, rax\n")
+	e.ExitRdi()
+}
+
+func (stmt ExitStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vExitStmt\n%v{", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Code.Print(indent + 1))
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Frees memory allocated with new.
+// Deleting a null pointer does nothing.
+type DeleteStmt struct {
+	// The delete token. Used for error handling.
+	DeleteToken lexer.Token
+	Expr        Expression
+}
+
+func (stmt *DeleteStmt) Semantics(s *semantics.SemanticChecker) error {
+	if err := stmt.Expr.Semantics(s); err != nil {
+		return err
+	}
+
+	if _, isPtr := semantics.Underlying(stmt.Expr.ExprType()).(semantics.Ptr); !isPtr {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("Only pointers can be deleted but received %v", stmt.Expr.ExprType().TypeID()),
+			stmt.DeleteToken,
+		)
+	}
+
+	return nil
+}
+
+func (stmt DeleteStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- DeleteStmt ------------------------- \n")
+	e.UseAllocator()
+	stmt.Expr.EmitCode(e)
+	fmt.Fprintf(e, "mov rdi, rax\n")
+	fmt.Fprintf(e, "call __clovis_free\n")
+}
+
+func (stmt DeleteStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vDeleteStmt\n%v{", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Expr.Print(indent + 1))
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Waits for a thread started by spawn to end and releases its stack.
+// Example:
+//  Thread t = spawn { ... };
+//  join(t);
+// Joining a thread again is a runtime error.
+type JoinStmt struct {
+	// The join token. Used for error handling.
+	JoinToken lexer.Token
+	Thread    Expression
+}
+
+func (stmt *JoinStmt) Semantics(s *semantics.SemanticChecker) error {
+	if err := stmt.Thread.Semantics(s); err != nil {
+		return err
+	}
+
+	if !semantics.Thread.Equals(stmt.Thread.ExprType()) {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("Only threads can be joined but received %v", stmt.Thread.ExprType().TypeID()),
+			stmt.JoinToken,
+		)
+	}
+
+	return nil
+}
+
+func (stmt JoinStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- JoinStmt ------------------------- \n")
+	e.UseThreads()
+	stmt.Thread.EmitCode(e)
+	fmt.Fprintf(e, "mov rdi, rax\n")
+	fmt.Fprintf(e, "call __clovis_join\n")
+}
+
+func (stmt JoinStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vJoinStmt\n%v{", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Thread.Print(indent + 1))
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Imports the top-level symbols of another file under the file's name.
+// Example:
+//  import "lib/math.clv";
+//  uint64 area = math.PI * 2;
+// The path is resolved relative to the importing file by the loader, which also sets Module.
+type ImportStmt struct {
+	// The import token. Used for error handling.
+	ImportToken lexer.Token
+	Path        lexer.Token
+	// The namespace of the imported module.
+	Module      string
+}
+
+func (stmt *ImportStmt) Semantics(s *semantics.SemanticChecker) error {
+	if stmt.Module == "" {
+		return s.AddError(diagnostics.Misplaced, "Imports are only allowed at the top level of a file", stmt.ImportToken)
+	}
+
+	return s.PushModule(stmt.Module, stmt.Path)
+}
+
+func (stmt ImportStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- ImportStmt: %v -------------------------\n", stmt.Path.Value)
+}
+
+func (stmt ImportStmt) Print(indent int) string {
+	return fmt.Sprintf("\n%vImportStmt \"%v\" as %v", indentStr(indent), stmt.Path.Value, stmt.Module)
+}
+
+// Assert statement.
+type AssertStmt struct {
+	// The assert token. Used for error handling information.
+	AssertToken lexer.Token
+	Expr        Expression
+}
+
+func (stmt *AssertStmt) Semantics(s *semantics.SemanticChecker) error {
+	if err := stmt.Expr.Semantics(s); err != nil {
+		return err
+	}
+
+	if stmt.Expr.ExprType().TypeID() != semantics.BOOL {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			"Assert statement expects a boolean expression",
+			stmt.AssertToken,
+		)
+	}
+
+	return nil
+}
+
+func (stmt AssertStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- AssertStmt ------------------------- \n")
+	stmt.Expr.EmitCode(e)
+	fmt.Fprintf(e, "cmp al, 1\n")
+	endLabel := e.NextLabel()
+	fmt.Fprintf(e, "je %v\n", endLabel)
+	e.Exit(1)
+	fmt.Fprintf(e, "%v:\n", endLabel)
+}
+
+func (stmt AssertStmt) Print(indent int) string {
+	b := strings.Builder{}
+	
+	fmt.Fprintf(&b, "\n%vAssertStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Expr.Print(indent + 1))
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Assertion checked during semantic analysis.
+// Example:
+//  static_assert sizeof(Header) == 16;
+//  static_assert large.len == small.len * 2, "large must hold two smalls";
+// The expression has to be a compile-time constant and nothing is emitted.
+type StaticAssertStmt struct {
+	// The static_assert token. Failures are reported at it.
+	AssertToken lexer.Token
+	Expr        Expression
+	Message     utils.Optional[lexer.Token]
+}
+
+func (stmt *StaticAssertStmt) Semantics(s *semantics.SemanticChecker) error {
+	if err := stmt.Expr.Semantics(s); err != nil {
+		return err
+	}
+
+	if stmt.Expr.ExprType().TypeID() != semantics.BOOL {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("static_assert expects a boolean expression but received %v", stmt.Expr.ExprType().TypeID()),
+			stmt.AssertToken,
+		)
+	}
+
+	value, isConst := constantValue(stmt.Expr)
+	if !isConst {
+		return s.AddError(
+			diagnostics.NotConstant,
+			"static_assert expression is not a compile-time constant",
+			stmt.AssertToken,
+		)
+	}
+
+	if value == 0 {
+		msg := "Static assertion failed"
+		if stmt.Message.HasVal() {
+			msg = fmt.Sprintf("%v: %v", msg, stmt.Message.Value().Value)
+		}
+		return s.AddError(diagnostics.StaticAssertFailed, msg, stmt.AssertToken)
+	}
+
+	return nil
+}
+
+// Static assertions are checked at compile time so no code is emitted.
+func (stmt StaticAssertStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- StaticAssertStmt ------------------------- \n")
+}
+
+func (stmt StaticAssertStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vStaticAssertStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Expr.Print(indent + 1))
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Statements run by the compile-time interpreter during semantic analysis.
+// Example:
+//  comptime {
+//      uint64[16] squares;
+//      uint64 i = 0;
+//      while i < 16 {
+//          squares[i] = i * i;
+//          i = i + 1;
+//      }
+//  }
+//  static_assert comptime squares[3] == 9;
+// The variables declared directly inside the block keep their computed values and are
+// placed in the .data section. Runtime variables cannot be read by the block.
+type ComptimeBlockStmt struct {
+	// The comptime token. Used for error handling.
+	ComptimeToken lexer.Token
+	Statements    []Statement
+	// The variables declared directly inside the block and their computed bytes. Set during semantics.
+	Decls         []*VarDeclStmt
+	Values        [][]byte
+}
+
+func (stmt *ComptimeBlockStmt) Semantics(s *semantics.SemanticChecker) error {
+	// The variables of the block live in static storage, so the statements are checked
+	// in the enclosing block to keep them visible to the code that follows.
+	frameStart := s.FrameSize()
+
+	var firstErr error
+	for _, innerStmt := range stmt.Statements {
+		if decl, isDecl := innerStmt.(*VarDeclStmt); isDecl {
+			decl.Comptime = true
+			stmt.Decls = append(stmt.Decls, decl)
+		}
+
+		if err := innerStmt.Semantics(s); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	for _, decl := range stmt.Decls {
+		if holdsAddress(decl.Type) {
+			return s.AddError(
+				diagnostics.ComptimeEvaluation,
+				fmt.Sprintf("Comptime variable '%v' of type %v cannot hold addresses", decl.Ident.Value, decl.Type.TypeID()),
+				decl.Ident,
+			)
+		}
+	}
+
+	in := newInterpreter(s, stmt.ComptimeToken, frameStart)
+	for _, innerStmt := range stmt.Statements {
+		if err := in.exec(innerStmt); err != nil {
+			return err
+		}
+	}
+
+	mem := s.ComptimeMemory()
+	for _, decl := range stmt.Decls {
+		bytes, _ := mem.Read(mem.Labels[decl.Symbol.Label], decl.Type.Size())
+		stmt.Values = append(stmt.Values, append([]byte{}, bytes...))
+	}
+
+	return nil
+}
+
+// Only the computed variables are emitted, the statements already ran during compilation.
+func (stmt ComptimeBlockStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- ComptimeBlockStmt ------------------------- \n")
+
+	for i, decl := range stmt.Decls {
+		e.WriteData(codegen.DataDefinition(decl.Symbol.Label, decl.Type.Align(), stmt.Values[i]))
+	}
+}
+
+func (stmt ComptimeBlockStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vComptimeBlockStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	for _, innerStmt := range stmt.Statements {
+		fmt.Fprintf(&b, "%v,", innerStmt.Print(indent + 1))
+	}
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Jump tables are used when the case values span at most this many values...
+const maxJumpTableSpan = 1024
+// ...and at least half of the spanned values are case values.
+const minJumpTableDensity = 2
+
+// A single case value or an inclusive range of case values.
+type SwitchCase struct {
+	// First token of the case. Used for error handling.
+	Token     lexer.Token
+	Low       Expression
+	High      utils.Optional[Expression]
+	// The evaluated bounds. Set during semantics.
+	LowValue  uint64
+	HighValue uint64
+}
+
+// A case list or the default arm of a switch statement.
+type SwitchArm struct {
+	// The case or default token. Used for error handling.
+	Token     lexer.Token
+	IsDefault bool
+	Cases     []SwitchCase
+	Stmt      Statement
+	// The size of the symbols declared by the arm's statement.
+	Size      int
+}
+
+// Switch statement.
+// Example:
+//  switch c {
+//      case Color.Red, Color.Green: x = 1;
+//      case 20..29: { x = 2; }
+//      default: x = 3;
+//  }
+// Ranges are inclusive and arms never fall through.
+// Dense cases are dispatched through a jump table in .rodata, sparse ones through a compare chain.
+type SwitchStmt struct {
+	// The switch token. Used for error handling.
+	SwitchToken lexer.Token
+	Subject     Expression
+	Arms        []SwitchArm
+}
+
+func (stmt *SwitchStmt) Semantics(s *semantics.SemanticChecker) error {
+	if err := stmt.Subject.Semantics(s); err != nil {
+		return err
+	}
+
+	subjectType := stmt.Subject.ExprType()
+	if subjectType.TypeID() == semantics.UINT_LIT {
+		subjectType = semantics.Uint64{}
+	}
+
+	_, isEnum := semantics.Underlying(subjectType).(semantics.Enum)
+	if !semantics.IsNumber(subjectType) && !isEnum {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("Switch expects an unsigned integer or enum but received %v", subjectType.TypeID()),
+			stmt.SwitchToken,
+		)
+	}
+
+	hasDefault := false
+	checked := []SwitchCase{}
+
+	for i := range stmt.Arms {
+		arm := &stmt.Arms[i]
+
+		if arm.IsDefault {
+			if hasDefault {
+				return s.AddError(diagnostics.InvalidArms, "Switch statement has more than one default arm", arm.Token)
+			}
+			hasDefault = true
+		}
+
+		for j := range arm.Cases {
+			c := &arm.Cases[j]
+
+			low, err := switchCaseValue(s, c.Low, subjectType, c.Token)
+			if err != nil {
+				return err
+			}
+			c.LowValue = low
+			c.HighValue = low
+
+			if c.High.HasVal() {
+				high, err := switchCaseValue(s, c.High.Value(), subjectType, c.Token)
+				if err != nil {
+					return err
+				}
+
+				if high < low {
+					return s.AddError(
+						diagnostics.InvalidArms,
+						fmt.Sprintf("Case range %v..%v is empty", low, high),
+						c.Token,
+					)
+				}
+				c.HighValue = high
+			}
+
+			for _, other := range checked {
+				if c.LowValue <= other.HighValue && other.LowValue <= c.HighValue {
+					return s.AddError(
+						diagnostics.InvalidArms,
+						fmt.Sprintf("Duplicate case value %v", max(c.LowValue, other.LowValue)),
+						c.Token,
+					)
+				}
+			}
+			checked = append(checked, *c)
+		}
+
+		s.PushBlock()
+		err := arm.Stmt.Semantics(s)
+		arm.Size = s.PopBlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	if enum, isEnum := semantics.Underlying(subjectType).(semantics.Enum); isEnum && !hasDefault {
+		missing := []string{}
+		for _, member := range enum.Members {
+			if !stmt.covers(member.Value) {
+				missing = append(missing, member.Ident)
+			}
+		}
+
+		if len(missing) != 0 {
+			return s.AddError(
+				diagnostics.InvalidArms,
+				fmt.Sprintf(
+					"Switch on enum %v is not exhaustive, missing %v",
+					enum.Name,
+					strings.Join(missing, ", "),
+				),
+				stmt.SwitchToken,
+			).WithHint("Add the missing cases or a default arm")
+		}
+	}
+
+	return nil
+}
+
+// Checks a case bound against the switch subject and returns its value.
+func switchCaseValue(s *semantics.SemanticChecker, expr Expression, subjectType semantics.Type, token lexer.Token) (uint64, error) {
+	if err := expr.Semantics(s); err != nil {
+		return 0, err
+	}
+
+	if !subjectType.Equals(expr.ExprType()) {
+		return 0, s.AddError(
+			diagnostics.TypeMismatch,
+			fmt.Sprintf(
+				"Case of type %v does not match switch type %v",
+				expr.ExprType().TypeID(),
+				subjectType.TypeID(),
+			),
+			token,
+		)
+	}
+
+	value, isConst := constantValue(expr)
+	if !isConst {
+		return 0, s.AddError(diagnostics.NotConstant, "Case values must be constant", token)
+	}
+
+	if value > semantics.MaxUint(subjectType.Size()) {
+		return 0, s.AddError(
+			diagnostics.InvalidArms,
+			fmt.Sprintf("Case value %v can never match type %v", value, subjectType.TypeID()),
+			token,
+		)
+	}
+
+	return value, nil
+}
+
+// Returns whether any case of the switch matches the value.
+func (stmt SwitchStmt) covers(value uint64) bool {
+	for _, arm := range stmt.Arms {
+		for _, c := range arm.Cases {
+			if c.LowValue <= value && value <= c.HighValue {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns the smallest and largest case values and how many values the cases match.
+func (stmt SwitchStmt) caseBounds() (uint64, uint64, uint64) {
+	low, high, count := ^uint64(0), uint64(0), uint64(0)
+	for _, arm := range stmt.Arms {
+		for _, c := range arm.Cases {
+			low = min(low, c.LowValue)
+			high = max(high, c.HighValue)
+			count += c.HighValue - c.LowValue + 1
+		}
+	}
+
+	return low, high, count
+}
+
+// Returns whether the cases are dense enough to be dispatched through a jump table.
+func (stmt SwitchStmt) useJumpTable() bool {
+	caseCount := 0
+	for _, arm := range stmt.Arms {
+		caseCount += len(arm.Cases)
+	}
+
+	if caseCount < 3 {
+		return false
+	}
+
+	low, high, count := stmt.caseBounds()
+	if high - low >= maxJumpTableSpan {
+		return false
+	}
+
+	return count * minJumpTableDensity >= high - low + 1
+}
+
+func (stmt SwitchStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- SwitchStmt ------------------------- \n")
+	stmt.Subject.EmitCode(e)
+	e.ZeroExtend(stmt.Subject.ExprType().Size())
+
+	armLabels := make([]string, len(stmt.Arms))
+	for i := range stmt.Arms {
+		armLabels[i] = e.NextUnscopedLabel()
+	}
+	endLabel := e.NextUnscopedLabel()
+
+	fallbackLabel := endLabel
+	for i, arm := range stmt.Arms {
+		if arm.IsDefault {
+			fallbackLabel = armLabels[i]
+		}
+	}
+
+	if stmt.useJumpTable() {
+		stmt.emitJumpTable(e, armLabels, fallbackLabel)
+	} else {
+		stmt.emitCompareChain(e, armLabels, fallbackLabel)
+	}
+
+	for i, arm := range stmt.Arms {
+		fmt.Fprintf(e, "%v:\n", armLabels[i])
+		arm.Stmt.EmitCode(e)
+		fmt.Fprintf(e, "add rsp, %v\n", arm.Size)
+		fmt.Fprintf(e, "jmp %v\n", endLabel)
+	}
+
+	fmt.Fprintf(e, "%v:\n", endLabel)
+}
+
+// Jumps to the matching arm by indexing a table of arm addresses with the subject in rax.
+func (stmt SwitchStmt) emitJumpTable(e *codegen.Emitter, armLabels []string, fallbackLabel string) {
+	low, high, _ := stmt.caseBounds()
+	tableLabel := e.NextUnscopedLabel()
+
+	fmt.Fprintf(e, "; jump table %v..%v\n", low, high)
+	fmt.Fprintf(e, "mov rbx, %v\n", low)
+	fmt.Fprintf(e, "sub rax, rbx\n")
+	fmt.Fprintf(e, "mov rbx, %v\n", high - low)
+	fmt.Fprintf(e, "cmp rax, rbx\n")
+	fmt.Fprintf(e, "ja %v\n", fallbackLabel)
+	// The table holds offsets from its start so the code is position independent.
+	fmt.Fprintf(e, "lea rbx, [rel %v]\n", tableLabel)
+	fmt.Fprintf(e, "movsxd rax, DWORD [rbx + rax * 4]\n")
+	fmt.Fprintf(e, "add rax, rbx\n")
+	fmt.Fprintf(e, "jmp rax\n")
+
+	targets := make([]string, high - low + 1)
+	for i := range targets {
+		targets[i] = fallbackLabel
+	}
+	for i, arm := range stmt.Arms {
+		for _, c := range arm.Cases {
+			for index := c.LowValue - low; index <= c.HighValue - low; index++ {
+				targets[index] = armLabels[i]
+			}
+		}
+	}
+
+	// The arm labels are unscoped so the table in .rodata can refer to them.
+	table := strings.Builder{}
+	fmt.Fprintf(&table, "align 4\n%v:\n", tableLabel)
+	for _, target := range targets {
+		fmt.Fprintf(&table, "dd %v - %v\n", target, tableLabel)
+	}
+	e.WriteROData(table.String())
+}
+
+// Compares the subject in rax against every case in order.
+func (stmt SwitchStmt) emitCompareChain(e *codegen.Emitter, armLabels []string, fallbackLabel string) {
+	for i, arm := range stmt.Arms {
+		for _, c := range arm.Cases {
+			fmt.Fprintf(e, "mov rbx, %v\n", c.LowValue)
+			fmt.Fprintf(e, "cmp rax, rbx\n")
+
+			if c.LowValue == c.HighValue {
+				fmt.Fprintf(e, "je %v\n", armLabels[i])
+				continue
+			}
+
+			nextLabel := e.NextUnscopedLabel()
+			fmt.Fprintf(e, "jb %v\n", nextLabel)
+			fmt.Fprintf(e, "mov rbx, %v\n", c.HighValue)
+			fmt.Fprintf(e, "cmp rax, rbx\n")
+			fmt.Fprintf(e, "jbe %v\n", armLabels[i])
+			fmt.Fprintf(e, "%v:\n", nextLabel)
+		}
+	}
+
+	fmt.Fprintf(e, "jmp %v\n", fallbackLabel)
+}
+
+func (stmt SwitchStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vSwitchStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Subject.Print(indent + 1))
+	for _, arm := range stmt.Arms {
+		if arm.IsDefault {
+			fmt.Fprintf(&b, "%vdefault:", indentStr(indent + 1))
+		} else {
+			fmt.Fprintf(&b, "%vcase", indentStr(indent + 1))
+			for _, c := range arm.Cases {
+				fmt.Fprintf(&b, " %v..%v", c.LowValue, c.HighValue)
+			}
+			fmt.Fprintf(&b, ":")
+		}
+		fmt.Fprintf(&b, "%v\n", arm.Stmt.Print(indent + 2))
+	}
+	fmt.Fprintf(&b, "%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// A member of an enum declaration with an optional explicit value.
+type EnumMemberDecl struct {
+	Ident lexer.Token
+	Value utils.Optional[lexer.Token]
+}
+
+// Enum declaration statement.
+// Example:
+//  enum Color : uint8 { Red, Green, Blue = 10 }
+// Members without an explicit value take the previous value plus one, starting from 0.
+type EnumDeclStmt struct {
+	// The enum token. Used for error handling.
+	EnumToken lexer.Token
+	Ident     lexer.Token
+	Backing   semantics.Type
+	Members   []EnumMemberDecl
+	// The declared type. Set during semantics.
+	Type      semantics.Enum
+}
+
+func (stmt *EnumDeclStmt) Semantics(s *semantics.SemanticChecker) error {
+	return s.FailDeclaration(stmt.Ident, stmt.declare(s))
+}
+
+func (stmt *EnumDeclStmt) declare(s *semantics.SemanticChecker) error {
+	backing, err := s.ResolveType(stmt.Backing)
+	if err != nil {
+		return err
+	}
+
+	if !semantics.IsNumber(backing) {
+		return s.AddError(
+			diagnostics.InvalidDeclaration,
+			fmt.Sprintf("Enum backing type must be an unsigned integer but received %v", backing.TypeID()),
+			stmt.Ident,
+		)
+	}
+
+	enum := semantics.Enum{
+		Name: stmt.Ident.Value,
+		Decl: s.DeclAt(stmt.Ident),
+		Backing: backing,
+		Members: []semantics.EnumMember{},
+	}
+	next := uint64(0)
+	overflowed := false
+
+	for _, member := range stmt.Members {
+		if _, exists := enum.Member(member.Ident.Value); exists {
+			return s.AddError(
+				diagnostics.InvalidDeclaration,
+				fmt.Sprintf("Duplicate member '%v' in enum %v", member.Ident.Value, enum.Name),
+				member.Ident,
+			)
+		}
+
+		value := next
+		if member.Value.HasVal() {
+			valueToken := member.Value.Value()
+			parsed, err := strconv.ParseUint(valueToken.Value, 10, 64)
+			if err != nil {
+				return s.AddError(
+					diagnostics.InvalidDeclaration,
+					fmt.Sprintf("Enum value %v does not fit in UINT64", valueToken.Value),
+					valueToken,
+				)
+			}
+			value = parsed
+		} else if overflowed {
+			return s.AddError(
+				diagnostics.InvalidDeclaration,
+				fmt.Sprintf("Enum value of '%v' overflows UINT64", member.Ident.Value),
+				member.Ident,
+			)
+		}
+
+		if value > semantics.MaxUint(backing.Size()) {
+			return s.AddError(
+				diagnostics.InvalidDeclaration,
+				fmt.Sprintf(
+					"Enum value %v of '%v' does not fit in backing type %v",
+					value,
+					member.Ident.Value,
+					backing.TypeID(),
+				),
+				member.Ident,
+			)
+		}
+
+		enum.Members = append(enum.Members, semantics.EnumMember{ Ident: member.Ident.Value, Value: value })
+		next = value + 1
+		overflowed = next == 0
+	}
+	stmt.Type = enum
+
+	return s.PushType(stmt.Ident.Value, enum, stmt.Ident)
+}
+
+// Enums are resolved at compile time so no code is emitted.
+func (stmt EnumDeclStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- EnumDeclStmt: %v -------------------------\n", stmt.Ident.Value)
+}
+
+func (stmt EnumDeclStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vEnumDeclStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%vIdent: %v\n", indentStr(indent + 1), stmt.Ident.Value)
+	fmt.Fprintf(&b, "%vBacking: %v\n", indentStr(indent + 1), stmt.Backing.TypeID())
+	for _, member := range stmt.Type.Members {
+		fmt.Fprintf(&b, "%v%v = %v\n", indentStr(indent + 1), member.Ident, member.Value)
+	}
+	fmt.Fprintf(&b, "%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Type declaration statement.
+// Example:
+//  type Meters = uint32; // Alias, Meters and uint32 are interchangeable
+//  type UserId uint64; // Distinct type, converting needs a cast
+type TypeDeclStmt struct {
+	// The type token. Used for error handling.
+	TypeToken lexer.Token
+	Ident     lexer.Token
+	IsAlias   bool
+	// The declared type. Resolved during semantics.
+	Type      semantics.Type
+}
+
+func (stmt *TypeDeclStmt) Semantics(s *semantics.SemanticChecker) error {
+	return s.FailDeclaration(stmt.Ident, stmt.declare(s))
+}
+
+func (stmt *TypeDeclStmt) declare(s *semantics.SemanticChecker) error {
+	declType, err := s.ResolveType(stmt.Type)
+	if err != nil {
+		return err
+	}
+
+	if declType.TypeID() == semantics.UNDEFINED {
+		return s.AddError(
+			diagnostics.InvalidDeclaration,
+			fmt.Sprintf("Cannot declare type '%v' from an undefined type", stmt.Ident.Value),
+			stmt.Ident,
+		)
+	}
+
+	if stmt.IsAlias {
+		stmt.Type = declType
+	} else {
+		stmt.Type = semantics.Named{
+			Name: stmt.Ident.Value,
+			Decl: s.DeclAt(stmt.Ident),
+			Underlying: semantics.Underlying(declType),
+		}
+	}
+
+	return s.PushType(stmt.Ident.Value, stmt.Type, stmt.Ident)
+}
+
+// Types are resolved at compile time so no code is emitted.
+func (stmt TypeDeclStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- TypeDeclStmt: %v -------------------------\n", stmt.Ident.Value)
+}
+
+func (stmt TypeDeclStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vTypeDeclStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%vIdent: %v\n", indentStr(indent + 1), stmt.Ident.Value)
+	fmt.Fprintf(&b, "%vIsAlias: %v\n", indentStr(indent + 1), stmt.IsAlias)
+	fmt.Fprintf(&b, "%vType: %v\n", indentStr(indent + 1), semantics.Underlying(stmt.Type).TypeID())
+	fmt.Fprintf(&b, "%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// A variant of a union declaration and its payload field types.
+type UnionVariantDecl struct {
+	Ident  lexer.Token
+	Fields []semantics.Type
+}
+
+// Union declaration statement.
+// Example:
+//  union Shape { Circle(uint32), Rect(uint32, uint32), Empty }
+type UnionDeclStmt struct {
+	// The union token. Used for error handling.
+	UnionToken lexer.Token
+	Ident      lexer.Token
+	Variants   []UnionVariantDecl
+	// The declared type. Set during semantics.
+	Type       semantics.Union
+}
+
+func (stmt *UnionDeclStmt) Semantics(s *semantics.SemanticChecker) error {
+	return s.FailDeclaration(stmt.Ident, stmt.declare(s))
+}
+
+func (stmt *UnionDeclStmt) declare(s *semantics.SemanticChecker) error {
+	if len(stmt.Variants) == 0 {
+		return s.AddError(
+			diagnostics.InvalidDeclaration,
+			fmt.Sprintf("Union %v must have at least one variant", stmt.Ident.Value),
+			stmt.Ident,
+		)
+	}
+
+	variants := []semantics.UnionVariant{}
+	for _, variantDecl := range stmt.Variants {
+		for _, other := range variants {
+			if other.Ident == variantDecl.Ident.Value {
+				return s.AddError(
+					diagnostics.InvalidDeclaration,
+					fmt.Sprintf("Duplicate variant '%v' in union %v", other.Ident, stmt.Ident.Value),
+					variantDecl.Ident,
+				)
+			}
+		}
+
+		variant := semantics.UnionVariant{ Ident: variantDecl.Ident.Value }
+		for _, field := range variantDecl.Fields {
+			fieldType, err := s.ResolveType(field)
+			if err != nil {
+				return err
+			}
+
+			if fieldType.TypeID() == semantics.UNDEFINED {
+				return s.AddError(
+					diagnostics.InvalidDeclaration,
+					fmt.Sprintf("Variant '%v' has a field of undefined type", variant.Ident),
+					variantDecl.Ident,
+				)
+			}
+			variant.Fields = append(variant.Fields, fieldType)
+		}
+		variants = append(variants, variant)
+	}
+	stmt.Type = semantics.NewUnion(stmt.Ident.Value, s.DeclAt(stmt.Ident), variants)
+
+	return s.PushType(stmt.Ident.Value, stmt.Type, stmt.Ident)
+}
+
+// Unions are resolved at compile time so no code is emitted.
+func (stmt UnionDeclStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(
+		e,
+		"; ------------------------- UnionDeclStmt: %v size = %v -------------------------\n",
+		stmt.Ident.Value,
+		stmt.Type.Size(),
+	)
+}
+
+func (stmt UnionDeclStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vUnionDeclStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%vIdent: %v\n", indentStr(indent + 1), stmt.Ident.Value)
+	for _, variant := range stmt.Type.Variants {
+		fmt.Fprintf(&b, "%v%v%v offsets = %v\n", indentStr(indent + 1), variant.Ident, variant.Fields, variant.Offsets)
+	}
+	fmt.Fprintf(&b, "%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Declares a function defined outside of Clovis, usually in C.
+// Example:
+//  extern uint64 strlen(uint8* s);
+//  extern uint32 printf(uint8* format, ...);
+// Calls follow the System V ABI so only values fitting in a register can be passed and returned.
+type ExternDeclStmt struct {
+	// The extern token. Used for error handling.
+	ExternToken lexer.Token
+	Ident       lexer.Token
+	Return      utils.Optional[semantics.Type]
+	Params      []semantics.Type
+	Variadic    bool
+	// The declared signature. Set during semantics.
+	Type        semantics.Function
+}
+
+func (stmt *ExternDeclStmt) Semantics(s *semantics.SemanticChecker) error {
+	return s.FailDeclaration(stmt.Ident, stmt.declare(s))
+}
+
+func (stmt *ExternDeclStmt) declare(s *semantics.SemanticChecker) error {
+	name := stmt.Ident.Value
+	if name == "main" || name == "_start" || strings.HasPrefix(name, "__clovis") {
+		return s.AddError(diagnostics.ReservedName, fmt.Sprintf("The name '%v' is reserved by the compiler", name), stmt.Ident)
+	}
+
+	for _, r := range name {
+		if r > unicode.MaxASCII {
+			return s.AddError(
+				diagnostics.InvalidDeclaration,
+				fmt.Sprintf("Extern function name '%v' must be ASCII", name),
+				stmt.Ident,
+			).WithHint("External symbols are linked by name and the assembler only takes ASCII names")
+		}
+	}
+
+	fn := semantics.Function{
+		Name: name,
+		Return: semantics.Undefined{},
+		Variadic: stmt.Variadic,
+	}
+
+	if stmt.Return.HasVal() {
+		returnType, err := s.ResolveType(stmt.Return.Value())
+		if err != nil {
+			return err
+		}
+
+		if !isScalar(returnType) {
+			return s.AddError(
+				diagnostics.RegisterSize,
+				fmt.Sprintf("Extern function %v cannot return %v, only values fitting in a register", name, returnType.TypeID()),
+				stmt.Ident,
+			)
+		}
+		fn.Return = returnType
+	}
+
+	for i, param := range stmt.Params {
+		paramType, err := s.ResolveType(param)
+		if err != nil {
+			return err
+		}
+
+		if !isScalar(paramType) {
+			return s.AddError(
+				diagnostics.RegisterSize,
+				fmt.Sprintf("Parameter %v of extern function %v has type %v which does not fit in a register", i, name, paramType.TypeID()),
+				stmt.Ident,
+			)
+		}
+		fn.Params = append(fn.Params, paramType)
+	}
+	stmt.Type = fn
+
+	return s.PushFunction(name, fn, stmt.Ident)
+}
+
+// Returns whether values of the type are passed and returned in a single integer register.
+func isScalar(t semantics.Type) bool {
+	switch semantics.Underlying(t).(type) {
+	case semantics.Ptr, semantics.Enum, semantics.Bool:
+		return true
+	}
+
+	return semantics.IsNumber(t) && t.TypeID() != semantics.UINT_LIT
+}
+
+// The $ prefix lets NASM accept symbols named like instructions or registers.
+func (stmt ExternDeclStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- ExternDeclStmt: %v -------------------------\n", stmt.Ident.Value)
+	fmt.Fprintf(e, "extern $%v\n", stmt.Ident.Value)
+}
+
+func (stmt ExternDeclStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vExternDeclStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%vIdent: %v\n", indentStr(indent + 1), stmt.Ident.Value)
+	for _, param := range stmt.Params {
+		fmt.Fprintf(&b, "%vParam: %v\n", indentStr(indent + 1), param.TypeID())
+	}
+	if stmt.Return.HasVal() {
+		fmt.Fprintf(&b, "%vReturn: %v\n", indentStr(indent + 1), stmt.Return.Value().TypeID())
+	}
+	fmt.Fprintf(&b, "%vVariadic: %v\n", indentStr(indent + 1), stmt.Variadic)
+	fmt.Fprintf(&b, "%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// An arm of a match statement.
+type MatchArm struct {
+	// The case or default token. Used for error handling.
+	Token     lexer.Token
+	IsDefault bool
+	Variant   lexer.Token
+	// Names bound to the payload fields, '_' skips a field.
+	Bindings  []lexer.Token
+	Stmt      Statement
+	// The discriminant of the matched variant. Set during semantics.
+	Tag       int
+	// The bound payload fields and their symbols. Set during semantics.
+	Fields    []int
+	Symbols   []semantics.Symbol
+	// The size of the bindings and the symbols declared by the arm's statement.
+	Size      int
+}
+
+// Match statement over a tagged union.
+// Example:
+//  match shape {
+//      case Circle(r): area = r * r * 3;
+//      case Rect(w, h): area = w * h;
+//  }
+// Each arm binds copies of the payload fields in its own block scope.
+// Without a default arm every variant must be matched.
+type MatchStmt struct {
+	// The match token. Used for error handling.
+	MatchToken    lexer.Token
+	Subject       Expression
+	Arms          []MatchArm
+	// Holds the address of the subject while the arms run. Set during semantics.
+	SubjectSymbol semantics.Symbol
+	// The size of the match block's own symbols.
+	Size          int
+}
+
+func (stmt *MatchStmt) Semantics(s *semantics.SemanticChecker) error {
+	s.PushBlock()
+	err := stmt.checkArms(s)
+	stmt.Size = s.PopBlock()
+
+	return err
+}
+
+func (stmt *MatchStmt) checkArms(s *semantics.SemanticChecker) error {
+	if err := stmt.Subject.Semantics(s); err != nil {
+		return err
+	}
+
+	union, isUnion := semantics.Underlying(stmt.Subject.ExprType()).(semantics.Union)
+	if !isUnion {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("Match expects a union but received %v", stmt.Subject.ExprType().TypeID()),
+			stmt.MatchToken,
+		)
+	}
+
+	// The identifier cannot be written in source so it never clashes with user symbols.
+	if err := s.PushSymbol(".match", semantics.Ptr{ ValueType: union }, stmt.MatchToken); err != nil {
+		return err
+	}
+	stmt.SubjectSymbol, _ = s.TopSymbol()
+
+	hasDefault := false
+	matched := make([]bool, len(union.Variants))
+
+	for i := range stmt.Arms {
+		arm := &stmt.Arms[i]
+
+		if arm.IsDefault {
+			if hasDefault {
+				return s.AddError(diagnostics.InvalidArms, "Match statement has more than one default arm", arm.Token)
+			}
+			hasDefault = true
+		} else {
+			variant, tag, exists := union.Variant(arm.Variant.Value)
+			if !exists {
+				return s.AddError(
+					diagnostics.UnknownMember,
+					fmt.Sprintf("Union %v has no variant '%v'", union.Name, arm.Variant.Value),
+					arm.Variant,
+				)
+			}
+
+			if matched[tag] {
+				return s.AddError(
+					diagnostics.InvalidArms,
+					fmt.Sprintf("Variant '%v' is matched more than once", variant.Ident),
+					arm.Variant,
+				)
+			}
+			matched[tag] = true
+			arm.Tag = tag
+
+			if len(arm.Bindings) != len(variant.Fields) {
+				return s.AddError(
+					diagnostics.ArgumentCount,
+					fmt.Sprintf(
+						"Variant '%v' has %v fields but %v were bound",
+						variant.Ident,
+						len(variant.Fields),
+						len(arm.Bindings),
+					),
+					arm.Variant,
+				)
+			}
+		}
+
+		s.PushBlock()
+		err := stmt.checkArm(s, arm, union)
+		arm.Size = s.PopBlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	if hasDefault {
+		return nil
+	}
+
+	missing := []string{}
+	for tag, variant := range union.Variants {
+		if !matched[tag] {
+			missing = append(missing, variant.Ident)
+		}
+	}
+
+	if len(missing) != 0 {
+		return s.AddError(
+			diagnostics.InvalidArms,
+			fmt.Sprintf("Match on union %v is not exhaustive, missing %v", union.Name, strings.Join(missing, ", ")),
+			stmt.MatchToken,
+		).WithHint("Add the missing variants or a default arm")
+	}
+
+	return nil
+}
+
+// Binds the payload fields of the arm and checks its statement inside the arm's block.
+func (stmt *MatchStmt) checkArm(s *semantics.SemanticChecker, arm *MatchArm, union semantics.Union) error {
+	arm.Fields = []int{}
+	arm.Symbols = []semantics.Symbol{}
+
+	if !arm.IsDefault {
+		variant := union.Variants[arm.Tag]
+		for i, binding := range arm.Bindings {
+			if binding.Value == "_" {
+				continue
+			}
+
+			if err := s.PushSymbol(binding.Value, variant.Fields[i], binding); err != nil {
+				return err
+			}
+			symbol, _ := s.TopSymbol()
+			arm.Fields = append(arm.Fields, i)
+			arm.Symbols = append(arm.Symbols, symbol)
+		}
+	}
+
+	return arm.Stmt.Semantics(s)
+}
+
+func (stmt MatchStmt) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ------------------------- MatchStmt ------------------------- \n")
+	union := semantics.Underlying(stmt.Subject.ExprType()).(semantics.Union)
+
+	stmt.Subject.EmitCode(e)
+	fmt.Fprintf(e, "sub rsp, %v\n", stmt.SubjectSymbol.Size + stmt.SubjectSymbol.Padding)
+	fmt.Fprintf(e, "mov QWORD [rbp - %v], rax\n", stmt.SubjectSymbol.Offset)
+	e.LoadRax(union.TagType().Size(), "[rax]")
+
+	armLabels := make([]string, len(stmt.Arms))
+	for i := range stmt.Arms {
+		armLabels[i] = e.NextLabel()
+	}
+	endLabel := e.NextLabel()
+
+	fallbackLabel := endLabel
+	for i, arm := range stmt.Arms {
+		if arm.IsDefault {
+			fallbackLabel = armLabels[i]
+			continue
+		}
+
+		fmt.Fprintf(e, "cmp rax, %v\n", arm.Tag)
+		fmt.Fprintf(e, "je %v\n", armLabels[i])
+	}
+	fmt.Fprintf(e, "jmp %v\n", fallbackLabel)
+
+	for i, arm := range stmt.Arms {
+		fmt.Fprintf(e, "%v:\n", armLabels[i])
+
+		for j, symbol := range arm.Symbols {
+			field := arm.Fields[j]
+			fieldOffset := union.Variants[arm.Tag].Offsets[field]
+
+			fmt.Fprintf(e, "; binding %v = %v.%v\n", symbol.Ident, union.Variants[arm.Tag].Ident, field)
+			fmt.Fprintf(e, "sub rsp, %v\n", symbol.Size + symbol.Padding)
+			fmt.Fprintf(e, "mov rbx, QWORD [rbp - %v]\n", stmt.SubjectSymbol.Offset)
+
+			if isAggregate(symbol.Type) {
+				fmt.Fprintf(e, "lea rsi, [rbx + %v]\n", fieldOffset)
+				fmt.Fprintf(e, "lea rdi, [rbp - %v]\n", symbol.Offset)
+				fmt.Fprintf(e, "mov rcx, %v\n", symbol.Size)
+				fmt.Fprintf(e, "rep movsb\n")
+			} else {
+				emitLoad(e, symbol.Type, fmt.Sprintf("rbx + %v", fieldOffset))
+				emitStore(e, symbol.Type, fmt.Sprintf("rbp - %v", symbol.Offset))
+			}
+		}
+
+		arm.Stmt.EmitCode(e)
+		fmt.Fprintf(e, "add rsp, %v\n", arm.Size)
+		fmt.Fprintf(e, "jmp %v\n", endLabel)
+	}
+
+	fmt.Fprintf(e, "%v:\n", endLabel)
+	fmt.Fprintf(e, "add rsp, %v\n", stmt.Size)
+}
+
+func (stmt MatchStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vMatchStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Subject.Print(indent + 1))
+	for _, arm := range stmt.Arms {
+		if arm.IsDefault {
+			fmt.Fprintf(&b, "%vdefault:", indentStr(indent + 1))
+		} else {
+			bindings := []string{}
+			for _, binding := range arm.Bindings {
+				bindings = append(bindings, binding.Value)
+			}
+			fmt.Fprintf(&b, "%vcase %v(%v):", indentStr(indent + 1), arm.Variant.Value, strings.Join(bindings, ", "))
+		}
+		fmt.Fprintf(&b, "%v\n", arm.Stmt.Print(indent + 2))
+	}
+	fmt.Fprintf(&b, "%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// Expression statement.
+type ExpressionStmt struct {
+	Expr Expression
+}
+
+func (stmt *ExpressionStmt) Semantics(s *semantics.SemanticChecker) error {
+	if inPlace, isInPlace := stmt.Expr.(InPlaceExpression); isInPlace {
+		inPlace.SetInPlace()
+	}
+
+	return stmt.Expr.Semantics(s)
+}
+
+// In place expressions are built in a temporary on the stack that is discarded afterwards.
+func (stmt ExpressionStmt) EmitCode(e *codegen.Emitter) {
+	if _, isInPlace := stmt.Expr.(InPlaceExpression); isInPlace {
+		size := semantics.Align16(stmt.Expr.ExprType().Size())
+		fmt.Fprintf(e, "sub rsp, %v\n", size)
+		fmt.Fprintf(e, "mov rax, rsp\n")
+		stmt.Expr.EmitCode(e)
+		fmt.Fprintf(e, "add rsp, %v\n", size)
+		return
+	}
+
+	stmt.Expr.EmitCode(e)
+}
+
+func (stmt ExpressionStmt) Print(indent int) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "\n%vExpressionStmt\n%v{\n", indentStr(indent), indentStr(indent))
+	fmt.Fprintf(&b, "%v\n", stmt.Expr.Print(indent + 1))
+	fmt.Fprintf(&b, "\n%v}", indentStr(indent))
+
+	return b.String()
+}
+
+// This interface represents an expression in the language.
+// and holds the needed functions for semantic analysis and code generation.
+// All expressions must have a type that can be check with the ExprType() function.
+type Expression interface {
+	ExprType() semantics.Type
+	// This checks whether the expression is semantically correct.
+	// Also sets some extra information that is used by the emitter.
+	Semantics(s *semantics.SemanticChecker) error
+	// Using codegen.Emitter this emits the assembly code for the expression.
+	// All expressions are evaluated in the rax register.
+	EmitCode(e *codegen.Emitter)
+	// Returns whether the expression is addressable.
+	IsAddressable() bool
+	// Pretty prints the expression.
+	Print(indent int) string
+}
+
+// An AddressableExpression implements everything that a Expression implements
+// it just represents expressions that have a memory location or point to one.
+type AddressableExpression interface {
+	Expression
+	// Moves the address of the expression into the rax register.
+	EmitAddressCode(e *codegen.Emitter)
+}
+
+// An InPlaceExpression builds its value directly at a destination instead of evaluating to it.
+// EmitCode expects the address of the destination in rax.
+type InPlaceExpression interface {
+	Expression
+	// Marks that the enclosing statement or expression provides the destination.
+	SetInPlace()
+}
+
+// A binary expression holds a left value and a right value and an operator.
+type BinaryExpression struct {
+	Type  semantics.Type
+	Left  Expression
+	Op	  lexer.Token
+	Right Expression
+}
+
+func (exp BinaryExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *BinaryExpression) Semantics(s *semantics.SemanticChecker) error {
+	if err := exp.Left.Semantics(s); err != nil {
+		return err
+	}
+
+	if err := exp.Right.Semantics(s); err != nil {
+		return err
+	}
+		
+	l, t := exp.Left.ExprType().CanUseOperator(exp.Op.Value, exp.Right.ExprType())
+	if !l {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf(
+				"Cannot use operator '%v' between types %v and %v", 
+				exp.Op.Value,
+				exp.Left.ExprType().TypeID(), 
+				exp.Right.ExprType().TypeID(),
+			),
+			exp.Op,
+		)
+	}
+	exp.Type = t
+	
+	return nil
+}
+
+// Binary expressions are evaluated in the rax register.
+func (exp BinaryExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; BinaryExpression: type = %v op = %v\n", exp.Type.TypeID(), exp.Op.Value)
+	if optional, ok := exp.NoneOperand(); ok {
+		// Only the presence flag at the optional's address is compared, none is never evaluated.
+		optional.EmitCode(e)
+		e.WriteString("cmp BYTE [rax], 0\n")
+		if exp.Op.Value == "==" {
+			e.WriteString("sete al\n")
+		} else {
+			e.WriteString("setne al\n")
+		}
+		return
+	}
+
+	exp.Right.EmitCode(e)
+	e.WriteString("push rax\n")
+	exp.Left.EmitCode(e)
+	e.WriteString("pop rbx\n")
+	
+	// TODO: Clean up the binary operation logic
+	binOp := codegen.ASMBinaryOp(exp.Op)
+	if binOp == "add" || binOp == "sub" {
+		fmt.Fprintf(e, "%v rax, rbx\n", binOp)
+	} else if binOp == "mul" || binOp == "div" {
+		fmt.Fprintf(e, "%v rbx\n", binOp)
+	} else if exp.Type.TypeID() == semantics.BOOL {
+		fmt.Fprintf(e, "cmp rax, rbx\n")
+		fmt.Fprintf(e, "%v al\n", binOp)
+	}
+}
+
+// Returns the optional operand when the expression compares an optional with none.
+func (exp BinaryExpression) NoneOperand() (Expression, bool) {
+	if exp.Right.ExprType().TypeID() == semantics.NONE_LIT {
+		return exp.Left, exp.Left.ExprType().TypeID() != semantics.NONE_LIT
+	}
+
+	if exp.Left.ExprType().TypeID() == semantics.NONE_LIT {
+		return exp.Right, true
+	}
+
+	return nil, false
+}
+
+func (_ BinaryExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp BinaryExpression) Print(indent int) string {
+	result := fmt.Sprintf("BinaryExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type)
+	result += fmt.Sprintf("%v\n", exp.Left.Print(indent + 1))
+	result += fmt.Sprintf("%vOp: %v\n", indentStr(indent + 1), exp.Op)
+	result += fmt.Sprintf("%v", exp.Right.Print(indent + 1))
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// A conditional expression evaluates only one of its branches.
+// Example:
+//  uint32 y = x > 10 ? x : 10;
+type ConditionalExpression struct {
+	Type      semantics.Type
+	Condition Expression
+	// The question mark token. Used for error handling.
+	Question  lexer.Token
+	Then      Expression
+	Else      Expression
+}
+
+func (exp ConditionalExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *ConditionalExpression) Semantics(s *semantics.SemanticChecker) error {
+	if err := exp.Condition.Semantics(s); err != nil {
+		return err
+	}
+
+	if exp.Condition.ExprType().TypeID() != semantics.BOOL {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf(
+				"Conditional expression condition must be of type BOOL received %v",
+				exp.Condition.ExprType().TypeID(),
+			),
+			exp.Question,
+		)
+	}
+
+	if err := exp.Then.Semantics(s); err != nil {
+		return err
+	}
+
+	if err := exp.Else.Semantics(s); err != nil {
+		return err
+	}
+
+	// Literals adapt to the type of the other branch.
+	branchType, otherType := exp.Then.ExprType(), exp.Else.ExprType()
+	if branchType.TypeID() == semantics.UINT_LIT {
+		branchType, otherType = otherType, branchType
+	}
+
+	if !branchType.Equals(otherType) {
+		return s.AddError(
+			diagnostics.TypeMismatch,
+			fmt.Sprintf(
+				"Conditional expression branches have different types %v and %v",
+				exp.Then.ExprType().TypeID(),
+				exp.Else.ExprType().TypeID(),
+			),
+			exp.Question,
+		)
+	}
+	exp.Type = branchType
+
+	return nil
+}
+
+func (exp ConditionalExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ConditionalExpression: type = %v\n", exp.Type.TypeID())
+	exp.Condition.EmitCode(e)
+	fmt.Fprintf(e, "cmp al, 1\n")
+	elseLabel := e.NextLabel()
+	fmt.Fprintf(e, "jne %v\n", elseLabel)
+	exp.Then.EmitCode(e)
+	endLabel := e.NextLabel()
+	fmt.Fprintf(e, "jmp %v\n", endLabel)
+	fmt.Fprintf(e, "%v:\n", elseLabel)
+	exp.Else.EmitCode(e)
+	fmt.Fprintf(e, "%v:\n", endLabel)
+}
+
+func (_ ConditionalExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp ConditionalExpression) Print(indent int) string {
+	result := fmt.Sprintf("ConditionalExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type.TypeID())
+	result += fmt.Sprintf("%v\n", exp.Condition.Print(indent + 1))
+	result += fmt.Sprintf("%v\n", exp.Then.Print(indent + 1))
+	result += exp.Else.Print(indent + 1)
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// A prefix expression holds a unary operator and a right value.
+type PrefixExpression struct {
+	Type        semantics.Type
+	Op    	    lexer.Token
+	Right 	    Expression
+	Addressable bool
+}
+
+func (exp PrefixExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+// TODO: PrefixExpression.Semantics for "-" and "!"
+func (exp *PrefixExpression) Semantics(s *semantics.SemanticChecker) error {
+	return nil
+}
+
+// TODO: PrefixExpression.EmitCode for "-" and "!"
+func (exp PrefixExpression) EmitCode(e *codegen.Emitter) {
+
+}
+
+func (exp PrefixExpression) IsAddressable() bool {
+	return exp.Addressable
+}
+
+func (exp PrefixExpression) Print(indent int) string {
 	result := fmt.Sprintf("PrefixExpression\n%v{\n", indentStr(indent))
 	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type)
 	result += fmt.Sprintf("%vOp: %v\n", indentStr(indent + 1), exp.Op)
-	result += exp.Right.Print(indent + 1)
+	result += exp.Right.Print(indent + 1)
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// TODO: PostfixExpression
+// A postfix expression holds a unary operator and a left value.
+type PostfixExpression struct {
+	Type        semantics.Type
+	Left        Expression
+	Op          lexer.Token
+	Addressable bool
+}
+
+func (exp PostfixExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *PostfixExpression) Semantics(s *semantics.SemanticChecker) error {
+	return nil
+}
+
+func (exp PostfixExpression) EmitCode(e *codegen.Emitter) {
+	
+}
+
+func (exp PostfixExpression) IsAddressable() bool {
+	return exp.Addressable
+}
+
+func (exp PostfixExpression) Print(indent int) string {
+	return ""
+}
+
+// A dereference expression.
+// Example:
+//	uint32 y = *x; // *x returns the value (rvalue) stored at the location where x is pointing to
+//  *x = 69; // Moves value 69 to the location x is pointing to (here *x returns an lvalue)
+type DerefExpression struct {
+	Type   semantics.Type
+	Op     lexer.Token
+	Right  Expression
+}
+
+func (exp DerefExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *DerefExpression) Semantics(s *semantics.SemanticChecker) error {
+	if err := exp.Right.Semantics(s); err != nil {
+		return err
+	}
+	
+	ptr, isPtr := semantics.Underlying(exp.Right.ExprType()).(semantics.Ptr)
+	if !isPtr {
+		return s.AddError(
+			 diagnostics.InvalidOperand,
+			 fmt.Sprintf(
+				 "'*' dereference operator expected a PTR not %v", 
+				 exp.Right.ExprType().TypeID(),
+			 ),
+			 exp.Op,
+		)
+	}
+	exp.Type = ptr.ValueType
+	
+	return nil
+}
+
+func (exp DerefExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; DerefExpression rvalue type = %v\n", exp.Type.TypeID())
+	exp.Right.EmitCode(e)
+	emitLoad(e, exp.Type, "rax")
+}
+
+func (exp DerefExpression) EmitAddressCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; DerefExpression lvalue type = %v\n", exp.Type.TypeID())
+	exp.Right.EmitCode(e)
+}
+
+func (exp DerefExpression) IsAddressable() bool {
+	return true
+}
+
+func (exp DerefExpression) Print(indent int) string {
+	return ""
+}
+
+// A refence expression.
+// Example:
+//	uint32* xPtr = &x; // &x returns the address of x
+type ReferenceExpression struct {
+	Type  semantics.Type
+	Op    lexer.Token
+	Right Expression
+}
+
+func (exp ReferenceExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *ReferenceExpression) Semantics(s *semantics.SemanticChecker) error {
+	if err := exp.Right.Semantics(s); err != nil {
+		return err
+	}
+
+	if !exp.Right.IsAddressable() {
+		return s.AddError(
+			diagnostics.NotAddressable,
+			"Expected an addressable expression",
+			exp.Op,
+		)
+	}
+
+	if variable, isVariable := frameVariable(exp.Right); isVariable && s.IsCaptured(variable.Symbol) {
+		return s.AddError(
+			diagnostics.Misplaced,
+			fmt.Sprintf(
+				"Cannot take the address of '%v' inside a spawn block since the thread only has a copy of it, declare it shared instead",
+				variable.Ident.Value,
+			),
+			exp.Op,
+		)
+	}
+
+	exp.Type = semantics.Ptr{ ValueType: exp.Right.ExprType() }
+
+	return nil
+}
+
+// Returns the variable an addressable expression is stored in when it is part of a variable
+// rather than reached through a pointer.
+func frameVariable(expr Expression) (*IdentExpression, bool) {
+	switch expr := expr.(type) {
+	case *IdentExpression:
+		return expr, true
+	case *GroupExpression:
+		return frameVariable(expr.Expr)
+	case *MemberExpression:
+		if expr.Qualified != nil {
+			return expr.Qualified, true
+		}
+	case *ArrayAccessExpression:
+		if _, isArray := semantics.Underlying(expr.Left.ExprType()).(semantics.Array); isArray {
+			return frameVariable(expr.Left)
+		}
+	}
+
+	return nil, false
+}
+
+func (exp ReferenceExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ReferenceExpression type = %v\n", exp.Type.TypeID())
+	addr, _ := exp.Right.(AddressableExpression)
+	addr.EmitAddressCode(e)
+}
+
+func (exp ReferenceExpression) EmitAddressCode(e *codegen.Emitter) {
+	// rax already holds the address
+	panic("&&x or &(&x) is incorrect usage")
+}
+
+func (exp ReferenceExpression) IsAddressable() bool {
+	return true
+}
+
+func (exp ReferenceExpression) Print(indent int) string {
+	return ""
+}
+
+// An array access expression.
+// Example:
+//  uint32[3] xs;
+//  xs[1] = 2;
+//  assert xs[1] == 2;
+// Indexing through a slice is bounds checked and exits with status 1 when out of range.
+type ArrayAccessExpression struct {
+	Type        semantics.Type
+	Left        Expression
+	IndexExpr   Expression
+	// For error handling.
+	OpenBracket lexer.Token
+}
+
+func (exp ArrayAccessExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *ArrayAccessExpression) Semantics(s *semantics.SemanticChecker) error {
+	if err := exp.Left.Semantics(s); err != nil {
+		return err
+	}
+
+	switch left := semantics.Underlying(exp.Left.ExprType()).(type) {
+	case semantics.Array:
+		exp.Type = left.Base
+	case semantics.Slice:
+		exp.Type = left.Base
+	default:
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf(
+				"'[]' operator can be only used on arrays and slices but received %v",
+				exp.Left.ExprType().TypeID(),
+			),
+			exp.OpenBracket,
+		)
+	}
+
+	if err := exp.IndexExpr.Semantics(s); err != nil {
+		return err
+	}
+
+	if !semantics.IsNumber(exp.IndexExpr.ExprType()) {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("Index must be an unsigned integer but received %v", exp.IndexExpr.ExprType().TypeID()),
+			exp.OpenBracket,
+		)
+	}
+
+	return nil
+}
+
+// Leaves the address of the first element in rbx and the byte offset of the indexed element in rax.
+func (exp ArrayAccessExpression) emitOffset(e *codegen.Emitter) {
+	if _, isSlice := semantics.Underlying(exp.Left.ExprType()).(semantics.Slice); isSlice {
+		exp.Left.EmitCode(e)
+		fmt.Fprintf(e, "push rax\n")
+		fmt.Fprintf(e, "push rdx\n")
+		exp.IndexExpr.EmitCode(e)
+		fmt.Fprintf(e, "pop rdx\n")
+		fmt.Fprintf(e, "cmp rax, rdx\n")
+		inBoundsLabel := e.NextLabel()
+		fmt.Fprintf(e, "jb %v\n", inBoundsLabel)
+		e.Fail("__clovis_msg_index_bounds", "clovis: slice index out of range")
+		fmt.Fprintf(e, "%v:\n", inBoundsLabel)
+	} else {
+		addrExp, _ := exp.Left.(AddressableExpression)
+		addrExp.EmitAddressCode(e)
+		fmt.Fprintf(e, "push rax\n")
+		exp.IndexExpr.EmitCode(e)
+	}
+
+	fmt.Fprintf(e, "mov rbx, %v\n", exp.Type.Size())
+	fmt.Fprintf(e, "mul rbx\n")
+	fmt.Fprintf(e, "pop rbx\n")
+}
+
+func (exp ArrayAccessExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ArrayAccessExpression rvalue type = %v\n", exp.Type.TypeID())
+	exp.emitOffset(e)
+	emitLoad(e, exp.Type, "rbx + rax")
+}
+
+func (exp ArrayAccessExpression) EmitAddressCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ArrayAccessExpression lvalue type = %v\n", exp.Type.TypeID())
+	exp.emitOffset(e)
+	fmt.Fprintf(e, "lea rax, [rbx + rax]\n")
+}
+
+func (_ ArrayAccessExpression) IsAddressable() bool {
+	return true
+}
+
+func (exp ArrayAccessExpression) Print(indent int) string {
+	return ""
+}
+
+// A member access expression.
+// Example:
+//  Color c = Color.Red; // Enum constant
+//  uint64 n = xs.len; // Length of an array or slice
+//  Shape s = Shape.Empty; // Union variant without fields, same as Shape.Empty()
+type MemberExpression struct {
+	Type   semantics.Type
+	Left   Expression
+	// The dot token. Used for error handling.
+	Dot    lexer.Token
+	Member lexer.Token
+	// The value of an enum constant or an array length, or the tag of a union variant.
+	Value  uint64
+	// The variable a module qualified name such as math.PI refers to.
+	Qualified *IdentExpression
+	// Set for a union variant without fields. Its value is a constant emitted in .rodata.
+	Variant   bool
+}
+
+func (exp MemberExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *MemberExpression) Semantics(s *semantics.SemanticChecker) error {
+	if module, isModule := moduleName(s, exp.Left); isModule {
+		symbol, err := s.GetQualifiedSymbol(module, exp.Member)
+		if err != nil {
+			return err
+		}
+
+		if symbol.IsType {
+			return s.AddError(
+				diagnostics.WrongSymbolKind,
+				fmt.Sprintf("'%v.%v' is a type and cannot be used as a value", module.Value, exp.Member.Value),
+				exp.Member,
+			)
+		}
+		exp.Qualified = &IdentExpression{ Type: symbol.Type, Ident: exp.Member, Symbol: *symbol }
+		exp.Type = symbol.Type
+
+		return nil
+	}
+
+	if t, isType := lookupTypeName(s, exp.Left); isType {
+		if union, isUnion := semantics.Underlying(t).(semantics.Union); isUnion {
+			return exp.checkVariant(s, union)
+		}
+
+		enum, isEnum := t.(semantics.Enum)
+		if !isEnum {
+			return s.AddError(
+				diagnostics.UnknownMember,
+				fmt.Sprintf("Type %v has no member '%v'", t.TypeID(), exp.Member.Value),
+				exp.Member,
+			)
+		}
+
+		member, exists := enum.Member(exp.Member.Value)
+		if !exists {
+			return s.AddError(
+				diagnostics.UnknownMember,
+				fmt.Sprintf("Enum %v has no member '%v'", enum.Name, exp.Member.Value),
+				exp.Member,
+			)
+		}
+		exp.Type = enum
+		exp.Value = member.Value
+
+		return nil
+	}
+
+	if err := exp.Left.Semantics(s); err != nil {
+		return err
+	}
+
+	if exp.Member.Value == "len" {
+		switch left := semantics.Underlying(exp.Left.ExprType()).(type) {
+		case semantics.Array:
+			exp.Type = semantics.Uint64{}
+			exp.Value = uint64(left.Length)
+			return nil
+		case semantics.Slice:
+			exp.Type = semantics.Uint64{}
+			return nil
+		}
+	}
+
+	return s.AddError(
+		diagnostics.UnknownMember,
+		fmt.Sprintf("Type %v has no member '%v'", exp.Left.ExprType().TypeID(), exp.Member.Value),
+		exp.Member,
+	)
+}
+
+// Checks a union variant named without arguments, which must have no fields.
+func (exp *MemberExpression) checkVariant(s *semantics.SemanticChecker, union semantics.Union) error {
+	variant, tag, exists := union.Variant(exp.Member.Value)
+	if !exists {
+		return s.AddError(
+			diagnostics.UnknownMember,
+			fmt.Sprintf("Union %v has no variant '%v'", union.Name, exp.Member.Value),
+			exp.Member,
+		)
+	}
+
+	if len(variant.Fields) != 0 {
+		return s.AddError(
+			diagnostics.ArgumentCount,
+			fmt.Sprintf("Variant '%v' expects %v fields but received 0", variant.Ident, len(variant.Fields)),
+			exp.Member,
+		).WithHint(fmt.Sprintf("Pass the fields as in %v.%v(...)", union.Name, variant.Ident))
+	}
+	exp.Type = union
+	exp.Value = uint64(tag)
+	exp.Variant = true
+
+	return nil
+}
+
+// Returns the bytes of a union value holding the variant with the given tag and no fields.
+func variantBytes(union semantics.Union, tag uint64) []byte {
+	bytes := make([]byte, union.Size())
+	for i := range union.TagType().Size() {
+		bytes[i] = byte(tag >> (8 * i))
+	}
+
+	return bytes
+}
+
+func (exp MemberExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; MemberExpression: type = %v member = %v\n", exp.Type.TypeID(), exp.Member.Value)
+	if exp.Qualified != nil {
+		exp.Qualified.EmitCode(e)
+		return
+	}
+
+	if exp.Variant {
+		union := exp.Type.(semantics.Union)
+		label := e.NextUnscopedLabel()
+		e.WriteROData(codegen.DataDefinition(label, union.Align(), variantBytes(union, exp.Value)))
+		fmt.Fprintf(e, "lea rax, [rel %v]\n", label)
+		return
+	}
+
+	if _, isSlice := semantics.Underlying(exp.Left.ExprType()).(semantics.Slice); isSlice {
+		exp.Left.EmitCode(e)
+		fmt.Fprintf(e, "mov rax, rdx\n")
+		return
+	}
+
+	fmt.Fprintf(e, "mov rax, %v\n", exp.Value)
+}
+
+// Only module qualified variables have an address.
+func (exp MemberExpression) EmitAddressCode(e *codegen.Emitter) {
+	exp.Qualified.EmitAddressCode(e)
+}
+
+func (exp MemberExpression) IsAddressable() bool {
+	return exp.Qualified != nil
+}
+
+func (exp MemberExpression) Print(indent int) string {
+	result := fmt.Sprintf("MemberExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type.TypeID())
+	result += fmt.Sprintf("%v\n", exp.Left.Print(indent + 1))
+	result += fmt.Sprintf("%vMember: %v", indentStr(indent + 1), exp.Member.Value)
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// Constructs a value of a tagged union.
+// Example:
+//  Shape s = Shape.Rect(2, 3);
+//  s = Shape.Circle(1);
+// The value is built in place, so constructors can only be used as the right side of
+// declarations and assignments or as fields of other constructors.
+// EmitCode expects the destination address in rax and leaves it there.
+type UnionConstructorExpression struct {
+	Type      semantics.Type
+	// The union and variant names.
+	Callee    *MemberExpression
+	Args      []Expression
+	// For error handling.
+	OpenParen lexer.Token
+	// The discriminant of the constructed variant. Set during semantics.
+	Tag       int
+	// Set by the enclosing expression or statement that provides the destination.
+	InPlace   bool
+}
+
+func (exp UnionConstructorExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *UnionConstructorExpression) Semantics(s *semantics.SemanticChecker) error {
+	var union semantics.Union
+	isUnion := false
+
+	if t, isType := lookupTypeName(s, exp.Callee.Left); isType {
+		union, isUnion = semantics.Underlying(t).(semantics.Union)
+	}
+
+	if !isUnion {
+		return s.AddError(diagnostics.Misplaced, "Only union variants can be constructed with '()'", exp.OpenParen)
+	}
+
+	variant, tag, exists := union.Variant(exp.Callee.Member.Value)
+	if !exists {
+		return s.AddError(
+			diagnostics.UnknownMember,
+			fmt.Sprintf("Union %v has no variant '%v'", union.Name, exp.Callee.Member.Value),
+			exp.Callee.Member,
+		)
+	}
+	exp.Type = union
+	exp.Tag = tag
+
+	if len(exp.Args) != len(variant.Fields) {
+		return s.AddError(
+			diagnostics.ArgumentCount,
+			fmt.Sprintf("Variant '%v' expects %v fields but received %v", variant.Ident, len(variant.Fields), len(exp.Args)),
+			exp.OpenParen,
+		)
+	}
+
+	for i, arg := range exp.Args {
+		if inPlace, isInPlace := arg.(InPlaceExpression); isInPlace {
+			inPlace.SetInPlace()
+		}
+
+		if err := arg.Semantics(s); err != nil {
+			return err
+		}
+		arg = wrapOptional(arg, variant.Fields[i])
+		exp.Args[i] = arg
+
+		if !variant.Fields[i].Equals(arg.ExprType()) {
+			return s.AddError(
+				diagnostics.TypeMismatch,
+				fmt.Sprintf(
+					"Field %v of variant '%v' has type %v but received %v",
+					i,
+					variant.Ident,
+					variant.Fields[i].TypeID(),
+					arg.ExprType().TypeID(),
+				),
+				exp.OpenParen,
+			)
+		}
+	}
+
+	if !exp.InPlace {
+		return s.AddError(
+			diagnostics.Misplaced,
+			"Union values can only be constructed in declarations, assignments and other union constructors",
+			exp.OpenParen,
+		)
+	}
+
+	return nil
+}
+
+func (exp *UnionConstructorExpression) SetInPlace() {
+	exp.InPlace = true
+}
+
+// Expects the address of the destination in rax.
+func (exp UnionConstructorExpression) EmitCode(e *codegen.Emitter) {
+	union := exp.Type.(semantics.Union)
+	variant := union.Variants[exp.Tag]
+
+	fmt.Fprintf(e, "; UnionConstructorExpression: %v.%v\n", union.Name, variant.Ident)
+	fmt.Fprintf(e, "push rax\n")
+
+	for i, arg := range exp.Args {
+		emitInPlaceStore(e, arg, variant.Fields[i], variant.Offsets[i])
+	}
+
+	fmt.Fprintf(e, "pop rax\n")
+	fmt.Fprintf(e, "mov %v [rax], %v\n", union.TagType().ASMSize(), exp.Tag)
+}
+
+func (_ UnionConstructorExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp UnionConstructorExpression) Print(indent int) string {
+	result := fmt.Sprintf("UnionConstructorExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type.TypeID())
+	result += fmt.Sprintf("%vVariant: %v", indentStr(indent + 1), exp.Callee.Member.Value)
+	for _, arg := range exp.Args {
+		result += fmt.Sprintf("\n%v", arg.Print(indent + 1))
+	}
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// A value implicitly wrapped into an optional, either none or a value of the optional's base type.
+// Created during semantics where an optional type is expected.
+type OptionalExpression struct {
+	Type    semantics.Optional
+	// The wrapped value or the none literal.
+	Value   Expression
+	InPlace bool
+}
+
+func (exp OptionalExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *OptionalExpression) Semantics(s *semantics.SemanticChecker) error {
+	return nil // The wrapped value is checked before it is wrapped
+}
+
+func (exp *OptionalExpression) SetInPlace() {
+	exp.InPlace = true
+}
+
+// Expects the address of the destination in rax.
+func (exp OptionalExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; OptionalExpression: type = %v\n", exp.Type.TypeID())
+
+	if exp.Value.ExprType().TypeID() == semantics.NONE_LIT {
+		fmt.Fprintf(e, "mov BYTE [rax], 0\n")
+		return
+	}
+
+	fmt.Fprintf(e, "push rax\n")
+	emitInPlaceStore(e, exp.Value, exp.Type.Base, exp.Type.ValueOffset())
+	fmt.Fprintf(e, "pop rax\n")
+	fmt.Fprintf(e, "mov BYTE [rax], 1\n")
+}
+
+func (_ OptionalExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp OptionalExpression) Print(indent int) string {
+	result := fmt.Sprintf("OptionalExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type.TypeID())
+	result += fmt.Sprintf("%v", exp.Value.Print(indent + 1))
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// An explicit type conversion.
+// Example:
+//  uint8 raw = c as uint8;
+//  Color c = 10 as Color;
+type CastExpression struct {
+	// The target type.
+	Type  semantics.Type
+	Left  Expression
+	// The as token. Used for error handling.
+	As    lexer.Token
+}
+
+func (exp CastExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *CastExpression) Semantics(s *semantics.SemanticChecker) error {
+	if err := exp.Left.Semantics(s); err != nil {
+		return err
+	}
+
+	target, err := s.ResolveType(exp.Type)
+	if err != nil {
+		return err
+	}
+	exp.Type = target
+
+	if !semantics.CanCast(exp.Left.ExprType(), exp.Type) {
+		return s.AddError(
+			diagnostics.InvalidCast,
+			fmt.Sprintf("Cannot cast type %v to %v", exp.Left.ExprType().TypeID(), exp.Type.TypeID()),
+			exp.As,
+		)
+	}
+
+	return nil
+}
+
+func (exp CastExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; CastExpression: %v -> %v\n", exp.Left.ExprType().TypeID(), exp.Type.TypeID())
+	exp.Left.EmitCode(e)
+	e.ZeroExtend(min(exp.Left.ExprType().Size(), exp.Type.Size()))
+}
+
+func (_ CastExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp CastExpression) Print(indent int) string {
+	result := fmt.Sprintf("CastExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type.TypeID())
+	result += exp.Left.Print(indent + 1)
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// A compile-time query of a type's size or alignment in bytes.
+// The operand is either a type or an expression, which is only checked and never evaluated.
+// Example:
+//  uint64 n = sizeof(uint32[4]); // 16
+//  uint64 a = alignof(uint16); // 2
+//  assert sizeof(x) == 8;
+type TypeInfoExpression struct {
+	Type    semantics.Type
+	// The sizeof or alignof token.
+	Op      lexer.Token
+	// The queried type. Taken from Expr during semantics when an expression was given.
+	Operand semantics.Type
+	Expr    utils.Optional[Expression]
+	// The computed constant.
+	Value   int
+}
+
+func (exp TypeInfoExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *TypeInfoExpression) Semantics(s *semantics.SemanticChecker) error {
+	if exp.Expr.HasVal() {
+		expr := exp.Expr.Value()
+
+		// A lone or module qualified identifier may name a user defined type instead of a variable.
+		if t, isType := lookupTypeName(s, expr); isType {
+			exp.Expr = utils.Optional[Expression]{}
+			exp.Operand = t
+			return exp.Semantics(s)
+		}
+
+		if err := expr.Semantics(s); err != nil {
+			return err
+		}
+		exp.Operand = expr.ExprType()
+	} else {
+		operand, err := s.ResolveType(exp.Operand)
+		if err != nil {
+			return err
+		}
+		exp.Operand = operand
+	}
+
+	if exp.Operand.TypeID() == semantics.UNDEFINED {
+		return s.AddError(
+			diagnostics.InvalidDeclaration,
+			fmt.Sprintf("Cannot use %v on an undefined type", exp.Op.Value),
+			exp.Op,
+		)
+	}
+
+	if exp.Op.Type == lexer.ALIGNOF {
+		exp.Value = exp.Operand.Align()
+	} else {
+		exp.Value = exp.Operand.Size()
+	}
+	exp.Type = semantics.Uint64{}
+
+	return nil
+}
+
+func (exp TypeInfoExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; TypeInfoExpression: %v(%v) = %v\n", exp.Op.Value, exp.Operand.TypeID(), exp.Value)
+	fmt.Fprintf(e, "mov rax, %v\n", exp.Value)
+}
+
+func (_ TypeInfoExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp TypeInfoExpression) Print(indent int) string {
+	result := fmt.Sprintf("TypeInfoExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vOp: %v\n", indentStr(indent + 1), exp.Op)
+	result += fmt.Sprintf("%vOperand: %v\n", indentStr(indent + 1), exp.Operand.TypeID())
+	result += fmt.Sprintf("%vValue: %v", indentStr(indent + 1), exp.Value)
 	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
 }
 
-// TODO: PostfixExpression
-// A postfix expression holds a unary operator and a left value.
-type PostfixExpression struct {
-	Type        semantics.Type
-	Left        Expression
-	Op          lexer.Token
-	Addressable bool
+// Allocates zeroed heap memory for a value or a run of count values.
+// Example:
+//  uint32* one = new uint32;
+//  uint8* buf = new uint8[n];
+// Evaluates to a pointer to the first value.
+type NewExpression struct {
+	Type      semantics.Type
+	// The new token. Used for error handling.
+	NewToken  lexer.Token
+	// The type of the allocated values.
+	ValueType semantics.Type
+	Count     utils.Optional[Expression]
+}
+
+func (exp NewExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *NewExpression) Semantics(s *semantics.SemanticChecker) error {
+	valueType, err := s.ResolveType(exp.ValueType)
+	if err != nil {
+		return err
+	}
+
+	if valueType.TypeID() == semantics.UNDEFINED {
+		return s.AddError(diagnostics.InvalidDeclaration, "Cannot allocate a value of an undefined type", exp.NewToken)
+	}
+	exp.ValueType = valueType
+
+	if exp.Count.HasVal() {
+		count := exp.Count.Value()
+		if err := count.Semantics(s); err != nil {
+			return err
+		}
+
+		if !semantics.IsNumber(count.ExprType()) {
+			return s.AddError(
+				diagnostics.InvalidOperand,
+				fmt.Sprintf("Element count must be an unsigned integer but received %v", count.ExprType().TypeID()),
+				exp.NewToken,
+			)
+		}
+	}
+	exp.Type = semantics.Ptr{ ValueType: valueType }
+
+	return nil
+}
+
+func (exp NewExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; NewExpression: type = %v\n", exp.ValueType.TypeID())
+	e.UseAllocator()
+
+	if exp.Count.HasVal() {
+		exp.Count.Value().EmitCode(e)
+		// The unsigned product sets the carry flag when it does not fit in 64 bits.
+		fmt.Fprintf(e, "mov rcx, %v\n", exp.ValueType.Size())
+		e.WriteString("mul rcx\n")
+		e.OutOfMemoryIf("jc")
+		e.WriteString("mov rdi, rax\n")
+	} else {
+		fmt.Fprintf(e, "mov rdi, %v\n", exp.ValueType.Size())
+	}
+	fmt.Fprintf(e, "call __clovis_alloc\n")
+}
+
+func (_ NewExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp NewExpression) Print(indent int) string {
+	result := fmt.Sprintf("NewExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vValueType: %v", indentStr(indent + 1), exp.ValueType.TypeID())
+	if exp.Count.HasVal() {
+		result += fmt.Sprintf("\n%v", exp.Count.Value().Print(indent + 1))
+	}
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// The builtin functions and the number of arguments they take.
+// Each is lowered to Linux system calls and returns an IoResult.
+var builtinArities = map[string]int{
+	"open": 2,
+	"read": 2,
+	"write": 2,
+	"close": 1,
+	"read_uint": 0,
+}
+
+// A call of a builtin or extern function.
+// Example:
+//  IoResult r = write(1, msg);
+//  uint64 n = strlen(path);
+// The IoResult of builtins is built in place like a union constructor.
+// Extern calls store their result to the destination when one is provided.
+type CallExpression struct {
+	Type      semantics.Type
+	Callee    *IdentExpression
+	Args      []Expression
+	// For error handling.
+	OpenParen lexer.Token
+	// Set by the enclosing expression or statement that provides the destination.
+	InPlace   bool
+	// The called extern function. Nil for builtins.
+	Extern    *semantics.Function
+}
+
+func (exp CallExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *CallExpression) Semantics(s *semantics.SemanticChecker) error {
+	name := exp.Callee.Ident.Value
+	if fn, isFunction := s.LookupFunction(name); isFunction {
+		exp.Extern = &fn
+		return exp.externSemantics(s)
+	}
+
+	arity, isBuiltin := builtinArities[name]
+	if !isBuiltin {
+		if s.IsFailed(name) {
+			return semantics.ErrFailedDeclaration
+		}
+
+		return s.AddError(diagnostics.WrongSymbolKind, fmt.Sprintf("'%v' is not a function", name), exp.Callee.Ident)
+	}
+
+	if len(exp.Args) != arity {
+		return s.AddError(
+			diagnostics.ArgumentCount,
+			fmt.Sprintf("%v expects %v arguments but received %v", name, arity, len(exp.Args)),
+			exp.OpenParen,
+		)
+	}
+
+	for _, arg := range exp.Args {
+		if err := arg.Semantics(s); err != nil {
+			return err
+		}
+	}
+
+	switch name {
+	case "open":
+		if err := exp.checkPath(s, exp.Args[0]); err != nil {
+			return err
+		}
+
+		if err := exp.checkNumber(s, exp.Args[1], "flags"); err != nil {
+			return err
+		}
+	case "read", "write":
+		if err := exp.checkNumber(s, exp.Args[0], "file descriptor"); err != nil {
+			return err
+		}
+
+		if !isByteBuffer(exp.Args[1].ExprType()) {
+			return s.AddError(
+				diagnostics.InvalidOperand,
+				fmt.Sprintf("%v expects a uint8 array or slice as buffer but received %v", name, exp.Args[1].ExprType().TypeID()),
+				exp.OpenParen,
+			)
+		}
+	case "close":
+		if err := exp.checkNumber(s, exp.Args[0], "file descriptor"); err != nil {
+			return err
+		}
+	}
+	exp.Type = semantics.IoResult
+
+	if !exp.InPlace {
+		return s.AddError(
+			diagnostics.Misplaced,
+			fmt.Sprintf("The IoResult of %v can only be used in declarations, assignments and expression statements", name),
+			exp.OpenParen,
+		)
+	}
+
+	return nil
+}
+
+func (exp *CallExpression) externSemantics(s *semantics.SemanticChecker) error {
+	fn := exp.Extern
+	if s.InSpawn() {
+		// Threads are started without the C library, so they have no thread local storage of their own.
+		return s.AddError(
+			diagnostics.Misplaced,
+			fmt.Sprintf("Extern function %v cannot be called inside a spawn block", fn.Name),
+			exp.OpenParen,
+		)
+	}
+
+	if len(exp.Args) < len(fn.Params) || (!fn.Variadic && len(exp.Args) != len(fn.Params)) {
+		return s.AddError(
+			diagnostics.ArgumentCount,
+			fmt.Sprintf("%v expects %v arguments but received %v", fn.Name, len(fn.Params), len(exp.Args)),
+			exp.OpenParen,
+		)
+	}
+
+	for i, arg := range exp.Args {
+		if err := arg.Semantics(s); err != nil {
+			return err
+		}
+
+		if i >= len(fn.Params) {
+			if !isScalar(arg.ExprType()) && arg.ExprType().TypeID() != semantics.UINT_LIT {
+				return s.AddError(
+					diagnostics.RegisterSize,
+					fmt.Sprintf("Argument %v of %v has type %v which does not fit in a register", i, fn.Name, arg.ExprType().TypeID()),
+					exp.OpenParen,
+				)
+			}
+			continue
+		}
+
+		if !fn.Params[i].Equals(arg.ExprType()) {
+			return s.AddError(
+				diagnostics.TypeMismatch,
+				fmt.Sprintf(
+					"Argument %v of %v expects type %v but received %v",
+					i,
+					fn.Name,
+					fn.Params[i].TypeID(),
+					arg.ExprType().TypeID(),
+				),
+				exp.OpenParen,
+			)
+		}
+	}
+	exp.Type = fn.Return
+
+	return nil
+}
+
+func (exp CallExpression) checkNumber(s *semantics.SemanticChecker, arg Expression, what string) error {
+	if semantics.IsNumber(arg.ExprType()) {
+		return nil
+	}
+
+	return s.AddError(
+		diagnostics.InvalidOperand,
+		fmt.Sprintf(
+			"%v expects an unsigned integer as %v but received %v",
+			exp.Callee.Ident.Value,
+			what,
+			arg.ExprType().TypeID(),
+		),
+		exp.OpenParen,
+	)
+}
+
+// Paths are either byte buffers, which are copied and null terminated,
+// or pointers to null terminated strings such as the ones in args.
+func (exp CallExpression) checkPath(s *semantics.SemanticChecker, arg Expression) error {
+	if isByteBuffer(arg.ExprType()) || isBytePointer(arg.ExprType()) {
+		return nil
+	}
+
+	return s.AddError(
+		diagnostics.InvalidOperand,
+		fmt.Sprintf("open expects a uint8 array, slice or pointer as path but received %v", arg.ExprType().TypeID()),
+		exp.OpenParen,
+	)
+}
+
+// Returns whether the type is an array or slice of bytes.
+func isByteBuffer(t semantics.Type) bool {
+	switch t := semantics.Underlying(t).(type) {
+	case semantics.Array:
+		return t.Base.TypeID() == semantics.UINT8
+	case semantics.Slice:
+		return t.Base.TypeID() == semantics.UINT8
+	}
+
+	return false
+}
+
+func isBytePointer(t semantics.Type) bool {
+	ptr, isPtr := semantics.Underlying(t).(semantics.Ptr)
+	return isPtr && ptr.ValueType.TypeID() == semantics.UINT8
+}
+
+func (exp *CallExpression) SetInPlace() {
+	exp.InPlace = true
+}
+
+// Evaluates a byte buffer into its pointer in rax and its length in rdx.
+func emitBuffer(e *codegen.Emitter, buf Expression) {
+	buf.EmitCode(e)
+	if array, isArray := semantics.Underlying(buf.ExprType()).(semantics.Array); isArray {
+		fmt.Fprintf(e, "mov rdx, %v\n", array.Length)
+	}
+}
+
+// Expects the address of the destination in rax unless an extern function is called outside of
+// declarations, assignments and expression statements.
+func (exp CallExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; CallExpression: %v\n", exp.Callee.Ident.Value)
+	if exp.Extern != nil {
+		exp.emitExternCall(e)
+		return
+	}
+
+	fmt.Fprintf(e, "push rax\n")
+
+	switch exp.Callee.Ident.Value {
+	case "open":
+		exp.emitOpen(e)
+	case "read", "write":
+		exp.Args[0].EmitCode(e)
+		fmt.Fprintf(e, "push rax\n")
+		emitBuffer(e, exp.Args[1])
+		fmt.Fprintf(e, "mov rsi, rax\n")
+		fmt.Fprintf(e, "pop rdi\n")
+		if exp.Callee.Ident.Value == "read" {
+			fmt.Fprintf(e, "mov rax, 0\n")
+		} else {
+			fmt.Fprintf(e, "mov rax, 1\n")
+		}
+		fmt.Fprintf(e, "syscall\n")
+	case "close":
+		exp.Args[0].EmitCode(e)
+		fmt.Fprintf(e, "mov rdi, rax\n")
+		fmt.Fprintf(e, "mov rax, 3\n")
+		fmt.Fprintf(e, "syscall\n")
+	case "read_uint":
+		e.UseReadUint()
+		fmt.Fprintf(e, "call __clovis_read_uint\n")
+	}
+
+	if exp.Callee.Ident.Value != "read_uint" {
+		// Failed syscalls return the negated error number.
+		okLabel := e.NextLabel()
+		fmt.Fprintf(e, "xor edx, edx\n")
+		fmt.Fprintf(e, "cmp rax, -4096\n")
+		fmt.Fprintf(e, "jbe %v\n", okLabel)
+		fmt.Fprintf(e, "neg rax\n")
+		fmt.Fprintf(e, "mov rdx, rax\n")
+		fmt.Fprintf(e, "%v:\n", okLabel)
+	}
+	exp.emitResult(e)
+}
+
+// Calls the extern function following the System V ABI.
+// The stack is aligned to 16 bytes and the old stack pointer is saved on the aligned stack.
+// Arguments are evaluated from right to left so the ones passed on the stack are left in order.
+func (exp CallExpression) emitExternCall(e *codegen.Emitter) {
+	if exp.InPlace {
+		fmt.Fprintf(e, "push rax\n")
+	}
+
+	stackArgs := max(len(exp.Args) - len(codegen.CallRegisters), 0)
+	stackSize := semantics.Align16(stackArgs * 8)
+
+	fmt.Fprintf(e, "mov rbx, rsp\n")
+	fmt.Fprintf(e, "and rsp, -16\n")
+	fmt.Fprintf(e, "push rbx\n")
+	fmt.Fprintf(e, "push rbx\n")
+	if padding := stackSize - stackArgs * 8; padding != 0 {
+		fmt.Fprintf(e, "sub rsp, %v\n", padding)
+	}
+
+	for i := len(exp.Args) - 1; i >= 0; i-- {
+		exp.Args[i].EmitCode(e)
+		fmt.Fprintf(e, "push rax\n")
+	}
+
+	for i := 0; i < len(exp.Args) && i < len(codegen.CallRegisters); i++ {
+		fmt.Fprintf(e, "pop %v\n", codegen.CallRegisters[i])
+	}
+
+	if exp.Extern.Variadic {
+		fmt.Fprintf(e, "xor eax, eax\n") // No vector registers are used
+	}
+	fmt.Fprintf(e, "call $%v wrt ..plt\n", exp.Extern.Name)
+
+	if stackSize != 0 {
+		fmt.Fprintf(e, "add rsp, %v\n", stackSize)
+	}
+	fmt.Fprintf(e, "mov rsp, QWORD [rsp]\n")
+
+	hasResult := exp.Type.TypeID() != semantics.UNDEFINED
+	if hasResult {
+		// Only the lowest bytes of narrow results are defined.
+		e.ZeroExtend(exp.Type.Size())
+	}
+
+	if exp.InPlace {
+		fmt.Fprintf(e, "pop rbx\n")
+		if hasResult {
+			emitStore(e, exp.Type, "rbx")
+		}
+		fmt.Fprintf(e, "mov rax, rbx\n")
+	}
+}
+
+// Opens the path with the given flags. New files are created with mode 0644.
+func (exp CallExpression) emitOpen(e *codegen.Emitter) {
+	path := exp.Args[0]
+
+	if isBytePointer(path.ExprType()) {
+		path.EmitCode(e)
+		fmt.Fprintf(e, "push rax\n")
+		exp.Args[1].EmitCode(e)
+		fmt.Fprintf(e, "mov rsi, rax\n")
+		fmt.Fprintf(e, "pop rdi\n")
+		fmt.Fprintf(e, "mov rdx, 420\n")
+		fmt.Fprintf(e, "mov rax, 2\n")
+		fmt.Fprintf(e, "syscall\n")
+		return
+	}
+
+	emitBuffer(e, path)
+	fmt.Fprintf(e, "push rax\n")
+	fmt.Fprintf(e, "push rdx\n")
+	exp.Args[1].EmitCode(e)
+	fmt.Fprintf(e, "mov r8, rax\n") // r8 holds the flags
+	fmt.Fprintf(e, "pop rcx\n")
+	fmt.Fprintf(e, "pop rsi\n")
+
+	// Copy the path into a null terminated temporary on the stack.
+	fmt.Fprintf(e, "lea rbx, [rcx + 16]\n")
+	fmt.Fprintf(e, "and rbx, -16\n") // rbx holds the size of the temporary
+	fmt.Fprintf(e, "sub rsp, rbx\n")
+	fmt.Fprintf(e, "mov rdi, rsp\n")
+	fmt.Fprintf(e, "rep movsb\n")
+	fmt.Fprintf(e, "mov BYTE [rdi], 0\n")
+
+	fmt.Fprintf(e, "mov rdi, rsp\n")
+	fmt.Fprintf(e, "mov rsi, r8\n")
+	fmt.Fprintf(e, "mov rdx, 420\n")
+	fmt.Fprintf(e, "mov rax, 2\n")
+	fmt.Fprintf(e, "syscall\n")
+	fmt.Fprintf(e, "add rsp, rbx\n")
+}
+
+// Stores the value in rax or, when rdx is not zero, the error number in rdx as an IoResult
+// to the destination on top of the stack.
+func (exp CallExpression) emitResult(e *codegen.Emitter) {
+	ok, okTag, _ := semantics.IoResult.Variant("Ok")
+	failed, errTag, _ := semantics.IoResult.Variant("Err")
+	tagSize := semantics.IoResult.TagType().ASMSize()
+
+	fmt.Fprintf(e, "pop rbx\n")
+	errLabel := e.NextLabel()
+	endLabel := e.NextLabel()
+	fmt.Fprintf(e, "test rdx, rdx\n")
+	fmt.Fprintf(e, "jnz %v\n", errLabel)
+	fmt.Fprintf(e, "mov %v [rbx], %v\n", tagSize, okTag)
+	fmt.Fprintf(e, "mov QWORD [rbx + %v], rax\n", ok.Offsets[0])
+	fmt.Fprintf(e, "jmp %v\n", endLabel)
+	fmt.Fprintf(e, "%v:\n", errLabel)
+	fmt.Fprintf(e, "mov %v [rbx], %v\n", tagSize, errTag)
+	fmt.Fprintf(e, "mov QWORD [rbx + %v], rdx\n", failed.Offsets[0])
+	fmt.Fprintf(e, "%v:\n", endLabel)
+	fmt.Fprintf(e, "mov rax, rbx\n")
+}
+
+func (_ CallExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp CallExpression) Print(indent int) string {
+	result := fmt.Sprintf("CallExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vCallee: %v", indentStr(indent + 1), exp.Callee.Ident.Value)
+	for _, arg := range exp.Args {
+		result += fmt.Sprintf("\n%v", arg.Print(indent + 1))
+	}
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// Runs a block on a new thread and evaluates to the thread's handle.
+// Example:
+//  shared uint64 done = 0;
+//  Thread t = spawn { atomic_store(&done, 1); };
+//  join(t);
+// The thread's stack starts with a copy of the enclosing stack frame, so the block reads the values
+// variables had when the thread was spawned and its assignments to them stay local to the thread.
+// Shared variables live in static storage instead and are seen by every thread.
+type SpawnExpression struct {
+	// The spawn token. Used for error handling.
+	SpawnToken lexer.Token
+	Block      Statement
+	// The size of the enclosing stack frame copied to the thread's stack.
+	FrameSize  int
+}
+
+func (exp SpawnExpression) ExprType() semantics.Type {
+	return semantics.Thread
+}
+
+func (exp *SpawnExpression) Semantics(s *semantics.SemanticChecker) error {
+	exp.FrameSize = s.EnterSpawn()
+	defer s.ExitSpawn()
+
+	return exp.Block.Semantics(s)
+}
+
+// The block is emitted inline and skipped by the spawning thread.
+// The new thread enters it with rbp pointing at its copy of the frame and exits when it ends.
+func (exp SpawnExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; SpawnExpression frame size = %v\n", exp.FrameSize)
+	e.UseThreads()
+	threadLabel := e.NextLabel()
+	endLabel := e.NextLabel()
+	fmt.Fprintf(e, "lea rsi, [rel %v]\n", threadLabel)
+	fmt.Fprintf(e, "mov rdi, %v\n", exp.FrameSize)
+	fmt.Fprintf(e, "call __clovis_spawn\n")
+	fmt.Fprintf(e, "jmp %v\n", endLabel)
+	fmt.Fprintf(e, "%v:\n", threadLabel)
+	exp.Block.EmitCode(e)
+	fmt.Fprintf(e, "mov rax, 60\n") // exit ends only the calling thread
+	fmt.Fprintf(e, "xor edi, edi\n")
+	fmt.Fprintf(e, "syscall\n")
+	fmt.Fprintf(e, "%v:\n", endLabel)
+}
+
+func (_ SpawnExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp SpawnExpression) Print(indent int) string {
+	result := fmt.Sprintf("SpawnExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vFrameSize: %v", indentStr(indent + 1), exp.FrameSize)
+	result += exp.Block.Print(indent + 1)
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
 }
 
-func (exp PostfixExpression) ExprType() semantics.Type {
+// The atomic intrinsics and the number of arguments they take.
+var atomicArities = map[lexer.TokenType]int{
+	lexer.ATOMIC_LOAD: 1,
+	lexer.ATOMIC_STORE: 2,
+	lexer.ATOMIC_ADD: 2,
+	lexer.ATOMIC_CAS: 3,
+}
+
+// An atomic operation on the number a pointer points to.
+// Example:
+//  atomic_store(&flag, 1);
+//  uint64 before = atomic_add(&counter, 1);
+//  bool swapped = atomic_cas(&state, 0, 1); // Replaces 0 with 1
+//  uint32 now = atomic_load(&state);
+// atomic_add returns the previous value and atomic_cas whether the value was replaced.
+// Loads are plain moves, which are atomic for aligned values on x86_64,
+// the other operations use lock prefixed instructions.
+type AtomicExpression struct {
+	Type semantics.Type
+	// The intrinsic's token. Used for error handling.
+	Op   lexer.Token
+	// The pointer followed by the operands.
+	Args []Expression
+}
+
+func (exp AtomicExpression) ExprType() semantics.Type {
 	return exp.Type
 }
 
-func (exp *PostfixExpression) Semantics(s *semantics.SemanticChecker) error {
+func (exp *AtomicExpression) Semantics(s *semantics.SemanticChecker) error {
+	if arity := atomicArities[exp.Op.Type]; len(exp.Args) != arity {
+		return s.AddError(
+			diagnostics.ArgumentCount,
+			fmt.Sprintf("%v expects %v arguments but received %v", exp.Op.Value, arity, len(exp.Args)),
+			exp.Op,
+		)
+	}
+
+	for _, arg := range exp.Args {
+		if err := arg.Semantics(s); err != nil {
+			return err
+		}
+	}
+
+	ptr, isPtr := semantics.Underlying(exp.Args[0].ExprType()).(semantics.Ptr)
+	if !isPtr || !semantics.IsNumber(ptr.ValueType) {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("%v expects a pointer to an unsigned integer but received %v", exp.Op.Value, exp.Args[0].ExprType().TypeID()),
+			exp.Op,
+		)
+	}
+
+	for i, arg := range exp.Args[1:] {
+		if canAssign, _ := ptr.ValueType.CanUseOperator("=", arg.ExprType()); !canAssign {
+			return s.AddError(
+				diagnostics.TypeMismatch,
+				fmt.Sprintf(
+					"Argument %v of %v expects type %v but received %v",
+					i + 1,
+					exp.Op.Value,
+					ptr.ValueType.TypeID(),
+					arg.ExprType().TypeID(),
+				),
+				exp.Op,
+			)
+		}
+	}
+
+	switch exp.Op.Type {
+	case lexer.ATOMIC_STORE:
+		exp.Type = semantics.Undefined{}
+	case lexer.ATOMIC_CAS:
+		exp.Type = semantics.Bool{}
+	default:
+		exp.Type = ptr.ValueType
+	}
+
 	return nil
 }
 
-func (exp PostfixExpression) EmitCode(e *codegen.Emitter) {
-	
+// Operands are evaluated from right to left and the pointer is left in rax.
+func (exp AtomicExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; AtomicExpression: %v\n", exp.Op.Value)
+	valueType := semantics.Underlying(exp.Args[0].ExprType()).(semantics.Ptr).ValueType
+	size := valueType.Size()
+	operand := codegen.SizedRegister("rbx", size)
+
+	for i := len(exp.Args) - 1; i > 0; i-- {
+		exp.Args[i].EmitCode(e)
+		fmt.Fprintf(e, "push rax\n")
+	}
+	exp.Args[0].EmitCode(e)
+
+	switch exp.Op.Type {
+	case lexer.ATOMIC_LOAD:
+		fmt.Fprintf(e, "mov rbx, rax\n")
+		e.LoadRax(size, "[rbx]")
+	case lexer.ATOMIC_STORE:
+		fmt.Fprintf(e, "pop rbx\n")
+		fmt.Fprintf(e, "xchg %v [rax], %v\n", valueType.ASMSize(), operand) // xchg with memory is always locked
+	case lexer.ATOMIC_ADD:
+		fmt.Fprintf(e, "pop rbx\n")
+		fmt.Fprintf(e, "lock xadd %v [rax], %v\n", valueType.ASMSize(), operand)
+		fmt.Fprintf(e, "mov rax, rbx\n")
+		e.ZeroExtend(size)
+	case lexer.ATOMIC_CAS:
+		fmt.Fprintf(e, "mov rcx, rax\n")
+		fmt.Fprintf(e, "pop rax\n") // The expected value
+		fmt.Fprintf(e, "pop rbx\n") // The replacement
+		fmt.Fprintf(e, "lock cmpxchg %v [rcx], %v\n", valueType.ASMSize(), operand)
+		fmt.Fprintf(e, "sete al\n")
+		fmt.Fprintf(e, "movzx eax, al\n")
+	}
 }
 
-func (exp PostfixExpression) IsAddressable() bool {
-	return exp.Addressable
+func (_ AtomicExpression) IsAddressable() bool {
+	return false
 }
 
-func (exp PostfixExpression) Print(indent int) string {
-	return ""
+func (exp AtomicExpression) Print(indent int) string {
+	result := fmt.Sprintf("AtomicExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vOp: %v", indentStr(indent + 1), exp.Op.Value)
+	for _, arg := range exp.Args {
+		result += fmt.Sprintf("\n%v", arg.Print(indent + 1))
+	}
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
 }
 
-// A dereference expression.
+// A direct Linux system call.
 // Example:
-//	uint32 y = *x; // *x returns the value (rvalue) stored at the location where x is pointing to
-//  *x = 69; // Moves value 69 to the location x is pointing to (here *x returns an lvalue)
-type DerefExpression struct {
-	Type   semantics.Type
-	Op     lexer.Token
-	Right  Expression
+//  uint64 written = syscall(1, 1, buf, buf.len);
+// The syscall number and up to six arguments are passed in registers and the result is returned as uint64.
+type SyscallExpression struct {
+	Type         semantics.Type
+	// The syscall token. Used for error handling.
+	SyscallToken lexer.Token
+	// The syscall number followed by the arguments.
+	Args         []Expression
 }
 
-func (exp DerefExpression) ExprType() semantics.Type {
+func (exp SyscallExpression) ExprType() semantics.Type {
 	return exp.Type
 }
 
-func (exp *DerefExpression) Semantics(s *semantics.SemanticChecker) error {
-	if err := exp.Right.Semantics(s); err != nil {
-		return err
+func (exp *SyscallExpression) Semantics(s *semantics.SemanticChecker) error {
+	if len(exp.Args) == 0 || len(exp.Args) > len(codegen.SyscallRegisters) {
+		return s.AddError(
+			diagnostics.ArgumentCount,
+			fmt.Sprintf(
+				"syscall expects a syscall number and up to %v arguments but received %v values",
+				len(codegen.SyscallRegisters) - 1,
+				len(exp.Args),
+			),
+			exp.SyscallToken,
+		)
 	}
-	
-	ptr, isPtr := exp.Right.ExprType().(semantics.Ptr)
-	if !isPtr {
+
+	for i, arg := range exp.Args {
+		if err := arg.Semantics(s); err != nil {
+			return err
+		}
+
+		if !isRegisterArgument(arg.ExprType()) {
+			return s.AddError(
+				diagnostics.RegisterSize,
+				fmt.Sprintf("Argument %v of syscall has type %v which does not fit in a register", i, arg.ExprType().TypeID()),
+				exp.SyscallToken,
+			)
+		}
+	}
+
+	if !semantics.IsNumber(exp.Args[0].ExprType()) {
 		return s.AddError(
-			 fmt.Sprintf(
-				 "'*' dereference operator expected a PTR not %v", 
-				 exp.Right.ExprType().TypeID(),
-			 ),
-			 exp.Op,
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("The syscall number must be a number but received %v", exp.Args[0].ExprType().TypeID()),
+			exp.SyscallToken,
 		)
 	}
-	exp.Type = ptr.ValueType
-	
+	exp.Type = semantics.Uint64{}
+
 	return nil
 }
 
-func (exp DerefExpression) EmitCode(e *codegen.Emitter) {
-	fmt.Fprintf(e, "; DerefExpression rvalue type = %v\n", exp.Type.TypeID())
-	exp.Right.EmitCode(e)
-	fmt.Fprintf(e, "mov %v, %v [rax]\n", exp.Type.Register(), exp.Type.ASMSize())
+// Returns whether a value of the type can be passed in a single register.
+// Arrays are passed as the address of their first element.
+func isRegisterArgument(t semantics.Type) bool {
+	switch semantics.Underlying(t).(type) {
+	case semantics.Slice, semantics.Union, semantics.Optional, semantics.NoneLiteral:
+		return false
+	}
+
+	return true
 }
 
-func (exp DerefExpression) EmitAddressCode(e *codegen.Emitter) {
-	fmt.Fprintf(e, "; DerefExpression lvalue type = %v\n", exp.Type.TypeID())
-	exp.Right.EmitCode(e)
+func (exp SyscallExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; SyscallExpression: args = %v\n", len(exp.Args) - 1)
+	for _, arg := range exp.Args {
+		arg.EmitCode(e)
+		fmt.Fprintf(e, "push rax\n")
+	}
+
+	for i := len(exp.Args) - 1; i >= 0; i-- {
+		fmt.Fprintf(e, "pop %v\n", codegen.SyscallRegisters[i])
+	}
+	fmt.Fprintf(e, "syscall\n")
 }
 
-func (exp DerefExpression) IsAddressable() bool {
-	return true
+func (_ SyscallExpression) IsAddressable() bool {
+	return false
 }
 
-func (exp DerefExpression) Print(indent int) string {
-	return ""
+func (exp SyscallExpression) Print(indent int) string {
+	result := fmt.Sprintf("SyscallExpression\n%v{", indentStr(indent))
+	for _, arg := range exp.Args {
+		result += fmt.Sprintf("\n%v", arg.Print(indent + 1))
+	}
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
 }
 
-// A refence expression.
-// Example:
-//	uint32* xPtr = &x; // &x returns the address of x
-type ReferenceExpression struct {
+// A value of the process arguments or environment saved by the program's entry.
+// argc is the argument count, args and envp are slices of pointers to the
+// null terminated argument and environment strings.
+type ProcessValueExpression struct {
 	Type  semantics.Type
-	Op    lexer.Token
-	Right Expression
+	// The argc, args or envp token.
+	Token lexer.Token
 }
 
-func (exp ReferenceExpression) ExprType() semantics.Type {
+func (exp ProcessValueExpression) ExprType() semantics.Type {
 	return exp.Type
 }
 
-func (exp *ReferenceExpression) Semantics(s *semantics.SemanticChecker) error {
-	if err := exp.Right.Semantics(s); err != nil {
+func (exp *ProcessValueExpression) Semantics(s *semantics.SemanticChecker) error {
+	if exp.Token.Type == lexer.ARGC {
+		exp.Type = semantics.Uint64{}
+	} else {
+		exp.Type = semantics.Slice{ Base: semantics.Ptr{ ValueType: semantics.Uint8{} } }
+	}
+
+	return nil
+}
+
+// The initial stack holds argc, the argument pointers, a null pointer,
+// the environment pointers and another null pointer.
+func (exp ProcessValueExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ProcessValueExpression: %v\n", exp.Token.Value)
+
+	e.LoadArgs()
+	switch exp.Token.Type {
+	case lexer.ARGS:
+		fmt.Fprintf(e, "mov rdx, rax\n")
+		fmt.Fprintf(e, "mov rax, rbx\n")
+	case lexer.ENVP:
+		// The environment follows the null pointer terminating the arguments.
+		fmt.Fprintf(e, "lea rax, [rbx + rax * 8 + 8]\n")
+		fmt.Fprintf(e, "mov rdx, 0\n")
+		loopLabel := e.NextLabel()
+		endLabel := e.NextLabel()
+		fmt.Fprintf(e, "%v:\n", loopLabel)
+		fmt.Fprintf(e, "cmp QWORD [rax + rdx * 8], 0\n")
+		fmt.Fprintf(e, "je %v\n", endLabel)
+		fmt.Fprintf(e, "inc rdx\n")
+		fmt.Fprintf(e, "jmp %v\n", loopLabel)
+		fmt.Fprintf(e, "%v:\n", endLabel)
+	}
+}
+
+func (_ ProcessValueExpression) IsAddressable() bool {
+	return false
+}
+
+func (exp ProcessValueExpression) Print(indent int) string {
+	result := fmt.Sprintf("ProcessValueExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type.TypeID())
+	result += fmt.Sprintf("%vValue: %v", indentStr(indent + 1), exp.Token.Value)
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
+}
+
+// An expression evaluated by the compile-time interpreter.
+// Example:
+//  uint64 nine = comptime squares[3];
+//  uint64[16] table = comptime squares; // A copy of the values computed during compilation
+// Scalars are emitted as immediates and aggregates as constants in the .rodata section.
+type ComptimeExpression struct {
+	Type          semantics.Type
+	// The comptime token. Used for error handling.
+	ComptimeToken lexer.Token
+	Expr          Expression
+	// The computed scalar, or the bytes of a computed aggregate. Set during semantics.
+	Value         uint64
+	Data          []byte
+}
+
+func (exp ComptimeExpression) ExprType() semantics.Type {
+	return exp.Type
+}
+
+func (exp *ComptimeExpression) Semantics(s *semantics.SemanticChecker) error {
+	if err := exp.Expr.Semantics(s); err != nil {
 		return err
 	}
+	exp.Type = exp.Expr.ExprType()
 
-	if !exp.Right.IsAddressable() {
+	if holdsAddress(exp.Type) {
 		return s.AddError(
-			"Expected an addressable expression",
-			exp.Op,
+			diagnostics.ComptimeEvaluation,
+			fmt.Sprintf("Values of type %v hold addresses and cannot be computed at compile time", exp.Type.TypeID()),
+			exp.ComptimeToken,
 		)
 	}
 
-	exp.Type = semantics.Ptr{ ValueType: exp.Right.ExprType() }
+	in := newInterpreter(s, exp.ComptimeToken, s.FrameSize())
+	value, err := in.eval(exp.Expr)
+	if err != nil {
+		return err
+	}
+
+	if isAggregate(exp.Type) {
+		bytes, _ := s.ComptimeMemory().Read(value.Scalar, exp.Type.Size())
+		exp.Data = append([]byte{}, bytes...)
+	} else {
+		exp.Value = value.Scalar
+	}
 
 	return nil
 }
 
-func (exp ReferenceExpression) EmitCode(e *codegen.Emitter) {
-	fmt.Fprintf(e, "; ReferenceExpression type = %v\n", exp.Type.TypeID())
-	addr, _ := exp.Right.(AddressableExpression)
-	addr.EmitAddressCode(e)
+func (exp ComptimeExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; ComptimeExpression: type = %v\n", exp.Type.TypeID())
+
+	if isAggregate(exp.Type) {
+		label := e.NextUnscopedLabel()
+		e.WriteROData(codegen.DataDefinition(label, exp.Type.Align(), exp.Data))
+		fmt.Fprintf(e, "lea rax, [rel %v]\n", label)
+		return
+	}
+
+	fmt.Fprintf(e, "mov rax, %v\n", exp.Value)
 }
 
-func (exp ReferenceExpression) EmitAddressCode(e *codegen.Emitter) {
-	// rax already holds the address
-	panic("&&x or &(&x) is incorrect usage")
+func (_ ComptimeExpression) IsAddressable() bool {
+	return false
 }
 
-func (exp ReferenceExpression) IsAddressable() bool {
-	return true
+func (exp ComptimeExpression) Print(indent int) string {
+	result := fmt.Sprintf("ComptimeExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type.TypeID())
+	result += exp.Expr.Print(indent + 1)
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
 }
 
-func (exp ReferenceExpression) Print(indent int) string {
-	return ""
+// Returns the value of a checked expression that is known at compile time.
+func constantValue(expr Expression) (uint64, bool) {
+	switch expr := expr.(type) {
+	case *LiteralExpression:
+		if expr.Type.TypeID() == semantics.BOOL {
+			if expr.Value.Value == "true" {
+				return 1, true
+			}
+			return 0, true
+		}
+
+		value, err := strconv.ParseUint(expr.Value.Value, 10, 64)
+		return value, err == nil
+	case *MemberExpression:
+		if expr.Qualified != nil || expr.Variant {
+			return 0, false
+		}
+
+		_, isSlice := semantics.Underlying(expr.Left.ExprType()).(semantics.Slice)
+		return expr.Value, !isSlice
+	case *TypeInfoExpression:
+		return uint64(expr.Value), true
+	case *ComptimeExpression:
+		return expr.Value, !isAggregate(expr.Type)
+	case *GroupExpression:
+		return constantValue(expr.Expr)
+	case *CastExpression:
+		value, isConst := constantValue(expr.Left)
+		return value & semantics.MaxUint(min(expr.Left.ExprType().Size(), expr.Type.Size())), isConst
+	case *BinaryExpression:
+		left, isLeftConst := constantValue(expr.Left)
+		right, isRightConst := constantValue(expr.Right)
+		if !isLeftConst || !isRightConst {
+			return 0, false
+		}
+
+		return foldBinary(expr.Op.Value, left, right, expr.Type)
+	case *ConditionalExpression:
+		condition, isConst := constantValue(expr.Condition)
+		if !isConst {
+			return 0, false
+		}
+
+		if condition != 0 {
+			return constantValue(expr.Then)
+		}
+		return constantValue(expr.Else)
+	}
+
+	return 0, false
 }
 
-// An array access expression.
+// Applies a binary operator to constant operands.
+// Arithmetic wraps at the size of the result type and division by zero has no value.
+func foldBinary(op string, left uint64, right uint64, t semantics.Type) (uint64, bool) {
+	mask := semantics.MaxUint(t.Size())
+
+	switch op {
+	case "+":
+		return (left + right) & mask, true
+	case "-":
+		return (left - right) & mask, true
+	case "*":
+		return (left * right) & mask, true
+	case "/":
+		if right == 0 {
+			return 0, false
+		}
+		return left / right, true
+	case "==":
+		return boolValue(left == right), true
+	case "!=":
+		return boolValue(left != right), true
+	// Orderings are unsigned like the emitted setb, setbe, seta and setae.
+	case "<":
+		return boolValue(left < right), true
+	case "<=":
+		return boolValue(left <= right), true
+	case ">":
+		return boolValue(left > right), true
+	case ">=":
+		return boolValue(left >= right), true
+	}
+
+	return 0, false
+}
+
+func boolValue(b bool) uint64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// A slicing expression takes a view of the elements [Low, High) of an array or slice.
 // Example:
-//  uint32[3] xs;
-//  xs[1] = 2;
-//  assert xs[1] == 2;
-type ArrayAccessExpression struct {
+//  uint8[8] buffer;
+//  uint8[] head = buffer[0..4];
+//  uint8[] tail = head[2..]; // Bounds default to 0 and the length
+// The bounds are checked at runtime and the program exits with status 1 when they are invalid.
+type SliceExpression struct {
 	Type        semantics.Type
 	Left        Expression
-	IndexExpr   Expression
+	Low         utils.Optional[Expression]
+	High        utils.Optional[Expression]
 	// For error handling.
 	OpenBracket lexer.Token
 }
 
-func (exp ArrayAccessExpression) ExprType() semantics.Type {
+func (exp SliceExpression) ExprType() semantics.Type {
 	return exp.Type
 }
 
-func (exp *ArrayAccessExpression) Semantics(s *semantics.SemanticChecker) error {
+func (exp *SliceExpression) Semantics(s *semantics.SemanticChecker) error {
 	if err := exp.Left.Semantics(s); err != nil {
 		return err
 	}
 
-	array, isArray := exp.Left.ExprType().(semantics.Array)
-	if !isArray {
+	length := -1
+	switch left := semantics.Underlying(exp.Left.ExprType()).(type) {
+	case semantics.Array:
+		exp.Type = semantics.Slice{ Base: left.Base }
+		length = left.Length
+	case semantics.Slice:
+		exp.Type = left
+	default:
 		return s.AddError(
+			diagnostics.InvalidOperand,
 			fmt.Sprintf(
-				"'[]' operator can be only used on arrays but received %v",
+				"Slicing can be only used on arrays and slices but received %v",
 				exp.Left.ExprType().TypeID(),
 			),
 			exp.OpenBracket,
 		)
 	}
-	exp.Type = array.Base
+
+	bounds := []uint64{}
+	for _, bound := range []utils.Optional[Expression]{ exp.Low, exp.High } {
+		if !bound.HasVal() {
+			continue
+		}
+
+		if err := bound.Value().Semantics(s); err != nil {
+			return err
+		}
+
+		if !semantics.IsNumber(bound.Value().ExprType()) {
+			return s.AddError(
+				diagnostics.InvalidOperand,
+				fmt.Sprintf("Slice bounds must be unsigned integers but received %v", bound.Value().ExprType().TypeID()),
+				exp.OpenBracket,
+			)
+		}
+
+		value, isConst := constantValue(bound.Value())
+		if isConst && length != -1 && value > uint64(length) {
+			return s.AddError(
+				diagnostics.InvalidOperand,
+				fmt.Sprintf("Slice bound %v is out of range for array of length %v", value, length),
+				exp.OpenBracket,
+			)
+		}
+		if isConst {
+			bounds = append(bounds, value)
+		}
+	}
+
+	if exp.Low.HasVal() && exp.High.HasVal() && len(bounds) == 2 && bounds[0] > bounds[1] {
+		return s.AddError(
+			diagnostics.InvalidOperand,
+			fmt.Sprintf("Lower slice bound %v is greater than the upper bound %v", bounds[0], bounds[1]),
+			exp.OpenBracket,
+		)
+	}
 
 	return nil
 }
 
-func (exp ArrayAccessExpression) EmitCode(e *codegen.Emitter) {
-	fmt.Fprintf(e, "; ArrayAccessExpression rvalue type = %v\n", exp.Type)
-	addrExp, _ := exp.Left.(AddressableExpression)
-	addrExp.EmitAddressCode(e)
+func (exp SliceExpression) EmitCode(e *codegen.Emitter) {
+	fmt.Fprintf(e, "; SliceExpression type = %v\n", exp.Type.TypeID())
+	exp.Left.EmitCode(e)
+	if array, isArray := semantics.Underlying(exp.Left.ExprType()).(semantics.Array); isArray {
+		fmt.Fprintf(e, "mov rdx, %v\n", array.Length)
+	}
 	fmt.Fprintf(e, "push rax\n")
-	exp.IndexExpr.EmitCode(e)
-	fmt.Fprintf(e, "mov rbx, %v\n", exp.Type.Size())
-	fmt.Fprintf(e, "mul rbx\n")
-	fmt.Fprintf(e, "pop rbx\n")
-	fmt.Fprintf(e, "mov %v, %v [rbx + rax]\n", exp.Type.Register(), exp.Type.ASMSize())
-}
+	fmt.Fprintf(e, "push rdx\n")
 
-func (exp ArrayAccessExpression) EmitAddressCode(e *codegen.Emitter) {
-	fmt.Fprintf(e, "; ArrayAccessExpression lvalue type = %v\n", exp.Type)
-	addrExp, _ := exp.Left.(AddressableExpression)
-	addrExp.EmitAddressCode(e)
+	if exp.Low.HasVal() {
+		exp.Low.Value().EmitCode(e)
+	} else {
+		fmt.Fprintf(e, "mov rax, 0\n")
+	}
 	fmt.Fprintf(e, "push rax\n")
-	exp.IndexExpr.EmitCode(e)
-	fmt.Fprintf(e, "mov rbx, %v\n", exp.Type.Size())
-	fmt.Fprintf(e, "mul rbx\n")
-	fmt.Fprintf(e, "pop rbx\n")
-	fmt.Fprintf(e, "lea rax, [rbx + rax]\n")
+
+	if exp.High.HasVal() {
+		exp.High.Value().EmitCode(e)
+	} else {
+		fmt.Fprintf(e, "mov rax, [rsp + 8]\n")
+	}
+
+	fmt.Fprintf(e, "mov rcx, rax\n") // rcx holds the upper bound
+	fmt.Fprintf(e, "pop rax\n") // rax holds the lower bound
+	fmt.Fprintf(e, "pop rdx\n") // rdx holds the length
+	fmt.Fprintf(e, "pop rbx\n") // rbx holds the pointer
+
+	validLabel := e.NextLabel()
+	failLabel := e.NextLabel()
+	fmt.Fprintf(e, "cmp rcx, rdx\n")
+	fmt.Fprintf(e, "ja %v\n", failLabel)
+	fmt.Fprintf(e, "cmp rax, rcx\n")
+	fmt.Fprintf(e, "jbe %v\n", validLabel)
+	fmt.Fprintf(e, "%v:\n", failLabel)
+	e.Fail("__clovis_msg_slice_bounds", "clovis: slice bounds out of range")
+	fmt.Fprintf(e, "%v:\n", validLabel)
+
+	fmt.Fprintf(e, "sub rcx, rax\n")
+	fmt.Fprintf(e, "imul rax, rax, %v\n", exp.Type.(semantics.Slice).Base.Size())
+	fmt.Fprintf(e, "add rax, rbx\n")
+	fmt.Fprintf(e, "mov rdx, rcx\n")
 }
 
-func (_ ArrayAccessExpression) IsAddressable() bool {
-	return true
+func (_ SliceExpression) IsAddressable() bool {
+	return false
 }
 
-func (exp ArrayAccessExpression) Print(indent int) string {
-	return ""
+func (exp SliceExpression) Print(indent int) string {
+	result := fmt.Sprintf("SliceExpression\n%v{\n", indentStr(indent))
+	result += fmt.Sprintf("%vType: %v\n", indentStr(indent + 1), exp.Type.TypeID())
+	result += exp.Left.Print(indent + 1)
+	if exp.Low.HasVal() {
+		result += fmt.Sprintf("\n%v", exp.Low.Value().Print(indent + 1))
+	}
+	if exp.High.HasVal() {
+		result += fmt.Sprintf("\n%v", exp.High.Value().Print(indent + 1))
+	}
+	return fmt.Sprintf("%v%v\n%v}", indentStr(indent), result, indentStr(indent))
 }
 
 // A literal expression holds a literal.
@@ -734,6 +4379,30 @@ func (exp *IdentExpression) Semantics(s *semantics.SemanticChecker) error {
 	if err != nil {
 		return err
 	}
+
+	if symbol.IsType {
+		return s.AddError(
+			diagnostics.WrongSymbolKind,
+			fmt.Sprintf("'%v' is a type and cannot be used as a value", exp.Ident.Value),
+			exp.Ident,
+		)
+	}
+
+	if symbol.IsModule {
+		return s.AddError(
+			diagnostics.WrongSymbolKind,
+			fmt.Sprintf("'%v' is a module and cannot be used as a value", exp.Ident.Value),
+			exp.Ident,
+		)
+	}
+
+	if _, isFunction := symbol.Type.(semantics.Function); isFunction {
+		return s.AddError(
+			diagnostics.WrongSymbolKind,
+			fmt.Sprintf("'%v' is a function and can only be called", exp.Ident.Value),
+			exp.Ident,
+		)
+	}
 	exp.Symbol = *symbol
 	exp.Type = symbol.Type
 
@@ -742,23 +4411,12 @@ func (exp *IdentExpression) Semantics(s *semantics.SemanticChecker) error {
 
 func (exp IdentExpression) EmitCode(e *codegen.Emitter) {
 	fmt.Fprintf(e, "; IdentExpression rvalue type = %v\n", exp.Type.TypeID())
-	_, isArray := exp.Type.(semantics.Array)
-	if isArray {
-		fmt.Fprintf(e, "lea rax, [rbp - %v]\n", exp.Symbol.Offset)
-	} else {
-		fmt.Fprintf(
-			e,
-			"mov %v, %v [rbp - %v]\n",
-			exp.Type.Register(),
-			exp.Type.ASMSize(),
-			exp.Symbol.Offset,
-		)
-	}
+	emitLoad(e, exp.Type, exp.Symbol.Addr())
 }
 
 func (exp IdentExpression) EmitAddressCode(e *codegen.Emitter) {
 	fmt.Fprintf(e, "; IdentExpression lvalue type = %v\n", exp.Type.TypeID())
-	fmt.Fprintf(e, "lea rax, [rbp - %v]\n", exp.Symbol.Offset)
+	fmt.Fprintf(e, "lea rax, [%v]\n", exp.Symbol.Addr())
 }
 
 func (exp IdentExpression) IsAddressable() bool {
@@ -804,7 +4462,7 @@ func (exp GroupExpression) EmitAddressCode(e *codegen.Emitter) {
 }
 
 func (exp GroupExpression) IsAddressable() bool {
-	return exp.IsAddressable()
+	return exp.Expr.IsAddressable()
 }
 
 func (exp GroupExpression) Print(indent int) string {
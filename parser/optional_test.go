@@ -0,0 +1,39 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptionalSyntax(t *testing.T) {
+	expectSyntaxError(t, `uint32? a; if let = a a = 1;`)
+	expectSyntaxError(t, `uint32? a; if let v a a = 1;`)
+}
+
+func TestIfLetReleasesItsScope(t *testing.T) {
+	asm := golden(t, "iflet")
+
+	// The body reserves its own locals, if let only reserves the binding.
+	expectLines(t, asm, "sub rsp, 4", "add rsp, 8")
+	if growth := stackGrowth(between(t, asm, "; binding v", "jmp ")); growth != 0 {
+		t.Errorf("Expected if let to release what it reserves but the stack grows by %v", growth)
+	}
+
+	if growth := stackGrowth(between(t, asm, "WhileStmt", "; Emitter.End()")); growth != 0 {
+		t.Errorf("Expected the loop to keep the stack balanced but it grows by %v", growth)
+	}
+}
+
+func TestNestedOptionalSyntax(t *testing.T) {
+	expectSyntaxError(t, `uint32?? a;`)
+}
+
+func TestNoneComparisonCode(t *testing.T) {
+	asm := emit(t, `uint32? a = 5; bool b = a == none; bool c = none != a;`)
+
+	// Only the presence flag is compared, none itself is never evaluated.
+	expectLines(t, asm, "cmp BYTE [rax], 0", "sete al", "cmp BYTE [rax], 0", "setne al")
+	if strings.Contains(asm, "mov rax, none") {
+		t.Errorf("Expected none not to be evaluated but received:\n%v", asm)
+	}
+}
@@ -1,40 +1,41 @@
 package parser
 
 import (
+	"clovis/diagnostics"
 	"clovis/lexer"
 	"clovis/semantics"
+	"clovis/utils"
 	"fmt"
 	"strconv"
 )
 
-type ParserError struct {
-	token lexer.Token
-	msg   string
-}
-
-func NewParserError(token lexer.Token, msg string) *ParserError {
-	return &ParserError{
-		token: token,
-		msg: msg,
-	}
-}
-
-func (e *ParserError) Error() string {
-	return fmt.Sprintf("Error at line %v at column %v at token %v\n\t%v", e.token.Line, e.token.Col, e.token.Type, e.msg)
+// Creates the diagnostic of a syntax error at token.
+func NewParserError(token lexer.Token, msg string) *diagnostics.Diagnostic {
+	return diagnostics.New(diagnostics.SyntaxError, token.Span(), msg)
 }
 
 type Parser struct {
-	Stmts  []Statement
-	Errors []error
-	tokens []lexer.Token
-	idx    int
+	Stmts       []Statement
+	// The path of the parsed file, set on the reported diagnostics.
+	File        string
+	Diagnostics *diagnostics.Collector
+	tokens      []lexer.Token
+	idx         int
 }
 
 func NewParser(tokens []lexer.Token) *Parser {
+	// Illegal tokens were reported by the lexer, parsing goes on without them.
+	valid := []lexer.Token{}
+	for _, token := range tokens {
+		if token.Type != lexer.ILLEGAL {
+			valid = append(valid, token)
+		}
+	}
+
 	return &Parser{
 		Stmts: []Statement{},
-		Errors: []error{},
-		tokens: tokens,
+		Diagnostics: diagnostics.NewCollector(),
+		tokens: valid,
 		idx: 0,
 	}
 }
@@ -42,11 +43,18 @@ func NewParser(tokens []lexer.Token) *Parser {
 func (p *Parser) Parse() error {
 	p.parseProgram()
 
-	if errLen := len(p.Errors); errLen != 0 {
-		return p.Errors[errLen - 1]
+	return p.Diagnostics.Err()
+}
+
+// Reports an error returned by a parse function.
+func (p *Parser) report(err error) {
+	d, isDiagnostic := err.(*diagnostics.Diagnostic)
+	if !isDiagnostic {
+		d = NewParserError(p.peek(), err.Error())
 	}
 
-	return nil
+	d.File = p.File
+	p.Diagnostics.Report(d)
 }
 
 func (p *Parser) parseProgram() {
@@ -59,8 +67,11 @@ func (p *Parser) parseStatements() []Statement {
 	for p.idx < len(p.tokens) && !p.isAtEnd() {
 		stmt, err := p.parseStatement()
 		if err != nil {
-			p.Errors = append(p.Errors, err)
+			p.report(err)
 			p.synchronize()
+			if p.match(lexer.CLOSE_CURLY) {
+				p.consume() // No block to end at the top level
+			}
 			continue
 		}
 		stmts = append(stmts, stmt)
@@ -70,8 +81,18 @@ func (p *Parser) parseStatements() []Statement {
 }
 
 func (p *Parser) parseStatement() (Statement, error) {
-	if p.matchAny(lexer.UINT_64, lexer.UINT_32, lexer.UINT_16, lexer.UINT_8, lexer.BOOL) {
+	if p.match(lexer.SHARED) || p.isVarDeclStart() {
 		return p.parseVarDecl()
+	} else if p.match(lexer.ENUM) {
+		return p.parseEnumDecl()
+	} else if p.match(lexer.TYPE) {
+		return p.parseTypeDecl()
+	} else if p.match(lexer.UNION) {
+		return p.parseUnionDecl()
+	} else if p.match(lexer.MATCH) {
+		return p.parseMatchStmt()
+	} else if p.match(lexer.SWITCH) {
+		return p.parseSwitchStmt()
 	} else if p.matchAny(lexer.STAR, lexer.IDENT, lexer.OPEN_PAREN) {
 		return p.parseVarDefinition()
 	} else if p.match(lexer.OPEN_CURLY) {
@@ -79,30 +100,154 @@ func (p *Parser) parseStatement() (Statement, error) {
 	} else if p.match(lexer.IF) {
 		return p.parseIfStmt()
 	} else if p.match(lexer.WHILE) {
-		p.parseWhileStmt()
+		return p.parseWhileStmt()
 	} else if p.match(lexer.FOR) {
-		p.parseForStmt()
+		return p.parseForStmt()
 	} else if p.match(lexer.ASSERT) {
 		return p.parseAssert()
+	} else if p.match(lexer.STATIC_ASSERT) {
+		return p.parseStaticAssert()
+	} else if p.match(lexer.COMPTIME) && p.tokens[p.idx + 1].Type == lexer.OPEN_CURLY {
+		return p.parseComptimeBlock()
+	} else if p.match(lexer.DEFER) {
+		return p.parseDeferStmt()
+	} else if p.match(lexer.ASM) {
+		return p.parseAsmStmt()
+	} else if p.match(lexer.EXIT) {
+		return p.parseExitStmt()
+	} else if p.match(lexer.DELETE) {
+		return p.parseDeleteStmt()
+	} else if p.match(lexer.JOIN) {
+		return p.parseJoinStmt()
+	} else if p.match(lexer.IMPORT) {
+		return p.parseImportStmt()
+	} else if p.match(lexer.EXTERN) {
+		return p.parseExternDecl()
 	} else {
 		return p.parseExpressionStmt()
 	}
-
-	return nil, nil
 }
 
-// <varDecl> ::= <typeID> { "*" | "[" UINT_LIT "]" } IDENT ( ";" | "=" <expression> ";" )
+// <varDecl> ::= [ "shared" ] <type> IDENT ( ";" | "=" <expression> ";" )
 func (p *Parser) parseVarDecl() (*VarDeclStmt, error) {
 	decl := VarDeclStmt{}
-	decl.Type = p.getType(p.consume().Type)
 
-	for p.matchAny(lexer.STAR, lexer.OPEN_BRACKET) {
+	if p.match(lexer.SHARED) {
+		p.consume() // 'shared'
+		decl.Shared = true
+	}
+
+	declType, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	decl.Type = declType
+
+	if !p.match(lexer.IDENT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected an identifer after type defintion but received '%v'", p.consume().Value),
+		)
+	}
+	decl.Ident = p.consume()
+
+	if p.match(lexer.ASSIGN) {
+		p.consume() // '='
+		right, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		decl.Right.SetVal(right)
+	}
+
+	if !p.match(lexer.SEMI) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ';' after variable declaration but received '%v'", p.consume().Value),
+		)
+	}
+	p.consume() // ';'
+
+	return &decl, nil
+}
+
+// <type> ::= <baseType> { "*" | "?" | "[" [ UINT_LIT ] "]" }
+func (p *Parser) parseType() (semantics.Type, error) {
+	t, err := p.parseBaseType()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseTypeModifiers(t, true)
+}
+
+// <baseType> ::= <typeID> | IDENT [ "." IDENT ] | "typeof" "(" <expression> ")"
+func (p *Parser) parseBaseType() (semantics.Type, error) {
+	var t semantics.Type
+
+	if p.match(lexer.TYPEOF) {
+		typeofToken := p.consume() // 'typeof'
+
+		if !p.match(lexer.OPEN_PAREN) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected '(' after typeof but received '%v'", p.peek().Value),
+			)
+		}
+		p.consume() // '('
+
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.match(lexer.CLOSE_PAREN) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected ')' after typeof expression but received '%v'", p.peek().Value),
+			)
+		}
+		p.consume() // ')'
+
+		t = semantics.TypeOf{ Token: typeofToken, Expr: expr }
+	} else if p.match(lexer.IDENT) {
+		typeRef := semantics.TypeRef{ Ident: p.consume() }
+
+		// A type declared by an imported module, e.g. math.Vec
+		if p.match(lexer.DOT) && p.tokens[p.idx + 1].Type == lexer.IDENT {
+			p.consume() // '.'
+			typeRef.Module.SetVal(typeRef.Ident)
+			typeRef.Ident = p.consume()
+		}
+		t = typeRef
+	} else {
+		t = p.getType(p.consume().Type)
+	}
+
+	return t, nil
+}
+
+// Applies the pointer, optional and, when arrays is set, array and slice modifiers following a type.
+func (p *Parser) parseTypeModifiers(t semantics.Type, arrays bool) (semantics.Type, error) {
+	for p.matchAny(lexer.STAR, lexer.QUESTION) || (arrays && p.match(lexer.OPEN_BRACKET)) {
 		if p.match(lexer.STAR) {
-			decl.Type = semantics.Ptr{ ValueType: decl.Type }
+			t = semantics.Ptr{ ValueType: t }
 			p.consume() // '*'
+		} else if p.match(lexer.QUESTION) {
+			if _, isOptional := t.(semantics.Optional); isOptional {
+				return nil, NewParserError(p.peek(), "Optionals cannot be nested")
+			}
+			t = semantics.Optional{ Base: t }
+			p.consume() // '?'
 		} else if p.match(lexer.OPEN_BRACKET) {
 			p.consume() // '['
 
+			if p.match(lexer.CLOSE_BRACKET) {
+				p.consume() // ']'
+				t = semantics.Slice{ Base: t }
+				continue
+			}
+
 			if !p.match(lexer.UINT_64_LIT) {
 				return nil, NewParserError(
 					p.peek(),
@@ -120,36 +265,201 @@ func (p *Parser) parseVarDecl() (*VarDeclStmt, error) {
 			p.consume() // ']'
 			
 			arrLength, _ := strconv.Atoi(sizeToken.Value)
-			decl.Type = semantics.Array{ Base: decl.Type, Length: arrLength }
+			t = semantics.Array{ Base: t, Length: arrLength }
 		}
 	}
 
+	return t, nil
+}
+
+// <enumDecl> ::= "enum" IDENT ":" <typeID> "{" [ <enumMember> { "," <enumMember> } [ "," ] ] "}"
+// <enumMember> ::= IDENT [ "=" UINT_LIT ]
+func (p *Parser) parseEnumDecl() (Statement, error) {
+	stmt := EnumDeclStmt{ EnumToken: p.consume() }
+
 	if !p.match(lexer.IDENT) {
 		return nil, NewParserError(
 			p.peek(),
-			fmt.Sprintf("Expected an identifer after type defintion but received '%v'", p.consume().Value),
+			fmt.Sprintf("Expected an identifier after enum but received '%v'", p.peek().Value),
 		)
 	}
-	decl.Ident = p.consume()
+	stmt.Ident = p.consume()
+
+	if !p.match(lexer.COLON) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ':' and a backing type after enum name but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // ':'
+
+	if !p.isTypeStart() {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected a backing type for enum but received '%v'", p.peek().Value),
+		)
+	}
+	stmt.Backing = p.getType(p.consume().Type)
+
+	if !p.match(lexer.OPEN_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '{' after enum backing type but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '{'
+
+	for !p.isAtEnd() && !p.match(lexer.CLOSE_CURLY) {
+		if !p.match(lexer.IDENT) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected an enum member but received '%v'", p.peek().Value),
+			)
+		}
+		member := EnumMemberDecl{ Ident: p.consume() }
+
+		if p.match(lexer.ASSIGN) {
+			p.consume() // '='
+
+			if !p.match(lexer.UINT_64_LIT) {
+				return nil, NewParserError(
+					p.peek(),
+					fmt.Sprintf("Expected an unsigned integer literal as enum value but received '%v'", p.peek().Value),
+				)
+			}
+			member.Value.SetVal(p.consume())
+		}
+		stmt.Members = append(stmt.Members, member)
+
+		if !p.match(lexer.COMMA) {
+			break
+		}
+		p.consume() // ','
+	}
+
+	if !p.match(lexer.CLOSE_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '}' after enum members but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '}'
+
+	return &stmt, nil
+}
+
+// <typeDecl> ::= "type" IDENT [ "=" ] <type> ";"
+func (p *Parser) parseTypeDecl() (Statement, error) {
+	stmt := TypeDeclStmt{ TypeToken: p.consume() }
+
+	if !p.match(lexer.IDENT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected an identifier after type but received '%v'", p.peek().Value),
+		)
+	}
+	stmt.Ident = p.consume()
 
 	if p.match(lexer.ASSIGN) {
 		p.consume() // '='
-		right, err := p.parseExpression()
-		if err != nil {
-			return nil, err
-		}
-		decl.Right.SetVal(right)
+		stmt.IsAlias = true
 	}
 
+	if !p.isTypeStart() && !p.match(lexer.IDENT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected a type in declaration of '%v' but received '%v'", stmt.Ident.Value, p.peek().Value),
+		)
+	}
+
+	declType, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Type = declType
+
 	if !p.match(lexer.SEMI) {
 		return nil, NewParserError(
 			p.peek(),
-			fmt.Sprintf("Expected ';' after variable declaration but received '%v'", p.consume().Value),
+			fmt.Sprintf("Expected ';' after type declaration but received '%v'", p.peek().Value),
 		)
 	}
 	p.consume() // ';'
 
-	return &decl, nil
+	return &stmt, nil
+}
+
+// <unionDecl> ::= "union" IDENT "{" [ <unionVariant> { "," <unionVariant> } [ "," ] ] "}"
+// <unionVariant> ::= IDENT [ "(" [ <type> { "," <type> } ] ")" ]
+func (p *Parser) parseUnionDecl() (Statement, error) {
+	stmt := UnionDeclStmt{ UnionToken: p.consume() }
+
+	if !p.match(lexer.IDENT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected an identifier after union but received '%v'", p.peek().Value),
+		)
+	}
+	stmt.Ident = p.consume()
+
+	if !p.match(lexer.OPEN_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '{' after union name but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '{'
+
+	for !p.isAtEnd() && !p.match(lexer.CLOSE_CURLY) {
+		if !p.match(lexer.IDENT) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected a union variant but received '%v'", p.peek().Value),
+			)
+		}
+		variant := UnionVariantDecl{ Ident: p.consume() }
+
+		if p.match(lexer.OPEN_PAREN) {
+			p.consume() // '('
+
+			for !p.isAtEnd() && !p.match(lexer.CLOSE_PAREN) {
+				field, err := p.parseType()
+				if err != nil {
+					return nil, err
+				}
+				variant.Fields = append(variant.Fields, field)
+
+				if !p.match(lexer.COMMA) {
+					break
+				}
+				p.consume() // ','
+			}
+
+			if !p.match(lexer.CLOSE_PAREN) {
+				return nil, NewParserError(
+					p.peek(),
+					fmt.Sprintf("Expected ')' after variant fields but received '%v'", p.peek().Value),
+				)
+			}
+			p.consume() // ')'
+		}
+		stmt.Variants = append(stmt.Variants, variant)
+
+		if !p.match(lexer.COMMA) {
+			break
+		}
+		p.consume() // ','
+	}
+
+	if !p.match(lexer.CLOSE_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '}' after union variants but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '}'
+
+	return &stmt, nil
 }
 
 // <varDefinition> ::= <lvalue> "=" <expression> ";"
@@ -162,6 +472,12 @@ func (p *Parser) parseVarDefinition() (Statement, error) {
 	}
 	varDefStmt.Left = left
 
+	// Not an assignment but an expression statement such as a call.
+	if p.match(lexer.SEMI) {
+		p.consume() // ';'
+		return &ExpressionStmt{ Expr: left }, nil
+	}
+
 	if !p.match(lexer.ASSIGN) {
 		return nil, NewParserError(
 			p.peek(),
@@ -197,7 +513,7 @@ func (p *Parser) parseBlockStmt() (Statement, error) {
 	for p.idx < len(p.tokens) && !p.isAtEnd() && !p.match(lexer.CLOSE_CURLY) {
 		stmt, err := p.parseStatement()
 		if err != nil {
-			p.Errors = append(p.Errors, err)
+			p.report(err)
 			p.synchronize()
 			continue
 		}
@@ -221,10 +537,269 @@ func (p *Parser) parseBlockStmt() (Statement, error) {
 	return &blockStmt, nil
 }
 
-// <ifStmt> ::= "if" <expression> <statement> ( "else" <statement> )
+// <deferStmt> ::= "defer" <statement>
+func (p *Parser) parseDeferStmt() (Statement, error) {
+	stmt := DeferStmt{ DeferToken: p.consume() }
+
+	deferred, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Stmt = deferred
+
+	return &stmt, nil
+}
+
+// <asmStmt> ::= "asm" [ "(" [ IDENT { "," IDENT } ] ")" ] "{" ASM_TEXT "}"
+func (p *Parser) parseAsmStmt() (Statement, error) {
+	stmt := AsmStmt{ AsmToken: p.consume() }
+
+	if p.match(lexer.OPEN_PAREN) {
+		p.consume() // '('
+
+		for p.match(lexer.IDENT) {
+			stmt.Clobbers = append(stmt.Clobbers, p.consume())
+
+			if !p.match(lexer.COMMA) {
+				break
+			}
+			p.consume() // ','
+		}
+
+		if !p.match(lexer.CLOSE_PAREN) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected ')' after clobbered registers but received '%v'", p.peek().Value),
+			)
+		}
+		p.consume() // ')'
+	}
+
+	if !p.match(lexer.OPEN_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '{' after asm but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '{'
+
+	if !p.match(lexer.ASM_TEXT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected assembly text but received '%v'", p.peek().Value),
+		)
+	}
+	stmt.Text = p.consume()
+
+	if !p.match(lexer.CLOSE_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			"Expected '}' after assembly text",
+		)
+	}
+	p.consume() // '}'
+
+	return &stmt, nil
+}
+
+// <exitStmt> ::= "exit" "(" <expression> ")" ";"
+func (p *Parser) parseExitStmt() (Statement, error) {
+	stmt := ExitStmt{ ExitToken: p.consume() }
+
+	if !p.match(lexer.OPEN_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '(' after exit but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '('
+
+	code, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Code = code
+
+	if !p.match(lexer.CLOSE_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ')' after exit code but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // ')'
+
+	if !p.match(lexer.SEMI) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ';' found %v", p.peek().Value),
+		)
+	}
+	p.consume() // ';'
+
+	return &stmt, nil
+}
+
+// <joinStmt> ::= "join" "(" <expression> ")" ";"
+func (p *Parser) parseJoinStmt() (Statement, error) {
+	stmt := JoinStmt{ JoinToken: p.consume() }
+
+	if !p.match(lexer.OPEN_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '(' after join but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '('
+
+	thread, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Thread = thread
+
+	if !p.match(lexer.CLOSE_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ')' after the joined thread but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // ')'
+
+	if !p.match(lexer.SEMI) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ';' found %v", p.peek().Value),
+		)
+	}
+	p.consume() // ';'
+
+	return &stmt, nil
+}
+
+// <deleteStmt> ::= "delete" <expression> ";"
+func (p *Parser) parseDeleteStmt() (Statement, error) {
+	stmt := DeleteStmt{ DeleteToken: p.consume() }
+
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Expr = expr
+
+	if !p.match(lexer.SEMI) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ';' found %v", p.peek().Value),
+		)
+	}
+	p.consume() // ';'
+
+	return &stmt, nil
+}
+
+// <importStmt> ::= "import" STRING_LIT ";"
+func (p *Parser) parseImportStmt() (Statement, error) {
+	stmt := ImportStmt{ ImportToken: p.consume() }
+
+	if !p.match(lexer.STRING_LIT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected a file path after import but received '%v'", p.peek().Value),
+		)
+	}
+	stmt.Path = p.consume()
+
+	if !p.match(lexer.SEMI) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ';' found %v", p.peek().Value),
+		)
+	}
+	p.consume() // ';'
+
+	return &stmt, nil
+}
+
+// <externDecl> ::= "extern" [ <type> ] IDENT "(" [ <param> { "," <param> } [ "," "..." ] | "..." ] ")" ";"
+// <param> ::= <type> [ IDENT ]
+func (p *Parser) parseExternDecl() (Statement, error) {
+	decl := ExternDeclStmt{ ExternToken: p.consume() }
+
+	// Functions returning nothing have no return type before their name.
+	if !p.match(lexer.IDENT) || p.tokens[p.idx + 1].Type != lexer.OPEN_PAREN {
+		returnType, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		decl.Return.SetVal(returnType)
+	}
+
+	if !p.match(lexer.IDENT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected a function name in extern declaration but received '%v'", p.peek().Value),
+		)
+	}
+	decl.Ident = p.consume()
+
+	if !p.match(lexer.OPEN_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '(' after extern function name but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '('
+
+	for !p.match(lexer.CLOSE_PAREN) {
+		if p.match(lexer.ELLIPSIS) {
+			p.consume() // '...'
+			decl.Variadic = true
+			break
+		}
+
+		param, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		decl.Params = append(decl.Params, param)
+
+		if p.match(lexer.IDENT) {
+			p.consume() // Parameter names are only documentation.
+		}
+
+		if !p.match(lexer.COMMA) {
+			break
+		}
+		p.consume() // ','
+	}
+
+	if !p.match(lexer.CLOSE_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ')' after extern parameters but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // ')'
+
+	if !p.match(lexer.SEMI) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ';' found %v", p.peek().Value),
+		)
+	}
+	p.consume() // ';'
+
+	return &decl, nil
+}
+
+// <ifStmt> ::= "if" <expression> <statement> ( "else" <statement> ) | <ifLet>
 func (p *Parser) parseIfStmt() (Statement, error) {
 	ifStmt := IfStmt{}
 	ifStmt.IfToken = p.consume()
+
+	if p.match(lexer.LET) {
+		return p.parseIfLetStmt(ifStmt.IfToken)
+	}
 	
 	expr, err := p.parseExpression()
 	if err != nil {
@@ -250,12 +825,246 @@ func (p *Parser) parseIfStmt() (Statement, error) {
 	return &ifStmt, nil
 }
 
-func (p *Parser) parseWhileStmt() {
+// <switchStmt> ::= "switch" <expression> "{" { <switchArm> } "}"
+// <switchArm> ::= ( "case" <caseItem> { "," <caseItem> } | "default" ) ":" <statement>
+// <caseItem> ::= <expression> [ ".." <expression> ]
+func (p *Parser) parseSwitchStmt() (Statement, error) {
+	stmt := SwitchStmt{ SwitchToken: p.consume() }
+
+	subject, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Subject = subject
+
+	if !p.match(lexer.OPEN_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '{' after switch expression but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '{'
+
+	for p.matchAny(lexer.CASE, lexer.DEFAULT) {
+		arm := SwitchArm{ Token: p.consume() }
+		arm.IsDefault = arm.Token.Type == lexer.DEFAULT
+
+		for !arm.IsDefault {
+			c := SwitchCase{ Token: p.peek() }
+
+			low, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			c.Low = low
+
+			if p.match(lexer.DOT_DOT) {
+				p.consume() // '..'
+				high, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				c.High.SetVal(high)
+			}
+			arm.Cases = append(arm.Cases, c)
+
+			if !p.match(lexer.COMMA) {
+				break
+			}
+			p.consume() // ','
+		}
+
+		if !p.match(lexer.COLON) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected ':' after %v but received '%v'", arm.Token.Value, p.peek().Value),
+			)
+		}
+		p.consume() // ':'
+
+		armStmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		arm.Stmt = armStmt
+
+		stmt.Arms = append(stmt.Arms, arm)
+	}
+
+	if !p.match(lexer.CLOSE_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected 'case', 'default' or '}' in switch but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '}'
+
+	return &stmt, nil
+}
+
+// <ifLet> ::= "if" "let" IDENT "=" <expression> <statement> [ "else" <statement> ]
+func (p *Parser) parseIfLetStmt(ifToken lexer.Token) (Statement, error) {
+	stmt := IfLetStmt{ IfToken: ifToken }
+	p.consume() // 'let'
+
+	if !p.match(lexer.IDENT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected an identifier after let but received '%v'", p.peek().Value),
+		)
+	}
+	stmt.Ident = p.consume()
+
+	if !p.match(lexer.ASSIGN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '=' after let binding but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '='
+
+	subject, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Subject = subject
+
+	ifStmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Stmt = ifStmt
+
+	if p.match(lexer.ELSE) {
+		p.consume() // 'else'
+		elseStmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmt.ElseStmt.SetVal(elseStmt)
+	}
+
+	return &stmt, nil
+}
+
+// <matchStmt> ::= "match" <expression> "{" { <matchArm> } "}"
+// <matchArm> ::= ( "case" IDENT [ "(" [ IDENT { "," IDENT } ] ")" ] | "default" ) ":" <statement>
+func (p *Parser) parseMatchStmt() (Statement, error) {
+	stmt := MatchStmt{ MatchToken: p.consume() }
+
+	subject, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Subject = subject
+
+	if !p.match(lexer.OPEN_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '{' after match expression but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '{'
+
+	for p.matchAny(lexer.CASE, lexer.DEFAULT) {
+		arm := MatchArm{ Token: p.consume() }
+		arm.IsDefault = arm.Token.Type == lexer.DEFAULT
+
+		if !arm.IsDefault {
+			if !p.match(lexer.IDENT) {
+				return nil, NewParserError(
+					p.peek(),
+					fmt.Sprintf("Expected a variant name after case but received '%v'", p.peek().Value),
+				)
+			}
+			arm.Variant = p.consume()
+
+			if p.match(lexer.OPEN_PAREN) {
+				p.consume() // '('
+
+				for p.match(lexer.IDENT) {
+					arm.Bindings = append(arm.Bindings, p.consume())
+
+					if !p.match(lexer.COMMA) {
+						break
+					}
+					p.consume() // ','
+				}
+
+				if !p.match(lexer.CLOSE_PAREN) {
+					return nil, NewParserError(
+						p.peek(),
+						fmt.Sprintf("Expected ')' after variant bindings but received '%v'", p.peek().Value),
+					)
+				}
+				p.consume() // ')'
+			}
+		}
+
+		if !p.match(lexer.COLON) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected ':' after %v but received '%v'", arm.Token.Value, p.peek().Value),
+			)
+		}
+		p.consume() // ':'
+
+		armStmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		arm.Stmt = armStmt
+
+		stmt.Arms = append(stmt.Arms, arm)
+	}
+
+	if !p.match(lexer.CLOSE_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected 'case', 'default' or '}' in match but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // '}'
+
+	return &stmt, nil
+}
+
+// <comptimeBlock> ::= "comptime" <blockStmt>
+func (p *Parser) parseComptimeBlock() (Statement, error) {
+	stmt := ComptimeBlockStmt{ ComptimeToken: p.consume() }
+
+	block, err := p.parseBlockStmt()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Statements = block.(*BlockStmt).Statements
+
+	return &stmt, nil
+}
+
+// <whileStmt> ::= "while" <expression> <statement>
+func (p *Parser) parseWhileStmt() (Statement, error) {
+	whileStmt := WhileStmt{ WhileToken: p.consume() }
 
-}
+	condition, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	whileStmt.Condition = condition
+
+	stmt, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	whileStmt.Stmt = stmt
 
-func (p *Parser) parseForStmt() {
+	return &whileStmt, nil
+}
 
+// For loops are not implemented yet. The 'for' token is consumed so parsing can recover.
+func (p *Parser) parseForStmt() (Statement, error) {
+	forToken := p.consume()
+	return nil, NewParserError(forToken, "for loops are not supported yet, use a while loop instead")
 }
 
 func (p *Parser) parseAssert() (Statement, error) {
@@ -284,6 +1093,39 @@ func (p *Parser) parseAssert() (Statement, error) {
 	return &stmt, nil
 }
 
+// <staticAssert> ::= "static_assert" <expression> [ "," STRING_LIT ] ";"
+func (p *Parser) parseStaticAssert() (Statement, error) {
+	stmt := StaticAssertStmt{ AssertToken: p.consume() }
+
+	expr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Expr = expr
+
+	if p.match(lexer.COMMA) {
+		p.consume() // ','
+
+		if !p.match(lexer.STRING_LIT) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected a message string after ',' but received '%v'", p.peek().Value),
+			)
+		}
+		stmt.Message.SetVal(p.consume())
+	}
+
+	if !p.match(lexer.SEMI) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ';' found %v", p.peek().Value),
+		)
+	}
+	p.consume() // ';'
+
+	return &stmt, nil
+}
+
 func (p *Parser) parseExpressionStmt() (Statement, error) {
 	exprStmt := ExpressionStmt{}
 
@@ -305,9 +1147,49 @@ func (p *Parser) parseExpressionStmt() (Statement, error) {
 	return &exprStmt, nil
 }
 
-// <expression> ::= <equality>
+// <expression> ::= <conditional>
 func (p *Parser) parseExpression() (Expression, error) {
-	return p.parseEquality()
+	return p.parseConditional()
+}
+
+// <conditional> ::= <equality> [ "?" <expression> ":" <conditional> ]
+func (p *Parser) parseConditional() (Expression, error) {
+	condition, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.match(lexer.QUESTION) {
+		return condition, nil
+	}
+
+	condExpr := ConditionalExpression{
+		Type: semantics.Undefined{},
+		Condition: condition,
+		Question: p.consume(),
+	}
+
+	then, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	condExpr.Then = then
+
+	if !p.match(lexer.COLON) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ':' in conditional expression but received '%v'", p.peek().Value),
+		)
+	}
+	p.consume() // ':'
+
+	otherwise, err := p.parseConditional()
+	if err != nil {
+		return nil, err
+	}
+	condExpr.Else = otherwise
+
+	return &condExpr, nil
 }
 
 // <equality> ::= <comparison> { ("==" | "!=") <comparison> }
@@ -385,16 +1267,16 @@ func (p *Parser) parseTerm() (Expression, error) {
 	return left, nil
 }
 
-// <factor> ::= <prefix> { ("*" | "/") <prefix> }
+// <factor> ::= <cast> { ("*" | "/") <cast> }
 func (p *Parser) parseFactor() (Expression, error) {
-	left, err := p.parsePrefix()
+	left, err := p.parseCast()
 	if err != nil {
 		return nil, err
 	}
 
 	for p.matchAny(lexer.STAR, lexer.F_SLASH) {
 		op := p.consume()
-		right, err := p.parsePrefix()
+		right, err := p.parseCast()
 		if err != nil {
 			return nil, err
 		}
@@ -410,6 +1292,31 @@ func (p *Parser) parseFactor() (Expression, error) {
 	return left, nil
 }
 
+// <cast> ::= <prefix> { "as" <type> }
+func (p *Parser) parseCast() (Expression, error) {
+	left, err := p.parsePrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.match(lexer.AS) {
+		castExpr := &CastExpression{
+			Left: left,
+			As: p.consume(),
+		}
+
+		target, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		castExpr.Type = target
+
+		left = castExpr
+	}
+
+	return left, nil
+}
+
 // <prefix> ::= ( "!" | "-" | "*" | "&" ) <prefix> | <postfix>
 func (p *Parser) parsePrefix() (Expression, error) {
 	if p.match(lexer.STAR) {
@@ -451,30 +1358,70 @@ func (p *Parser) parsePrefix() (Expression, error) {
 	return p.parsePostfix()
 }
 
-// <postfix> ::= <primary> { ( "++" | "--" | <arrayAccess> }
+// <postfix> ::= <primary> { ( "++" | "--" | <arrayAccess> | <memberAccess> }
 func (p *Parser) parsePostfix() (Expression, error) {
 	left, err := p.parsePrimary()
 	if err != nil {
 		return nil, err
 	}
+
+	if ident, isIdent := left.(*IdentExpression); isIdent && p.match(lexer.OPEN_PAREN) {
+		left, err = p.parseCall(ident)
+		if err != nil {
+			return nil, err
+		}
+	}
 	
 	// TODO: ++ and -- postfix operators
-	for p.match(lexer.OPEN_BRACKET) {
-		expr, err := p.parseArrayAccess()
+	for p.matchAny(lexer.OPEN_BRACKET, lexer.DOT) {
+		if p.match(lexer.DOT) {
+			memberExpr, err := p.parseMemberAccess()
+			if err != nil {
+				return nil, err
+			}
+			memberExpr.Left = left
+			left = memberExpr
+
+			if p.match(lexer.OPEN_PAREN) {
+				left, err = p.parseUnionConstructor(memberExpr)
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		expr, err := p.parseArrayAccess(left)
 		if err != nil {
 			return nil, err
 		}
-		arrayAccessExpr := expr.(*ArrayAccessExpression)
-		arrayAccessExpr.Left = left
-		left = arrayAccessExpr
+		left = expr
 	}
 
 	return left, nil
 }
 
-// <primary> ::= <literal> | ident | "(" <expression> ")" 
+// <primary> ::= <literal> | ident | "(" <expression> ")" | <typeInfo>
 func (p *Parser) parsePrimary() (Expression, error) {
-	if p.matchAny(lexer.UINT_64_LIT, lexer.TRUE_LIT, lexer.FALSE_LIT) {
+	if p.matchAny(lexer.SIZEOF, lexer.ALIGNOF) {
+		return p.parseTypeInfo()
+	} else if p.match(lexer.SYSCALL) {
+		return p.parseSyscall()
+	} else if p.match(lexer.NEW) {
+		return p.parseNew()
+	} else if p.match(lexer.SPAWN) {
+		return p.parseSpawn()
+	} else if p.match(lexer.COMPTIME) {
+		return p.parseComptime()
+	} else if p.matchAny(lexer.ATOMIC_LOAD, lexer.ATOMIC_STORE, lexer.ATOMIC_ADD, lexer.ATOMIC_CAS) {
+		return p.parseAtomic()
+	} else if p.matchAny(lexer.ARGC, lexer.ARGS, lexer.ENVP) {
+		processExpr := &ProcessValueExpression{
+			Type: semantics.Undefined{},
+			Token: p.consume(),
+		}
+		return processExpr, nil
+	} else if p.matchAny(lexer.UINT_64_LIT, lexer.TRUE_LIT, lexer.FALSE_LIT, lexer.NONE_LIT) {
 		litExpr := &LiteralExpression{
 			Type: p.getType(p.peek().Type),
 			Value: p.consume(),
@@ -497,18 +1444,45 @@ func (p *Parser) parsePrimary() (Expression, error) {
 	}
 }
 
-// <arrayAccess> := "[" <expression> "]"
-func (p *Parser) parseArrayAccess() (Expression, error) {
-	arrayAccessExpr := ArrayAccessExpression{ 
-		Type: semantics.Undefined{},
-		OpenBracket: p.consume(),
+// <arrayAccess> := "[" ( <expression> | [ <expression> ] ".." [ <expression> ] ) "]"
+func (p *Parser) parseArrayAccess(left Expression) (Expression, error) {
+	openBracket := p.consume() // '['
+
+	var index utils.Optional[Expression]
+	if !p.match(lexer.DOT_DOT) {
+		indexExpr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		index.SetVal(indexExpr)
 	}
 
-	indexExpr, err := p.parseExpression()
-	if err != nil {
-		return nil, err
+	var result Expression
+	if p.match(lexer.DOT_DOT) {
+		p.consume() // '..'
+		sliceExpr := &SliceExpression{
+			Type: semantics.Undefined{},
+			Left: left,
+			Low: index,
+			OpenBracket: openBracket,
+		}
+
+		if !p.match(lexer.CLOSE_BRACKET) {
+			high, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			sliceExpr.High.SetVal(high)
+		}
+		result = sliceExpr
+	} else {
+		result = &ArrayAccessExpression{
+			Type: semantics.Undefined{},
+			Left: left,
+			IndexExpr: index.Value(),
+			OpenBracket: openBracket,
+		}
 	}
-	arrayAccessExpr.IndexExpr = indexExpr
 
 	if !p.match(lexer.CLOSE_BRACKET) {
 		return nil, NewParserError(
@@ -518,7 +1492,251 @@ func (p *Parser) parseArrayAccess() (Expression, error) {
 	}
 	p.consume() // ']'
 
-	return &arrayAccessExpr, nil
+	return result, nil
+}
+
+// <memberAccess> ::= "." IDENT
+func (p *Parser) parseMemberAccess() (*MemberExpression, error) {
+	memberExpr := MemberExpression{
+		Type: semantics.Undefined{},
+		Dot: p.consume(),
+	}
+
+	if !p.match(lexer.IDENT) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected a member name after '.' but received '%v'", p.peek().Value),
+		)
+	}
+	memberExpr.Member = p.consume()
+
+	return &memberExpr, nil
+}
+
+// <unionConstructor> ::= <memberAccess> <arguments>
+func (p *Parser) parseUnionConstructor(callee *MemberExpression) (Expression, error) {
+	ctorExpr := UnionConstructorExpression{
+		Type: semantics.Undefined{},
+		Callee: callee,
+		OpenParen: p.peek(),
+	}
+
+	args, err := p.parseArguments("union constructor")
+	if err != nil {
+		return nil, err
+	}
+	ctorExpr.Args = args
+
+	return &ctorExpr, nil
+}
+
+// <new> ::= "new" <baseType> { "*" | "?" } [ "[" <expression> "]" ]
+func (p *Parser) parseNew() (Expression, error) {
+	newExpr := NewExpression{
+		Type: semantics.Undefined{},
+		NewToken: p.consume(),
+	}
+
+	valueType, err := p.parseBaseType()
+	if err != nil {
+		return nil, err
+	}
+
+	valueType, err = p.parseTypeModifiers(valueType, false)
+	if err != nil {
+		return nil, err
+	}
+	newExpr.ValueType = valueType
+
+	if p.match(lexer.OPEN_BRACKET) {
+		p.consume() // '['
+
+		count, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		newExpr.Count.SetVal(count)
+
+		if !p.match(lexer.CLOSE_BRACKET) {
+			return nil, NewParserError(
+				p.peek(),
+				fmt.Sprintf("Expected ']' after element count but received '%v'", p.peek().Value),
+			)
+		}
+		p.consume() // ']'
+	}
+
+	return &newExpr, nil
+}
+
+// <call> ::= IDENT <arguments>
+func (p *Parser) parseCall(callee *IdentExpression) (Expression, error) {
+	callExpr := CallExpression{
+		Type: semantics.Undefined{},
+		Callee: callee,
+		OpenParen: p.peek(),
+	}
+
+	args, err := p.parseArguments(callee.Ident.Value)
+	if err != nil {
+		return nil, err
+	}
+	callExpr.Args = args
+
+	return &callExpr, nil
+}
+
+// <syscall> ::= "syscall" <arguments>
+func (p *Parser) parseSyscall() (Expression, error) {
+	syscallExpr := SyscallExpression{
+		Type: semantics.Undefined{},
+		SyscallToken: p.consume(),
+	}
+
+	if !p.match(lexer.OPEN_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '(' after syscall but received '%v'", p.peek().Value),
+		)
+	}
+
+	args, err := p.parseArguments("syscall")
+	if err != nil {
+		return nil, err
+	}
+	syscallExpr.Args = args
+
+	return &syscallExpr, nil
+}
+
+// <spawn> ::= "spawn" <blockStmt>
+func (p *Parser) parseSpawn() (Expression, error) {
+	spawnExpr := SpawnExpression{ SpawnToken: p.consume() }
+
+	if !p.match(lexer.OPEN_CURLY) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '{' after spawn but received '%v'", p.peek().Value),
+		)
+	}
+
+	block, err := p.parseBlockStmt()
+	if err != nil {
+		return nil, err
+	}
+	spawnExpr.Block = block
+
+	return &spawnExpr, nil
+}
+
+// <comptime> ::= "comptime" <postfix>
+func (p *Parser) parseComptime() (Expression, error) {
+	comptimeExpr := ComptimeExpression{
+		Type: semantics.Undefined{},
+		ComptimeToken: p.consume(),
+	}
+
+	expr, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	comptimeExpr.Expr = expr
+
+	return &comptimeExpr, nil
+}
+
+// <atomic> ::= ( "atomic_load" | "atomic_store" | "atomic_add" | "atomic_cas" ) <arguments>
+func (p *Parser) parseAtomic() (Expression, error) {
+	atomicExpr := AtomicExpression{
+		Type: semantics.Undefined{},
+		Op: p.consume(),
+	}
+
+	if !p.match(lexer.OPEN_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '(' after %v but received '%v'", atomicExpr.Op.Value, p.peek().Value),
+		)
+	}
+
+	args, err := p.parseArguments(atomicExpr.Op.Value)
+	if err != nil {
+		return nil, err
+	}
+	atomicExpr.Args = args
+
+	return &atomicExpr, nil
+}
+
+// <arguments> ::= "(" [ <expression> { "," <expression> } ] ")"
+// The name of the construct taking the arguments is used in error messages.
+func (p *Parser) parseArguments(name string) ([]Expression, error) {
+	p.consume() // '('
+	args := []Expression{}
+
+	for !p.isAtEnd() && !p.match(lexer.CLOSE_PAREN) {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		if !p.match(lexer.COMMA) {
+			break
+		}
+		p.consume() // ','
+	}
+
+	if !p.match(lexer.CLOSE_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ')' after %v arguments but received '%v'", name, p.peek().Value),
+		)
+	}
+	p.consume() // ')'
+
+	return args, nil
+}
+
+// <typeInfo> ::= ( "sizeof" | "alignof" ) "(" ( <type> | <expression> ) ")"
+func (p *Parser) parseTypeInfo() (Expression, error) {
+	typeInfoExpr := TypeInfoExpression{
+		Type: semantics.Undefined{},
+		Operand: semantics.Undefined{},
+		Op: p.consume(),
+	}
+
+	if !p.match(lexer.OPEN_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected '(' after %v but received '%v'", typeInfoExpr.Op.Value, p.peek().Value),
+		)
+	}
+	p.consume() // '('
+
+	if p.isTypeStart() {
+		operand, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		typeInfoExpr.Operand = operand
+	} else {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		typeInfoExpr.Expr.SetVal(expr)
+	}
+
+	if !p.match(lexer.CLOSE_PAREN) {
+		return nil, NewParserError(
+			p.peek(),
+			fmt.Sprintf("Expected ')' after %v operand but received '%v'", typeInfoExpr.Op.Value, p.peek().Value),
+		)
+	}
+	p.consume() // ')'
+
+	return &typeInfoExpr, nil
 }
 
 // <groupExpr> ::= "(" <expression> ")"
@@ -550,9 +1768,13 @@ func (p *Parser) parseGroupExpr() (Expression, error) {
     return groupExpr, nil
 }
 
+// Returns the current token and moves past it. The closing EOF token is never moved past
+// so malformed programs cannot make the parser read beyond the tokens.
 func (p *Parser) consume() lexer.Token {
 	t := p.tokens[p.idx]
-	p.idx++
+	if t.Type != lexer.EOF {
+		p.idx++
+	}
 	return t
 }
 
@@ -575,16 +1797,42 @@ func (p *Parser) matchAny(tokenTypes ...lexer.TokenType) bool {
 	return false
 }
 
+// Reports whether the upcoming tokens form a variable declaration.
+// Types named by an identifier are told apart from expressions by parsing ahead
+// for a <type> followed by the declared identifier.
+func (p *Parser) isVarDeclStart() bool {
+	if p.isTypeStart() {
+		return true
+	}
+
+	if !p.match(lexer.IDENT) {
+		return false
+	}
+
+	start := p.idx
+	defer func() { p.idx = start }()
+
+	_, err := p.parseType()
+	return err == nil && p.match(lexer.IDENT)
+}
+
+// Reports whether the current token can start a <type>.
+func (p *Parser) isTypeStart() bool {
+	return p.matchAny(lexer.UINT_64, lexer.UINT_32, lexer.UINT_16, lexer.UINT_8, lexer.BOOL, lexer.TYPEOF)
+}
+
 func (p *Parser) isAtEnd() bool {
 	return p.tokens[p.idx].Type == lexer.EOF
 }
 
+// Skips the rest of a statement that failed to parse, up to and including its ';'.
+// Stops before the '}' of the enclosing block and at the end of the file.
 func (p *Parser) synchronize() {
-	for !p.isAtEnd() && p.matchAny(lexer.SEMI, lexer.CLOSE_CURLY, lexer.EOF) {
-		p.consume()
+	for !p.isAtEnd() && !p.match(lexer.CLOSE_CURLY) {
+		if p.consume().Type == lexer.SEMI {
+			return
+		}
 	}
-
-	p.consume()
 }
 
 func (p *Parser) getType(tokenType lexer.TokenType) semantics.Type {
@@ -605,6 +1853,8 @@ func (p *Parser) getType(tokenType lexer.TokenType) semantics.Type {
 		fallthrough
 	case lexer.FALSE_LIT:
 		return semantics.Bool{}
+	case lexer.NONE_LIT:
+		return semantics.NoneLiteral{}
 	}
 
 	return semantics.Undefined{}
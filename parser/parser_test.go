@@ -0,0 +1,202 @@
+package parser_test
+
+import (
+	"clovis/compiler"
+	"clovis/diagnostics"
+	"clovis/parser"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Rewrites the golden files with the assembly the compiler currently emits.
+var update = flag.Bool("update", false, "rewrite the golden assembly in testdata")
+
+// Compiles the program src up to emit. Options such as the target can be changed by configure.
+func compile(t *testing.T, src string, emit compiler.Emit, configure ...func(*compiler.Options)) *compiler.Result {
+	t.Helper()
+
+	opts := compiler.Options{
+		Main: "main.clv",
+		Sources: map[string]string{ "main.clv": src },
+		Emit: emit,
+	}
+	for _, c := range configure {
+		c(&opts)
+	}
+
+	result, err := compiler.Compile(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	return result
+}
+
+// Returns the assembly of src and fails if the program has any diagnostic.
+func emit(t *testing.T, src string, configure ...func(*compiler.Options)) string {
+	t.Helper()
+
+	result := compile(t, src, compiler.EmitASM, configure...)
+	if len(result.Diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics but received %v", result.Diagnostics)
+	}
+
+	return result.ASM
+}
+
+// Checks src and fails unless it reports exactly the given codes in order.
+func expectCodes(t *testing.T, src string, codes ...diagnostics.Code) []*diagnostics.Diagnostic {
+	t.Helper()
+
+	result := compile(t, src, compiler.EmitAST)
+	received := []diagnostics.Code{}
+	for _, d := range result.Diagnostics {
+		received = append(received, d.Code)
+	}
+
+	if strings.Join(codeStrings(received), ",") != strings.Join(codeStrings(codes), ",") {
+		t.Fatalf("Expected codes %v but received %v", codes, result.Diagnostics)
+	}
+
+	return result.Diagnostics
+}
+
+// Fails unless src checks without diagnostics.
+func expectValid(t *testing.T, src string) {
+	t.Helper()
+	expectCodes(t, src)
+}
+
+func codeStrings(codes []diagnostics.Code) []string {
+	s := []string{}
+	for _, code := range codes {
+		s = append(s, string(code))
+	}
+
+	return s
+}
+
+// Compares the assembly emitted for testdata/<name>.clv with testdata/<name>.asm.
+// Run the tests with -update to rewrite the golden file.
+func golden(t *testing.T, name string, configure ...func(*compiler.Options)) string {
+	t.Helper()
+
+	src, err := os.ReadFile(filepath.Join("testdata", name + ".clv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	asm := emit(t, string(src), configure...)
+
+	goldenPath := filepath.Join("testdata", name + ".asm")
+	if *update {
+		if err := os.WriteFile(goldenPath, []byte(asm), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if asm != string(want) {
+		t.Errorf("Assembly of %v differs from %v, rerun with -update if the change is intended:\n%v", name, goldenPath, asm)
+	}
+
+	return asm
+}
+
+// Returns the assembly of the program testdata/name.clv. Unlike golden it compares nothing,
+// for programs whose output is checked line by line.
+func testdata(t *testing.T, name string, configure ...func(*compiler.Options)) string {
+	t.Helper()
+
+	src, err := os.ReadFile(filepath.Join("testdata", name + ".clv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return emit(t, string(src), configure...)
+}
+
+// Returns the lines of asm from the first line containing from up to the next line containing to.
+func between(t *testing.T, asm string, from string, to string) string {
+	t.Helper()
+
+	start := strings.Index(asm, from)
+	if start == -1 {
+		t.Fatalf("Expected %q in the assembly:\n%v", from, asm)
+	}
+
+	end := strings.Index(asm[start:], to)
+	if end == -1 {
+		t.Fatalf("Expected %q after %q in the assembly:\n%v", to, from, asm)
+	}
+
+	return asm[start:start + end + len(to)]
+}
+
+var stackAdjustment = regexp.MustCompile(`(?m)^(sub|add) rsp, (\d+)$`)
+
+// Returns how many bytes code reserves on the stack without releasing them.
+func stackGrowth(code string) int {
+	growth := 0
+	for _, match := range stackAdjustment.FindAllStringSubmatch(code, -1) {
+		n, _ := strconv.Atoi(match[2])
+		if match[1] == "sub" {
+			growth += n
+		} else {
+			growth -= n
+		}
+	}
+
+	return growth
+}
+
+// Fails unless asm contains every line of want.
+func expectLines(t *testing.T, asm string, want ...string) {
+	t.Helper()
+
+	lines := map[string]bool{}
+	for _, line := range strings.Split(asm, "\n") {
+		lines[line] = true
+	}
+
+	for _, line := range want {
+		if !lines[line] {
+			t.Errorf("Expected the line %q in the assembly:\n%v", line, asm)
+		}
+	}
+}
+
+// Returns the statements of the checked program src and fails if it has any diagnostic.
+func check(t *testing.T, src string) []parser.Statement {
+	t.Helper()
+
+	result := compile(t, src, compiler.EmitAST)
+	if len(result.Diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics but received %v", result.Diagnostics)
+	}
+
+	return result.Modules[len(result.Modules) - 1].Stmts
+}
+
+// Fails unless parsing src reports a syntax error.
+func expectSyntaxError(t *testing.T, src string) {
+	t.Helper()
+
+	result := compile(t, src, compiler.EmitTokens)
+	for _, d := range result.Diagnostics {
+		if d.Code == diagnostics.SyntaxError {
+			return
+		}
+	}
+
+	t.Fatalf("Expected a syntax error but received %v", result.Diagnostics)
+}
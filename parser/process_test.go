@@ -0,0 +1,34 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExitSyntax(t *testing.T) {
+	expectSyntaxError(t, `exit 1;`)
+	expectSyntaxError(t, `exit(1)`)
+	expectSyntaxError(t, `argc = 2;`)
+}
+
+func TestExitCodegen(t *testing.T) {
+	asm := golden(t, "process")
+
+	// Every exit goes through the common exit path.
+	expectLines(t, asm, "mov rdi, rax", "jmp __clovis_exit", "__clovis_exit:", "mov rax, 231")
+}
+
+// argc and argv are only saved by the entry when the program reads a process value.
+func TestProcessValuesSavedWhenUsed(t *testing.T) {
+	asm := emit(t, `uint64 n = argc;`)
+	expectLines(t, asm, "_start:", "mov QWORD [rel __clovis_argc], rax", "mov QWORD [rel __clovis_argv], rax", "mov rbp, rsp")
+	expectLines(t, asm, "__clovis_argc: resq 1")
+
+	asm = emit(t, `exit(3);`)
+	if strings.Contains(asm, "__clovis_argc") || strings.Contains(asm, "__clovis_argv") {
+		t.Errorf("Expected argc and argv not to be saved but received:\n%v", asm)
+	}
+
+	asm = emit(t, `uint64 n = argc;`, library)
+	expectLines(t, asm, "push rbx", "mov QWORD [rel __clovis_argc], rdi", "mov QWORD [rel __clovis_argv], rsi", "mov rbp, rsp")
+}
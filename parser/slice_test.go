@@ -0,0 +1,35 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSliceSyntax(t *testing.T) {
+	expectSyntaxError(t, `uint8[4] a; uint8[] s = a[0..;`)
+	expectSyntaxError(t, `uint8[4] a; uint8[] s = a[0...2];`)
+}
+
+func TestSliceBoundsChecks(t *testing.T) {
+	asm := golden(t, "slice")
+	expectLines(t, asm, "cmp rcx, rdx", "mov rdx, 8")
+
+	// Failed checks say why before exiting.
+	fail := between(t, asm, "lea rsi, [rel __clovis_msg_slice_bounds]", "mov rdi, 1")
+	expectLines(t, fail, "mov rdx, __clovis_msg_slice_bounds_len", "mov rdi, 2", "syscall", "mov rax, 231")
+	expectLines(t, asm, `__clovis_msg_slice_bounds: db "clovis: slice bounds out of range", 10`)
+}
+
+func TestSliceIndexCheck(t *testing.T) {
+	asm := emit(t, `uint8[4] a; uint8[] s = a[..]; uint8 x = s[1]; s[2] = x;`)
+	expectLines(t, asm,
+		"; ArrayAccessExpression rvalue type = UINT8",
+		"; ArrayAccessExpression lvalue type = UINT8",
+		"lea rsi, [rel __clovis_msg_index_bounds]",
+	)
+
+	// The message is stored once for both checks.
+	if count := strings.Count(asm, "__clovis_msg_index_bounds: db"); count != 1 {
+		t.Errorf("Expected the message to be stored once but found it %v times", count)
+	}
+}
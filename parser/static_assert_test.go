@@ -0,0 +1,36 @@
+package parser_test
+
+import (
+	"clovis/diagnostics"
+	"strings"
+	"testing"
+)
+
+func TestStaticAssertSyntax(t *testing.T) {
+	expectSyntaxError(t, `static_assert;`)
+	expectSyntaxError(t, `static_assert true, ;`)
+	expectSyntaxError(t, `static_assert true`)
+}
+
+// Orderings are folded as unsigned comparisons like the code emitted for them at runtime.
+// Every type is unsigned, so a difference that wraps around is a large value, not a negative one.
+func TestStaticAssertUnsignedOrdering(t *testing.T) {
+	expectCodes(t, `static_assert 10 - 20 < 0;`, diagnostics.StaticAssertFailed)
+	expectValid(t, `static_assert 10 - 20 > 0;`)
+	expectValid(t, `static_assert 0 - 1 >= 0; static_assert 0 <= 0 - 1;`)
+	expectValid(t, `bool b = comptime (10 - 20 > 0); static_assert comptime (10 - 20 > 0);`)
+
+	asm := emit(t, `assert 10 - 20 > 0;`)
+	expectLines(t, between(t, asm, "AssertStmt", "cmp al, 1"), "cmp rax, rbx", "seta al")
+
+	asm = emit(t, `uint64 a = 1; uint64 b = 2; bool c = a < b; bool d = a <= b; bool e = a >= b;`)
+	expectLines(t, asm, "setb al", "setbe al", "setae al")
+}
+
+func TestStaticAssertEmitsNothing(t *testing.T) {
+	asm := emit(t, `static_assert 1 == 1;`)
+	code := between(t, asm, "StaticAssertStmt", "Emitter.End()")
+	if strings.Count(code, "\n") != 2 {
+		t.Errorf("Expected no code for static_assert:\n%v", code)
+	}
+}
@@ -0,0 +1,33 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSwitchSyntax(t *testing.T) {
+	expectSyntaxError(t, `uint8 x; switch x { case 1 x = 1; }`)
+	expectSyntaxError(t, `uint8 x; switch x { x = 1; }`)
+}
+
+func TestSwitchJumpTable(t *testing.T) {
+	asm := golden(t, "switch_table")
+
+	// The table lives in .rodata, after the code that indexes it.
+	rodata := strings.Index(asm, "section .rodata")
+	table := strings.Index(asm, "dd ..@L")
+	if rodata == -1 || table < rodata {
+		t.Errorf("Expected the jump table in .rodata:\n%v", asm)
+	}
+
+	if growth := stackGrowth(between(t, asm, "SwitchStmt", "; Emitter.End()")); growth != 0 {
+		t.Errorf("Expected the arms to release their locals but the stack grows by %v", growth)
+	}
+}
+
+func TestSwitchCompareChain(t *testing.T) {
+	asm := golden(t, "switch_chain")
+	if strings.Contains(asm, "jmp rax") {
+		t.Errorf("Expected sparse cases to be compared in order:\n%v", asm)
+	}
+}
@@ -0,0 +1,53 @@
+package parser_test
+
+import (
+	"clovis/compiler"
+	"clovis/diagnostics"
+	"strings"
+	"testing"
+)
+
+func TestSyntaxErrorRecovery(t *testing.T) {
+	result := compile(t, "uint8 x = ;\nuint8 y = 2;\nuint8 z = ;\n", compiler.EmitTokens)
+	if len(result.Diagnostics) != 2 {
+		t.Fatalf("Expected both errors to be reported but received %v", result.Diagnostics)
+	}
+
+	for i, line := range []int{ 1, 3 } {
+		if d := result.Diagnostics[i]; d.Code != diagnostics.SyntaxError || d.Span.Start.Line != line {
+			t.Errorf("Expected a syntax error on line %v but received %v", line, d)
+		}
+	}
+}
+
+// Malformed programs are reported without crashing the parser.
+func TestSyntaxErrorsAtTheEnd(t *testing.T) {
+	for _, src := range []string{
+		`{ defer; }`,
+		`{ uint8 x = }`,
+		`}`,
+		`{`,
+		`uint8 x = (1 + ;`,
+		`if`,
+		`switch x { case`,
+		`match x { case A(`,
+		`union U {`,
+		`uint32* p = new uint32[1;`,
+		`uint8 x = sizeof(`,
+		`enum E : uint8 { A =`,
+		`asm (`,
+		`extern uint8 f(uint8`,
+		`import`,
+		`for i = 0 .. 4 { }`,
+	} {
+		expectSyntaxError(t, src)
+	}
+}
+
+// For loops are rejected instead of hanging the parser, and the statements after them are still parsed.
+func TestForLoopsUnsupported(t *testing.T) {
+	d := expectCodes(t, `for i = 0 .. 4 { } uint64 x = ;`, diagnostics.SyntaxError, diagnostics.SyntaxError)
+	if !strings.Contains(d[0].Message, "while") {
+		t.Errorf("Expected a hint to use while but received %v", d[0])
+	}
+}
@@ -0,0 +1,15 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestSyscallSyntax(t *testing.T) {
+	expectSyntaxError(t, `uint64 r = syscall;`)
+	expectSyntaxError(t, `uint64 r = syscall(1, 2;`)
+}
+
+func TestSyscallRegisters(t *testing.T) {
+	asm := golden(t, "syscall")
+	expectLines(t, asm, "pop r10", "pop rdx", "pop rsi", "pop rdi", "pop rax", "syscall")
+}
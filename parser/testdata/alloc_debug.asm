@@ -0,0 +1,191 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8_PTR ident = big offset = 8 size = 8
+sub rsp, 8
+; NewExpression: type = UINT8
+; LiteralExpression: type = UINT_LIT value = 100000
+mov rax, 100000
+mov rcx, 1
+mul rcx
+jc __clovis_out_of_memory
+mov rdi, rax
+call __clovis_alloc
+mov QWORD [rbp - 8], rax
+; ------------------------- DeleteStmt ------------------------- 
+; IdentExpression rvalue type = UINT8_PTR
+mov rax, QWORD [rbp - 8]
+mov rdi, rax
+call __clovis_free
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT32_PTR ident = small offset = 16 size = 8
+sub rsp, 8
+; NewExpression: type = UINT32
+mov rdi, 4
+call __clovis_alloc
+mov QWORD [rbp - 16], rax
+; ------------------------- DeleteStmt ------------------------- 
+; IdentExpression rvalue type = UINT32_PTR
+mov rax, QWORD [rbp - 16]
+mov rdi, rax
+call __clovis_free
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+cmp QWORD [rel __clovis_live_allocs], 0
+je .no_leaks
+lea rsi, [rel __clovis_msg_leak]
+mov rdx, __clovis_msg_leak_len
+jmp __clovis_fail
+.no_leaks:
+mov rax, 231
+syscall
+
+; Allocator runtime
+__clovis_alloc:
+add rdi, 15
+jc __clovis_out_of_memory
+and rdi, -16
+cmp rdi, 16
+jae .search_start
+mov rdi, 16
+.search_start:
+lea rcx, [rel __clovis_free_list]
+.search:
+mov rax, QWORD [rcx]
+test rax, rax
+jz .fresh
+cmp QWORD [rax], rdi
+jae .reuse
+lea rcx, [rax + 16]
+jmp .search
+.reuse:
+mov rdx, QWORD [rax + 16]
+mov QWORD [rcx], rdx
+mov rdi, QWORD [rax]
+jmp .chunk_block
+.fresh:
+mov rdx, rdi
+add rdx, 16
+jc __clovis_out_of_memory
+cmp rdx, 65536
+ja .mapped_block
+mov rax, QWORD [rel __clovis_heap_cur]
+mov rsi, QWORD [rel __clovis_heap_end]
+sub rsi, rax
+cmp rsi, rdx
+jae .bump
+push rdi
+push rdx
+mov rsi, 65536
+call __clovis_mmap
+pop rdx
+pop rdi
+lea rsi, [rax + 65536]
+mov QWORD [rel __clovis_heap_end], rsi
+.bump:
+lea rsi, [rax + rdx]
+mov QWORD [rel __clovis_heap_cur], rsi
+mov QWORD [rax], rdi
+.chunk_block:
+mov QWORD [rax + 8], 0xa110c
+jmp .zero
+.mapped_block:
+push rdi
+mov rsi, rdx
+call __clovis_mmap
+pop rdi
+mov QWORD [rax], rdi
+mov QWORD [rax + 8], 0xa11aa
+.zero:
+inc QWORD [rel __clovis_live_allocs]
+lea rdx, [rax + 16]
+push rdx
+mov rcx, rdi
+mov rdi, rdx
+xor eax, eax
+rep stosb
+pop rax
+ret
+
+__clovis_free:
+test rdi, rdi
+jz .done
+sub rdi, 16
+mov rax, QWORD [rdi + 8]
+cmp rax, 0xf4eed
+je .double_free
+cmp rax, 0xa110c
+je .valid
+cmp rax, 0xa11aa
+je .valid
+lea rsi, [rel __clovis_msg_invalid_free]
+mov rdx, __clovis_msg_invalid_free_len
+jmp __clovis_fail
+.double_free:
+lea rsi, [rel __clovis_msg_double_free]
+mov rdx, __clovis_msg_double_free_len
+jmp __clovis_fail
+.valid:
+dec QWORD [rel __clovis_live_allocs]
+cmp QWORD [rdi + 8], 0xa11aa
+je .unmap
+mov QWORD [rdi + 8], 0xf4eed
+mov rax, QWORD [rel __clovis_free_list]
+mov QWORD [rdi + 16], rax
+mov QWORD [rel __clovis_free_list], rdi
+.done:
+ret
+.unmap:
+mov rsi, QWORD [rdi]
+add rsi, 16
+mov QWORD [rdi + 8], 0xf4eed
+add rdi, 4096
+sub rsi, 4096
+mov rax, 11
+syscall
+ret
+
+__clovis_mmap:
+mov rax, 9
+xor edi, edi
+mov rdx, 3
+mov r10, 0x22
+mov r8, -1
+xor r9d, r9d
+syscall
+cmp rax, -4096
+ja __clovis_out_of_memory
+ret
+__clovis_out_of_memory:
+lea rsi, [rel __clovis_msg_out_of_memory]
+mov rdx, __clovis_msg_out_of_memory_len
+
+__clovis_fail:
+mov rax, 1
+mov rdi, 2
+syscall
+mov rax, 231
+mov rdi, 1
+syscall
+
+section .rodata
+__clovis_msg_out_of_memory: db "clovis: out of memory", 10
+__clovis_msg_out_of_memory_len equ $ - __clovis_msg_out_of_memory
+__clovis_msg_double_free: db "clovis: double free detected", 10
+__clovis_msg_double_free_len equ $ - __clovis_msg_double_free
+__clovis_msg_invalid_free: db "clovis: delete of a pointer not returned by new", 10
+__clovis_msg_invalid_free_len equ $ - __clovis_msg_invalid_free
+__clovis_msg_leak: db "clovis: memory leaked at exit", 10
+__clovis_msg_leak_len equ $ - __clovis_msg_leak
+
+section .bss
+__clovis_free_list: resq 1
+__clovis_heap_cur: resq 1
+__clovis_heap_end: resq 1
+__clovis_live_allocs: resq 1
@@ -0,0 +1,24 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = x offset = 8 size = 8
+sub rsp, 8
+; LiteralExpression: type = UINT_LIT value = 1
+mov rax, 1
+mov QWORD [rbp - 8], rax
+; ------------------------- AsmStmt ------------------------- 
+push r12
+mov r12, QWORD [rbp - 8]
+add r12, 1
+mov QWORD [rbp - 8], r12
+pop r12
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
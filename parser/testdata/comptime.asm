@@ -0,0 +1,41 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- ComptimeBlockStmt ------------------------- 
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = x offset = 8 size = 8
+sub rsp, 8
+; ComptimeExpression: type = UINT_LIT
+mov rax, 42
+mov QWORD [rbp - 8], rax
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8 ident = s offset = 9 size = 1
+sub rsp, 1
+; ArrayAccessExpression rvalue type = UINT8
+; IdentExpression lvalue type = UINT8_ARRAY(4)
+lea rax, [rel __clovis_comptime_squares_1]
+push rax
+; LiteralExpression: type = UINT_LIT value = 3
+mov rax, 3
+mov rbx, 1
+mul rbx
+pop rbx
+movzx eax, BYTE [rbx + rax]
+mov BYTE [rbp - 9], al
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
+
+section .data
+align 1, db 0
+__clovis_comptime_squares_1:
+db 0x00, 0x01, 0x04, 0x09
+align 1, db 0
+__clovis_comptime_i_2:
+db 0x04
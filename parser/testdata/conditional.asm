@@ -0,0 +1,34 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = BOOL ident = b offset = 1 size = 1
+sub rsp, 1
+; LiteralExpression: type = BOOL value = 1
+mov rax, 1
+mov BYTE [rbp - 1], al
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8 ident = y offset = 2 size = 1
+sub rsp, 1
+; ConditionalExpression: type = UINT_LIT
+; IdentExpression rvalue type = BOOL
+movzx eax, BYTE [rbp - 1]
+cmp al, 1
+jne .L01
+; LiteralExpression: type = UINT_LIT value = 10
+mov rax, 10
+jmp .L02
+.L01:
+; LiteralExpression: type = UINT_LIT value = 20
+mov rax, 20
+.L02:
+mov BYTE [rbp - 2], al
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
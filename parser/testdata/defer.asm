@@ -0,0 +1,45 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- BlockStmt: Size = 8 -------------------------
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = x offset = 8 size = 8
+sub rsp, 8
+; ------------------------- DeferStmt ------------------------- 
+; ------------------------- DeferStmt ------------------------- 
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 8]
+push rax
+; LiteralExpression: type = UINT_LIT value = 333
+mov rax, 333
+pop rbx
+mov QWORD [rbx], rax
+; deferred statement 1
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 8]
+push rax
+; LiteralExpression: type = UINT_LIT value = 222
+mov rax, 222
+pop rbx
+mov QWORD [rbx], rax
+; deferred statement 0
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 8]
+push rax
+; LiteralExpression: type = UINT_LIT value = 111
+mov rax, 111
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 8
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
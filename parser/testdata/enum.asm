@@ -0,0 +1,19 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- EnumDeclStmt: Color -------------------------
+; ------------------------- VarDeclStmt -------------------------
+; type = Color ident = c offset = 1 size = 1
+sub rsp, 1
+; MemberExpression: type = Color member = Blue
+mov rax, 10
+mov BYTE [rbp - 1], al
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
@@ -0,0 +1,97 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- ExternDeclStmt: printf -------------------------
+extern $printf
+; ------------------------- ExternDeclStmt: seven -------------------------
+extern $seven
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8_ARRAY(4) ident = msg offset = 4 size = 4
+sub rsp, 4
+sub rsp, 16
+mov rax, rsp
+; CallExpression: printf
+push rax
+mov rbx, rsp
+and rsp, -16
+push rbx
+push rbx
+; LiteralExpression: type = UINT_LIT value = 1
+mov rax, 1
+push rax
+; ReferenceExpression type = UINT8_PTR
+; ArrayAccessExpression lvalue type = UINT8
+; IdentExpression lvalue type = UINT8_ARRAY(4)
+lea rax, [rbp - 4]
+push rax
+; LiteralExpression: type = UINT_LIT value = 0
+mov rax, 0
+mov rbx, 1
+mul rbx
+pop rbx
+lea rax, [rbx + rax]
+push rax
+pop rdi
+pop rsi
+xor eax, eax
+call $printf wrt ..plt
+mov rsp, QWORD [rsp]
+mov eax, eax
+pop rbx
+mov DWORD [rbx], eax
+mov rax, rbx
+add rsp, 16
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8 ident = r offset = 5 size = 1
+sub rsp, 1
+lea rax, [rbp - 5]
+; CallExpression: seven
+push rax
+mov rbx, rsp
+and rsp, -16
+push rbx
+push rbx
+sub rsp, 8
+; LiteralExpression: type = UINT_LIT value = 7
+mov rax, 7
+push rax
+; LiteralExpression: type = UINT_LIT value = 6
+mov rax, 6
+push rax
+; LiteralExpression: type = UINT_LIT value = 5
+mov rax, 5
+push rax
+; LiteralExpression: type = UINT_LIT value = 4
+mov rax, 4
+push rax
+; LiteralExpression: type = UINT_LIT value = 3
+mov rax, 3
+push rax
+; LiteralExpression: type = UINT_LIT value = 2
+mov rax, 2
+push rax
+; LiteralExpression: type = UINT_LIT value = 1
+mov rax, 1
+push rax
+pop rdi
+pop rsi
+pop rdx
+pop rcx
+pop r8
+pop r9
+call $seven wrt ..plt
+add rsp, 16
+mov rsp, QWORD [rsp]
+movzx eax, al
+pop rbx
+mov BYTE [rbx], al
+mov rax, rbx
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
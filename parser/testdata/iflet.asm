@@ -0,0 +1,54 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT32_OPTIONAL ident = b offset = 8 size = 8
+sub rsp, 8
+lea rax, [rbp - 8]
+; OptionalExpression: type = UINT32_OPTIONAL
+push rax
+; LiteralExpression: type = UINT_LIT value = 5
+mov rax, 5
+mov rbx, QWORD [rsp]
+mov DWORD [rbx + 4], eax
+pop rax
+mov BYTE [rax], 1
+; ------------------------- WhileStmt ------------------------- 
+.L01:
+; LiteralExpression: type = BOOL value = 1
+mov rax, 1
+cmp al, 1
+jne .L02
+; ------------------------- BlockStmt: Size = 0 -------------------------
+; ------------------------- IfLetStmt ------------------------- 
+; IdentExpression rvalue type = UINT32_OPTIONAL
+lea rax, [rbp - 8]
+mov rbx, rax
+cmp BYTE [rbx], 0
+je .L03
+; binding v
+sub rsp, 4
+mov eax, DWORD [rbx + 4]
+mov DWORD [rbp - 12], eax
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT32 ident = k offset = 16 size = 4
+sub rsp, 4
+; IdentExpression rvalue type = UINT32
+mov eax, DWORD [rbp - 12]
+mov DWORD [rbp - 16], eax
+add rsp, 8
+jmp .L04
+.L03:
+.L04:
+add rsp, 0
+jmp .L01
+.L02:
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
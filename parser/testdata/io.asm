@@ -0,0 +1,220 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8_ARRAY(16) ident = buf offset = 16 size = 16
+sub rsp, 16
+; ------------------------- VarDeclStmt -------------------------
+; type = IoResult ident = n offset = 32 size = 16
+sub rsp, 16
+lea rax, [rbp - 32]
+; CallExpression: read
+push rax
+; LiteralExpression: type = UINT_LIT value = 0
+mov rax, 0
+push rax
+; IdentExpression rvalue type = UINT8_ARRAY(16)
+lea rax, [rbp - 16]
+mov rdx, 16
+mov rsi, rax
+pop rdi
+mov rax, 0
+syscall
+xor edx, edx
+cmp rax, -4096
+jbe .L01
+neg rax
+mov rdx, rax
+.L01:
+pop rbx
+test rdx, rdx
+jnz .L02
+mov BYTE [rbx], 0
+mov QWORD [rbx + 8], rax
+jmp .L03
+.L02:
+mov BYTE [rbx], 1
+mov QWORD [rbx + 8], rdx
+.L03:
+mov rax, rbx
+sub rsp, 16
+mov rax, rsp
+; CallExpression: write
+push rax
+; LiteralExpression: type = UINT_LIT value = 1
+mov rax, 1
+push rax
+; SliceExpression type = UINT8_SLICE
+; IdentExpression rvalue type = UINT8_ARRAY(16)
+lea rax, [rbp - 16]
+mov rdx, 16
+push rax
+push rdx
+; LiteralExpression: type = UINT_LIT value = 0
+mov rax, 0
+push rax
+; LiteralExpression: type = UINT_LIT value = 4
+mov rax, 4
+mov rcx, rax
+pop rax
+pop rdx
+pop rbx
+cmp rcx, rdx
+ja .L05
+cmp rax, rcx
+jbe .L04
+.L05:
+lea rsi, [rel __clovis_msg_slice_bounds]
+mov rdx, __clovis_msg_slice_bounds_len
+mov rax, 1
+mov rdi, 2
+syscall
+mov rax, 231
+mov rdi, 1
+syscall
+.L04:
+sub rcx, rax
+imul rax, rax, 1
+add rax, rbx
+mov rdx, rcx
+mov rsi, rax
+pop rdi
+mov rax, 1
+syscall
+xor edx, edx
+cmp rax, -4096
+jbe .L06
+neg rax
+mov rdx, rax
+.L06:
+pop rbx
+test rdx, rdx
+jnz .L07
+mov BYTE [rbx], 0
+mov QWORD [rbx + 8], rax
+jmp .L08
+.L07:
+mov BYTE [rbx], 1
+mov QWORD [rbx + 8], rdx
+.L08:
+mov rax, rbx
+add rsp, 16
+sub rsp, 16
+mov rax, rsp
+; CallExpression: close
+push rax
+; LiteralExpression: type = UINT_LIT value = 0
+mov rax, 0
+mov rdi, rax
+mov rax, 3
+syscall
+xor edx, edx
+cmp rax, -4096
+jbe .L09
+neg rax
+mov rdx, rax
+.L09:
+pop rbx
+test rdx, rdx
+jnz .L10
+mov BYTE [rbx], 0
+mov QWORD [rbx + 8], rax
+jmp .L11
+.L10:
+mov BYTE [rbx], 1
+mov QWORD [rbx + 8], rdx
+.L11:
+mov rax, rbx
+add rsp, 16
+; ------------------------- VarDeclStmt -------------------------
+; type = IoResult ident = x offset = 48 size = 16
+sub rsp, 16
+lea rax, [rbp - 48]
+; CallExpression: read_uint
+push rax
+call __clovis_read_uint
+pop rbx
+test rdx, rdx
+jnz .L12
+mov BYTE [rbx], 0
+mov QWORD [rbx + 8], rax
+jmp .L13
+.L12:
+mov BYTE [rbx], 1
+mov QWORD [rbx + 8], rdx
+.L13:
+mov rax, rbx
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
+
+; read_uint runtime
+__clovis_read_uint:
+sub rsp, 16
+xor r8d, r8d
+xor r9d, r9d
+.next:
+mov rax, 0
+mov rdi, 0
+mov rsi, rsp
+mov rdx, 1
+syscall
+cmp rax, -4096
+ja .error
+test rax, rax
+jz .end
+movzx eax, BYTE [rsp]
+sub eax, '0'
+cmp eax, 9
+ja .not_digit
+mov rcx, rax
+mov rax, r8
+mov rdx, 10
+mul rdx
+jc .overflow
+add rax, rcx
+jc .overflow
+mov r8, rax
+inc r9
+jmp .next
+.not_digit:
+test r9, r9
+jnz .end
+movzx eax, BYTE [rsp]
+cmp eax, ' '
+je .next
+cmp eax, 9
+jb .invalid
+cmp eax, 13
+jbe .next
+.invalid:
+mov rdx, 22
+jmp .failed
+.overflow:
+mov rdx, 34
+jmp .failed
+.error:
+neg rax
+mov rdx, rax
+jmp .failed
+.end:
+test r9, r9
+jz .invalid
+mov rax, r8
+xor edx, edx
+add rsp, 16
+ret
+.failed:
+xor eax, eax
+add rsp, 16
+ret
+
+section .rodata
+__clovis_msg_slice_bounds: db "clovis: slice bounds out of range", 10
+__clovis_msg_slice_bounds_len equ $ - __clovis_msg_slice_bounds
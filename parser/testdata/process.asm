@@ -0,0 +1,57 @@
+section .text
+global _start
+
+_start:
+mov rax, QWORD [rsp]
+mov QWORD [rel __clovis_argc], rax
+lea rax, [rsp + 8]
+mov QWORD [rel __clovis_argv], rax
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = n offset = 8 size = 8
+sub rsp, 8
+; ProcessValueExpression: argc
+mov rax, QWORD [rel __clovis_argc]
+mov rbx, QWORD [rel __clovis_argv]
+mov QWORD [rbp - 8], rax
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8_PTR_SLICE ident = e offset = 24 size = 16
+sub rsp, 16
+; ProcessValueExpression: envp
+mov rax, QWORD [rel __clovis_argc]
+mov rbx, QWORD [rel __clovis_argv]
+lea rax, [rbx + rax * 8 + 8]
+mov rdx, 0
+.L01:
+cmp QWORD [rax + rdx * 8], 0
+je .L02
+inc rdx
+jmp .L01
+.L02:
+mov QWORD [rbp - 24], rax
+mov QWORD [rbp - 24 + 8], rdx
+; ------------------------- ExitStmt ------------------------- 
+; BinaryExpression: type = UINT64 op = +
+; MemberExpression: type = UINT64 member = len
+; IdentExpression rvalue type = UINT8_PTR_SLICE
+mov rdx, QWORD [rbp - 24 + 8]
+mov rax, QWORD [rbp - 24]
+mov rax, rdx
+push rax
+; IdentExpression rvalue type = UINT64
+mov rax, QWORD [rbp - 8]
+pop rbx
+add rax, rbx
+mov rdi, rax
+jmp __clovis_exit
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
+
+section .bss
+__clovis_argc: resq 1
+__clovis_argv: resq 1
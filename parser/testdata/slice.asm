@@ -0,0 +1,66 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8_ARRAY(8) ident = buffer offset = 8 size = 8
+sub rsp, 8
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8_SLICE ident = head offset = 24 size = 16
+sub rsp, 16
+; SliceExpression type = UINT8_SLICE
+; IdentExpression rvalue type = UINT8_ARRAY(8)
+lea rax, [rbp - 8]
+mov rdx, 8
+push rax
+push rdx
+; LiteralExpression: type = UINT_LIT value = 2
+mov rax, 2
+push rax
+; LiteralExpression: type = UINT_LIT value = 6
+mov rax, 6
+mov rcx, rax
+pop rax
+pop rdx
+pop rbx
+cmp rcx, rdx
+ja .L02
+cmp rax, rcx
+jbe .L01
+.L02:
+lea rsi, [rel __clovis_msg_slice_bounds]
+mov rdx, __clovis_msg_slice_bounds_len
+mov rax, 1
+mov rdi, 2
+syscall
+mov rax, 231
+mov rdi, 1
+syscall
+.L01:
+sub rcx, rax
+imul rax, rax, 1
+add rax, rbx
+mov rdx, rcx
+mov QWORD [rbp - 24], rax
+mov QWORD [rbp - 24 + 8], rdx
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = n offset = 32 size = 8
+sub rsp, 8
+; MemberExpression: type = UINT64 member = len
+; IdentExpression rvalue type = UINT8_SLICE
+mov rdx, QWORD [rbp - 24 + 8]
+mov rax, QWORD [rbp - 24]
+mov rax, rdx
+mov QWORD [rbp - 32], rax
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
+
+section .rodata
+__clovis_msg_slice_bounds: db "clovis: slice bounds out of range", 10
+__clovis_msg_slice_bounds_len equ $ - __clovis_msg_slice_bounds
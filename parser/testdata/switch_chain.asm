@@ -0,0 +1,72 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = x offset = 8 size = 8
+sub rsp, 8
+; LiteralExpression: type = UINT_LIT value = 3
+mov rax, 3
+mov QWORD [rbp - 8], rax
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = y offset = 16 size = 8
+sub rsp, 8
+; ------------------------- SwitchStmt ------------------------- 
+; IdentExpression rvalue type = UINT64
+mov rax, QWORD [rbp - 8]
+mov rbx, 1
+cmp rax, rbx
+je ..@L01
+mov rbx, 1000
+cmp rax, rbx
+jb ..@L05
+mov rbx, 2000
+cmp rax, rbx
+jbe ..@L02
+..@L05:
+mov rbx, 90000
+cmp rax, rbx
+je ..@L03
+jmp ..@L04
+..@L01:
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 16]
+push rax
+; LiteralExpression: type = UINT_LIT value = 1
+mov rax, 1
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 0
+jmp ..@L04
+..@L02:
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 16]
+push rax
+; LiteralExpression: type = UINT_LIT value = 2
+mov rax, 2
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 0
+jmp ..@L04
+..@L03:
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 16]
+push rax
+; LiteralExpression: type = UINT_LIT value = 3
+mov rax, 3
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 0
+jmp ..@L04
+..@L04:
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
@@ -0,0 +1,86 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT8 ident = x offset = 1 size = 1
+sub rsp, 1
+; LiteralExpression: type = UINT_LIT value = 3
+mov rax, 3
+mov BYTE [rbp - 1], al
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = y offset = 16 size = 8
+sub rsp, 15
+; ------------------------- SwitchStmt ------------------------- 
+; IdentExpression rvalue type = UINT8
+movzx eax, BYTE [rbp - 1]
+movzx eax, al
+; jump table 1..5
+mov rbx, 1
+sub rax, rbx
+mov rbx, 4
+cmp rax, rbx
+ja ..@L03
+lea rbx, [rel ..@L05]
+movsxd rax, DWORD [rbx + rax * 4]
+add rax, rbx
+jmp rax
+..@L01:
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 16]
+push rax
+; LiteralExpression: type = UINT_LIT value = 10
+mov rax, 10
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 0
+jmp ..@L04
+..@L02:
+; ------------------------- BlockStmt: Size = 8 -------------------------
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = z offset = 24 size = 8
+sub rsp, 8
+; LiteralExpression: type = UINT_LIT value = 2
+mov rax, 2
+mov QWORD [rbp - 24], rax
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 16]
+push rax
+; IdentExpression rvalue type = UINT64
+mov rax, QWORD [rbp - 24]
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 8
+add rsp, 0
+jmp ..@L04
+..@L03:
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 16]
+push rax
+; LiteralExpression: type = UINT_LIT value = 30
+mov rax, 30
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 0
+jmp ..@L04
+..@L04:
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
+
+section .rodata
+align 4
+..@L05:
+dd ..@L01 - ..@L05
+dd ..@L01 - ..@L05
+dd ..@L02 - ..@L05
+dd ..@L02 - ..@L05
+dd ..@L02 - ..@L05
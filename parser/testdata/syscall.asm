@@ -0,0 +1,42 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = r offset = 8 size = 8
+sub rsp, 8
+; SyscallExpression: args = 5
+; LiteralExpression: type = UINT_LIT value = 9
+mov rax, 9
+push rax
+; LiteralExpression: type = UINT_LIT value = 0
+mov rax, 0
+push rax
+; LiteralExpression: type = UINT_LIT value = 4096
+mov rax, 4096
+push rax
+; LiteralExpression: type = UINT_LIT value = 3
+mov rax, 3
+push rax
+; LiteralExpression: type = UINT_LIT value = 34
+mov rax, 34
+push rax
+; LiteralExpression: type = UINT_LIT value = 0
+mov rax, 0
+push rax
+pop r8
+pop r10
+pop rdx
+pop rsi
+pop rdi
+pop rax
+syscall
+mov QWORD [rbp - 8], rax
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
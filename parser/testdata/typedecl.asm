@@ -0,0 +1,20 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- TypeDeclStmt: Meters -------------------------
+; ------------------------- TypeDeclStmt: Id -------------------------
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT32 ident = m offset = 4 size = 4
+sub rsp, 4
+; LiteralExpression: type = UINT_LIT value = 7
+mov rax, 7
+mov DWORD [rbp - 4], eax
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
@@ -0,0 +1,24 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = size offset = 8 size = 8
+sub rsp, 8
+; TypeInfoExpression: sizeof(UINT32) = 4
+mov rax, 4
+mov QWORD [rbp - 8], rax
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = align offset = 16 size = 8
+sub rsp, 8
+; TypeInfoExpression: alignof(UINT64_PTR) = 8
+mov rax, 8
+mov QWORD [rbp - 16], rax
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
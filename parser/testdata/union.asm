@@ -0,0 +1,103 @@
+section .text
+global _start
+
+_start:
+mov rbp, rsp
+
+; ------------------------- UnionDeclStmt: Shape size = 16 -------------------------
+; ------------------------- VarDeclStmt -------------------------
+; type = Shape ident = s offset = 16 size = 16
+sub rsp, 16
+lea rax, [rbp - 16]
+; UnionConstructorExpression: Shape.Circle
+push rax
+; LiteralExpression: type = UINT_LIT value = 3
+mov rax, 3
+mov rbx, QWORD [rsp]
+mov QWORD [rbx + 8], rax
+pop rax
+mov BYTE [rax], 0
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = Shape
+lea rax, [rbp - 16]
+push rax
+; MemberExpression: type = Shape member = Empty
+lea rax, [rel ..@L01]
+mov rcx, 16
+mov rsi, rax
+pop rdi
+rep movsb
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = area offset = 24 size = 8
+sub rsp, 8
+; ------------------------- MatchStmt ------------------------- 
+; IdentExpression rvalue type = Shape
+lea rax, [rbp - 16]
+sub rsp, 8
+mov QWORD [rbp - 32], rax
+movzx eax, BYTE [rax]
+cmp rax, 0
+je .L02
+cmp rax, 1
+je .L03
+jmp .L04
+.L02:
+; binding r = Circle.0
+sub rsp, 8
+mov rbx, QWORD [rbp - 32]
+mov rax, QWORD [rbx + 8]
+mov QWORD [rbp - 40], rax
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 24]
+push rax
+; BinaryExpression: type = UINT64 op = *
+; LiteralExpression: type = UINT_LIT value = 3
+mov rax, 3
+push rax
+; BinaryExpression: type = UINT64 op = *
+; IdentExpression rvalue type = UINT64
+mov rax, QWORD [rbp - 40]
+push rax
+; IdentExpression rvalue type = UINT64
+mov rax, QWORD [rbp - 40]
+pop rbx
+mul rbx
+pop rbx
+mul rbx
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 8
+jmp .L04
+.L03:
+; ------------------------- BlockStmt: Size = 8 -------------------------
+; ------------------------- VarDeclStmt -------------------------
+; type = UINT64 ident = zero offset = 40 size = 8
+sub rsp, 8
+; LiteralExpression: type = UINT_LIT value = 0
+mov rax, 0
+mov QWORD [rbp - 40], rax
+; ------------------------- VarDefinitionStmt -------------------------
+; IdentExpression lvalue type = UINT64
+lea rax, [rbp - 24]
+push rax
+; IdentExpression rvalue type = UINT64
+mov rax, QWORD [rbp - 40]
+pop rbx
+mov QWORD [rbx], rax
+add rsp, 8
+add rsp, 0
+jmp .L04
+.L04:
+add rsp, 8
+
+; Emitter.End()
+mov rdi, 0
+__clovis_exit:
+mov rax, 231
+syscall
+
+section .rodata
+align 8, db 0
+..@L01:
+db 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00
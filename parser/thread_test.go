@@ -0,0 +1,27 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestThreadSyntax(t *testing.T) {
+	expectSyntaxError(t, `Thread t = spawn;`)
+	expectSyntaxError(t, `Thread t = spawn { }; join t;`)
+	expectSyntaxError(t, `shared;`)
+}
+
+func TestThreadRuntime(t *testing.T) {
+	asm := testdata(t, "thread")
+	expectLines(t, asm, "call __clovis_spawn", "call __clovis_join", "lock xadd QWORD [rax], rbx", "lock cmpxchg QWORD [rcx], rbx")
+
+	// Shared variables live in static storage, so the thread reaches them without the spawning frame.
+	spawned := between(t, asm, "BlockStmt", "syscall")
+	expectLines(t, spawned, "lea rax, [rel __clovis_shared_counter_1]", "lock xadd QWORD [rax], rbx", "mov rax, 60")
+
+	// Threads take the heap lock around the allocator routines.
+	expectLines(t, asm, "__clovis_alloc:", "call __clovis_heap_lock", "call __clovis_alloc_unlocked", "jmp __clovis_heap_unlock")
+
+	// Joining marks the handle, whose first page stays mapped, so a second join is reported.
+	join := between(t, asm, "__clovis_join:", "__clovis_msg_joined_twice_len")
+	expectLines(t, join, "xchg eax, DWORD [rbx + 4]", "jnz .joined", "lea rdi, [rbx + 4096]", "mov rsi, 0xff000")
+}
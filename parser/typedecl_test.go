@@ -0,0 +1,15 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestTypeDeclSyntax(t *testing.T) {
+	expectSyntaxError(t, `type = uint8;`)
+	expectSyntaxError(t, `type T uint8`)
+}
+
+func TestNamedTypesEmitNoCode(t *testing.T) {
+	asm := golden(t, "typedecl")
+	expectLines(t, asm, "mov DWORD [rbp - 4], eax")
+}
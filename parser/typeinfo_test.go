@@ -0,0 +1,15 @@
+package parser_test
+
+import (
+	"testing"
+)
+
+func TestTypeInfoSyntax(t *testing.T) {
+	expectSyntaxError(t, `uint64 n = sizeof uint32;`)
+	expectSyntaxError(t, `uint64 n = alignof();`)
+}
+
+func TestTypeInfoEmitsConstants(t *testing.T) {
+	asm := golden(t, "typeinfo")
+	expectLines(t, asm, "mov rax, 4", "mov rax, 8")
+}
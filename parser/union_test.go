@@ -0,0 +1,23 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnionSyntax(t *testing.T) {
+	expectSyntaxError(t, `union Shape { Circle(uint64 }`)
+	expectSyntaxError(t, `union Shape { Circle } Shape s; match s { case Circle(1): s = s; }`)
+}
+
+func TestUnionCodegen(t *testing.T) {
+	asm := golden(t, "union")
+
+	// Empty has the tag 1 and no fields, its value is a constant.
+	rodata := asm[strings.Index(asm, "section .rodata"):]
+	expectLines(t, rodata, "db 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00")
+
+	if growth := stackGrowth(between(t, asm, "MatchStmt", "; Emitter.End()")); growth != 0 {
+		t.Errorf("Expected the match to release its locals but the stack grows by %v", growth)
+	}
+}
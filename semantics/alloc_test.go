@@ -0,0 +1,31 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestNewTypes(t *testing.T) {
+	stmts := check(t, `
+		uint32* one = new uint32;
+		uint64 n = 4;
+		uint8* buf = new uint8[n];
+		uint16** indirect = new uint16*;
+		delete one;
+		delete buf;
+	`)
+
+	alloc := stmts[2].(*parser.VarDeclStmt).Right.Value().(*parser.NewExpression)
+	if !alloc.ExprType().Equals(semantics.Ptr{ ValueType: semantics.Uint8{} }) || !alloc.Count.HasVal() {
+		t.Errorf("Expected a counted UINT8 allocation but received %v", alloc.Print(0))
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	expectCodes(t, `uint32* p = new uint32[true];`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint32* p = new Missing;`, diagnostics.UndeclaredSymbol)
+	expectCodes(t, `uint32 x; delete x;`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint16* p = new uint32;`, diagnostics.TypeMismatch)
+}
@@ -0,0 +1,66 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"testing"
+)
+
+func TestAsmPlaceholders(t *testing.T) {
+	stmts := check(t, `
+		uint64 x = 1;
+		uint8[4] bytes;
+		asm (r12, rax) {
+			mov r12, {x}
+			lea rax, { bytes }
+			add r12, 1
+			mov {x}, r12
+		}
+	`)
+
+	stmt := stmts[2].(*parser.AsmStmt)
+	if len(stmt.Symbols) != 2 || stmt.Symbols["x"].Ident != "x" || stmt.Symbols["bytes"].Ident != "bytes" {
+		t.Errorf("Expected the symbols of x and bytes but received %v", stmt.Symbols)
+	}
+}
+
+func TestAsmErrors(t *testing.T) {
+	expectCodes(t, `asm (rax) { mov rax, {missing} }`, diagnostics.UndeclaredSymbol)
+	expectCodes(t, `type T = uint8; asm (rax) { lea rax, {T} }`, diagnostics.WrongSymbolKind)
+	expectCodes(t, `asm { mov rax, 1 }`, diagnostics.InvalidRegister)
+	expectCodes(t, `asm (eax) { mov eax, 1 }`, diagnostics.InvalidRegister)
+	expectCodes(t, `asm (rbp) { mov rbp, 1 }`, diagnostics.InvalidRegister)
+	expectCodes(t, `asm (rax, rax) { mov rax, 1 }`, diagnostics.InvalidRegister)
+	expectValid(t, `asm (rax) { mov rax, 1 ; rbx is only mentioned in a comment }`)
+	expectValid(t, `asm (rax) { mov AL, 1 }`)
+	expectCodes(t, `asm (rax) { lea rax, [rbp - 8] }`, diagnostics.InvalidRegister)
+	expectCodes(t, `asm (rax) { mov rax, rsp }`, diagnostics.InvalidRegister)
+}
+
+func TestAsmLabels(t *testing.T) {
+	expectValid(t, `asm (rcx) {
+		mov rcx, 4
+	.again:
+		dec rcx
+		jnz .again
+	..@done: nop
+	}`)
+	expectCodes(t, `asm (rcx) {
+	again:
+		dec rcx
+		jnz again
+	}`, diagnostics.InvalidAsmLabel)
+}
+
+// Errors point at the offending part of the text rather than at the asm keyword.
+func TestAsmErrorPositions(t *testing.T) {
+	d := expectCodes(t, "uint64 x;\nasm (rax) {\n\tmov rax, {x}\n\tadd rax, rbx\n}", diagnostics.InvalidRegister)
+	if d[0].Span.Start.Line != 4 || d[0].Span.Start.Col != 11 {
+		t.Errorf("Expected the error at rbx on 4:11 but received %v", d[0])
+	}
+
+	d = expectCodes(t, "asm (rax) {\n\tmov rax, {missing}\n}", diagnostics.UndeclaredSymbol)
+	if d[0].Span.Start.Line != 2 || d[0].Span.Start.Col != 12 {
+		t.Errorf("Expected the error at missing on 2:12 but received %v", d[0])
+	}
+}
@@ -0,0 +1,25 @@
+package semantics
+
+// The error number of a failed system call.
+var Errno = Named{ Name: "Errno", Underlying: Uint64{} }
+
+// The result of an I/O builtin.
+// Ok holds the byte count, file descriptor or parsed value, Err the error number.
+var IoResult = NewUnion("IoResult", Decl{}, []UnionVariant{
+	{ Ident: "Ok", Fields: []Type{ Uint64{} } },
+	{ Ident: "Err", Fields: []Type{ Errno } },
+})
+
+// The handle of a thread started by spawn. It holds the address of the thread's stack mapping.
+var Thread = Named{ Name: "Thread", Underlying: Uint64{} }
+
+// The types every program can refer to by name.
+var builtinTypes = []Type{ Errno, IoResult, Thread }
+
+// Declares the builtin types in the outermost scope.
+// Their names are reserved, so programs cannot declare types that would stand in for them.
+func (s *SemanticChecker) declareBuiltins() {
+	for _, t := range builtinTypes {
+		s.symbolTable.Push(Symbol{ Ident: string(t.TypeID()), Type: t, IsType: true })
+	}
+}
@@ -0,0 +1,74 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"encoding/binary"
+	"testing"
+)
+
+func TestComptimeTable(t *testing.T) {
+	stmts := check(t, `
+		comptime {
+			uint32[8] table;
+			uint64 i = 0;
+			while i < 8 {
+				uint32 c = i as uint32;
+				uint8 k = 0;
+				while k < 3 {
+					c = c * 2 + 1;
+					k = k + 1;
+				}
+				table[i] = c;
+				i = i + 1;
+			}
+		}
+		uint32 first = table[1];
+	`)
+
+	block := stmts[0].(*parser.ComptimeBlockStmt)
+	if len(block.Decls) != 2 || len(block.Values[0]) != 32 {
+		t.Fatalf("Expected the 32 bytes of table but received %v", block.Values)
+	}
+
+	for i := range 8 {
+		want := uint32(i * 8 + 7)
+		if got := binary.LittleEndian.Uint32(block.Values[0][i * 4:]); got != want {
+			t.Errorf("Expected table[%v] to be %v but received %v", i, want, got)
+		}
+	}
+}
+
+func TestComptimeExpression(t *testing.T) {
+	stmts := check(t, `
+		uint64 x = comptime (6 * 7);
+		bool b = comptime (sizeof(uint16) == 2);
+	`)
+
+	exp := stmts[0].(*parser.VarDeclStmt).Right.Value().(*parser.ComptimeExpression)
+	if exp.Value != 42 {
+		t.Errorf("Expected 42 but received %v", exp.Value)
+	}
+}
+
+func TestComptimeErrors(t *testing.T) {
+	d := expectCodes(t, "comptime {\n\tuint64 x = 1 / 0;\n}", diagnostics.ComptimeEvaluation)
+	if d[0].Span.Start.Line != 2 {
+		t.Errorf("Expected the error on line 2 but received %v", d[0])
+	}
+
+	expectCodes(t, `comptime { assert 1 == 2; }`, diagnostics.ComptimeEvaluation)
+	expectCodes(t, `comptime { exit(1); }`, diagnostics.ComptimeEvaluation)
+	expectCodes(t, `comptime { asm { nop } }`, diagnostics.ComptimeEvaluation)
+	expectCodes(t, `comptime { uint64* p; }`, diagnostics.ComptimeEvaluation)
+	expectCodes(t, `uint64 x = 1; uint64 y = comptime x;`, diagnostics.ComptimeEvaluation)
+	expectCodes(t, `comptime { uint64 x = missing; }`, diagnostics.UndeclaredSymbol)
+}
+
+// Runaway loops are stopped by the step limit instead of hanging the compiler.
+func TestComptimeStepLimit(t *testing.T) {
+	d := expectCodes(t, `comptime { while true { } }`, diagnostics.ComptimeEvaluation)
+	if d[0].Span.Start.Col != 12 {
+		t.Errorf("Expected the error at the loop but received %v", d[0])
+	}
+}
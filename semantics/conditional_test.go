@@ -0,0 +1,39 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestConditionalType(t *testing.T) {
+	stmts := check(t, `
+		uint16 a = 1;
+		bool b = true;
+		uint16 c = b ? a : 5;
+	`)
+
+	decl := stmts[2].(*parser.VarDeclStmt)
+	conditional, isConditional := decl.Right.Value().(*parser.ConditionalExpression)
+	if !isConditional {
+		t.Fatalf("Expected a conditional expression but received %v", decl.Print(0))
+	}
+
+	if conditional.ExprType().TypeID() != semantics.UINT16 {
+		t.Errorf("Expected the literal branch to adapt to UINT16 but received %v", conditional.ExprType().TypeID())
+	}
+}
+
+func TestConditionalNesting(t *testing.T) {
+	expectValid(t, `
+		uint8 x = 3;
+		uint8 y = x == 1 ? 10 : x == 2 ? 20 : 30;
+		static_assert (true ? 1 : 2) == 1;
+	`)
+}
+
+func TestConditionalErrors(t *testing.T) {
+	expectCodes(t, `uint8 x = 1; uint8 y = x ? 1 : 2;`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint8 x = 1; bool b = true; uint8 y = b ? x : b;`, diagnostics.TypeMismatch)
+}
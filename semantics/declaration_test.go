@@ -0,0 +1,39 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"testing"
+)
+
+// A declaration with errors still declares its name, so its uses are not reported again.
+func TestFailedDeclarations(t *testing.T) {
+	expectCodes(t, `uint64 x = true; x = 2; uint64 y = x + 1;`, diagnostics.TypeMismatch)
+	expectCodes(t, `type T = Missing; T a; T* p = &a;`, diagnostics.UndeclaredSymbol)
+	expectCodes(t, `enum E : bool { A } E e = E.A;`, diagnostics.InvalidDeclaration)
+	expectCodes(t, `union U { } U u = U.A;`, diagnostics.InvalidDeclaration)
+	expectCodes(t, `type UINT64 uint8; UINT64 a = 1;`, diagnostics.ReservedName)
+	expectCodes(t, `extern uint64[2] f(); uint64 n = f();`, diagnostics.RegisterSize)
+	expectCodes(t, `uint64 x = true; asm (rax) { mov rax, {x} }`, diagnostics.TypeMismatch)
+
+	// Only the failed name is silenced, other mistakes are still reported.
+	expectCodes(t, `uint64 x = true; x = 2; y = 3;`, diagnostics.TypeMismatch, diagnostics.UndeclaredSymbol)
+
+	// A redeclaration keeps the first declaration.
+	expectCodes(t, `uint64 x = 1; bool x = true; x = 2;`, diagnostics.Redeclaration)
+
+	// Names declared inside a block go out of scope with it like any other.
+	expectCodes(t, `{ uint64 x = true; } x = 1;`, diagnostics.TypeMismatch, diagnostics.UndeclaredSymbol)
+}
+
+func TestFailedModuleDeclarations(t *testing.T) {
+	broken := withFiles(map[string]string{ "broken.clv": `uint64 x = true; type T = Missing;` })
+	result := compile(t, `import "broken.clv"; uint64 y = broken.x; broken.T t;`, broken)
+
+	codes := []diagnostics.Code{}
+	for _, d := range result.Diagnostics {
+		codes = append(codes, d.Code)
+	}
+	if len(codes) != 2 || codes[0] != diagnostics.TypeMismatch || codes[1] != diagnostics.UndeclaredSymbol {
+		t.Errorf("Expected only the errors of broken.clv but received %v", result.Diagnostics)
+	}
+}
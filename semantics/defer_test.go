@@ -0,0 +1,29 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"testing"
+)
+
+func TestDeferRegistration(t *testing.T) {
+	stmts := check(t, `
+		uint64 x;
+		{
+			defer x = 1;
+			defer { x = 2; }
+			x = 3;
+		}
+	`)
+
+	block := stmts[1].(*parser.BlockStmt)
+	if len(block.Deferred) != 2 {
+		t.Errorf("Expected two deferred statements but received %v", len(block.Deferred))
+	}
+}
+
+func TestDeferErrors(t *testing.T) {
+	expectCodes(t, `uint64 x; if true defer x = 1;`, diagnostics.Misplaced)
+	expectCodes(t, `{ defer uint64 x = 1; }`, diagnostics.Misplaced)
+	expectCodes(t, `{ defer y = 1; }`, diagnostics.UndeclaredSymbol)
+}
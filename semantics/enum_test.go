@@ -0,0 +1,78 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"testing"
+)
+
+func TestEnumMemberValues(t *testing.T) {
+	stmts := check(t, `enum Color : uint8 { Red, Green, Blue = 10, Violet, }`)
+
+	enum := stmts[0].(*parser.EnumDeclStmt).Type
+	want := map[string]uint64{ "Red": 0, "Green": 1, "Blue": 10, "Violet": 11 }
+	if len(enum.Members) != len(want) {
+		t.Fatalf("Expected %v members but received %v", len(want), enum.Members)
+	}
+
+	for _, member := range enum.Members {
+		if member.Value != want[member.Ident] {
+			t.Errorf("Expected %v = %v but received %v", member.Ident, want[member.Ident], member.Value)
+		}
+	}
+}
+
+func TestEnumUsage(t *testing.T) {
+	expectValid(t, `
+		enum Color : uint8 { Red, Green, Blue = 10 }
+		Color c = Color.Blue;
+		static_assert sizeof(Color) == 1;
+		assert c == Color.Blue;
+		uint8 raw = c as uint8;
+	`)
+}
+
+func TestEnumErrors(t *testing.T) {
+	expectCodes(t, `enum E : uint8 { A, A }`, diagnostics.InvalidDeclaration)
+	expectCodes(t, `enum E : uint8 { A = 256 }`, diagnostics.InvalidDeclaration)
+	expectCodes(t, `enum E : uint8 { A = 255, B }`, diagnostics.InvalidDeclaration)
+	expectCodes(t, `enum E : bool { A }`, diagnostics.InvalidDeclaration)
+	expectCodes(t, `enum E : uint8 { A } E e = E.B;`, diagnostics.UnknownMember)
+	expectCodes(t, `enum E : uint8 { A } uint8 n = E.A;`, diagnostics.TypeMismatch)
+}
+
+// Enums are told apart by their declaration, not their name.
+func TestEnumIdentity(t *testing.T) {
+	expectCodes(t, `
+		enum E : uint8 { A }
+		E outer = E.A;
+		{
+			enum E : uint64 { A = 1000 }
+			E inner = outer;
+		}
+	`, diagnostics.TypeMismatch)
+
+	colors := withFiles(map[string]string{ "colors.clv": `enum Color : uint8 { Red }` })
+	result := compile(t, `
+		import "colors.clv";
+		enum Color : uint64 { Red = 1000 }
+		Color c = Color.Red;
+		bool same = c == colors.Color.Red;
+	`, colors)
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != diagnostics.InvalidOperand {
+		t.Errorf("Expected enums of different modules not to mix but received %v", result.Diagnostics)
+	}
+
+	expectValid(t, `
+		enum E : uint8 { A }
+		E e = E.A;
+		E* p = &e;
+		E[2] pair;
+		pair[0] = *p;
+	`)
+}
+
+func TestEnumReservedNames(t *testing.T) {
+	expectCodes(t, `enum UINT64 : uint8 { A }`, diagnostics.ReservedName)
+	expectCodes(t, `enum BOOL : uint8 { A }`, diagnostics.ReservedName)
+}
@@ -0,0 +1,46 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestExternSignatures(t *testing.T) {
+	stmts := check(t, `
+		extern uint64 strlen(uint8* s);
+		extern uint32 printf(uint8* format, ...);
+		extern abort();
+		extern bool flag(uint8 byte, uint16*);
+		uint8[4] msg;
+		uint64 n = strlen(&msg[0]);
+		printf(&msg[0], n, 1);
+		abort();
+	`)
+
+	printf := stmts[1].(*parser.ExternDeclStmt).Type
+	if !printf.Variadic || len(printf.Params) != 1 || printf.Return.TypeID() != semantics.UINT32 {
+		t.Errorf("Expected a variadic UINT32 function with one parameter but received %v", printf)
+	}
+
+	call := stmts[5].(*parser.VarDeclStmt).Right.Value().(*parser.CallExpression)
+	if call.Extern == nil || call.ExprType().TypeID() != semantics.UINT64 {
+		t.Errorf("Expected a UINT64 extern call but received %v", call.Print(0))
+	}
+}
+
+func TestExternErrors(t *testing.T) {
+	expectCodes(t, `extern main();`, diagnostics.ReservedName)
+	expectCodes(t, `extern __clovis_alloc();`, diagnostics.ReservedName)
+	expectCodes(t, `extern größe();`, diagnostics.InvalidDeclaration)
+	expectCodes(t, `extern uint64 f(); extern uint64 f();`, diagnostics.Redeclaration)
+	expectCodes(t, `union U { A(uint64, uint64) } extern f(U u);`, diagnostics.RegisterSize)
+	expectCodes(t, `extern uint64[2] f();`, diagnostics.RegisterSize)
+	expectCodes(t, `extern f(uint64 x); f();`, diagnostics.ArgumentCount)
+	expectCodes(t, `extern f(uint64 x); f(1, 2);`, diagnostics.ArgumentCount)
+	expectCodes(t, `extern f(uint8* p, ...); uint8* p; f(p);`)
+	expectCodes(t, `extern f(uint8* p); uint64 x = 1; f(x);`, diagnostics.TypeMismatch)
+	expectCodes(t, `extern f(...); uint64[2] a; f(a);`, diagnostics.RegisterSize)
+	expectCodes(t, `extern f(); spawn { f(); };`, diagnostics.Misplaced)
+}
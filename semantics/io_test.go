@@ -0,0 +1,48 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestIoBuiltinTypes(t *testing.T) {
+	stmts := check(t, `
+		uint8[4] path;
+		IoResult f = open(path, 0);
+		uint8[16] buf;
+		IoResult n = read(0, buf);
+		write(1, buf[0..2]);
+		close(3);
+		IoResult x = read_uint();
+		open(args[0], 0);
+	`)
+
+	call := stmts[1].(*parser.VarDeclStmt).Right.Value().(*parser.CallExpression)
+	if !call.ExprType().Equals(semantics.IoResult) || !call.InPlace {
+		t.Errorf("Expected an IoResult built in place but received %v", call.Print(0))
+	}
+}
+
+func TestIoResultMatch(t *testing.T) {
+	expectValid(t, `
+		uint8[16] buf;
+		IoResult r = read(0, buf);
+		match r {
+			case Ok(n): exit(n);
+			case Err(errno): exit(errno);
+		}
+	`)
+}
+
+func TestIoBuiltinErrors(t *testing.T) {
+	expectCodes(t, `IoResult r = missing(1);`, diagnostics.WrongSymbolKind)
+	expectCodes(t, `IoResult r = close();`, diagnostics.ArgumentCount)
+	expectCodes(t, `IoResult r = read_uint(1);`, diagnostics.ArgumentCount)
+	expectCodes(t, `uint64 fd = 1; IoResult r = write(fd, fd);`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint32[4] buf; IoResult r = write(1, buf);`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint8[4] buf; IoResult r = write(true, buf);`, diagnostics.InvalidOperand)
+	expectCodes(t, `IoResult r = open(1, 0);`, diagnostics.InvalidOperand)
+	expectCodes(t, `bool b = close(1) == close(2);`, diagnostics.Misplaced)
+}
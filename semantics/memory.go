@@ -0,0 +1,100 @@
+package semantics
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The memory of the compile-time interpreter.
+// Addresses are offsets into Bytes. The first bytes are never handed out so 0 can stand for null.
+type Memory struct {
+	Bytes  []byte
+	// The addresses of the variables declared by comptime blocks by their label.
+	Labels map[string]uint64
+	// The address of the simulated stack, or 0 before it is first needed.
+	Stack  uint64
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		Bytes: make([]byte, 16),
+		Labels: map[string]uint64{},
+	}
+}
+
+// Reserves size zeroed bytes aligned to align and returns their address.
+func (m *Memory) Alloc(size int, align int) uint64 {
+	addr := alignTo(len(m.Bytes), align)
+	m.Bytes = append(m.Bytes, make([]byte, addr + size - len(m.Bytes))...)
+	return uint64(addr)
+}
+
+// Returns the size bytes at addr.
+func (m *Memory) Read(addr uint64, size int) ([]byte, error) {
+	if err := m.check(addr, size); err != nil {
+		return nil, err
+	}
+
+	return m.Bytes[addr:addr + uint64(size)], nil
+}
+
+// Loads the little endian number of the given size in bytes at addr.
+func (m *Memory) Load(addr uint64, size int) (uint64, error) {
+	bytes, err := m.Read(addr, size)
+	if err != nil {
+		return 0, err
+	}
+
+	word := make([]byte, 8)
+	copy(word, bytes)
+	return binary.LittleEndian.Uint64(word), nil
+}
+
+// Stores the lowest size bytes of value at addr in little endian order.
+func (m *Memory) Store(addr uint64, size int, value uint64) error {
+	if err := m.check(addr, size); err != nil {
+		return err
+	}
+
+	word := make([]byte, 8)
+	binary.LittleEndian.PutUint64(word, value)
+	copy(m.Bytes[addr:addr + uint64(size)], word[:size])
+	return nil
+}
+
+// Copies size bytes from src to dst.
+func (m *Memory) Copy(dst uint64, src uint64, size int) error {
+	bytes, err := m.Read(src, size)
+	if err != nil {
+		return err
+	}
+
+	if err := m.check(dst, size); err != nil {
+		return err
+	}
+
+	copy(m.Bytes[dst:dst + uint64(size)], bytes)
+	return nil
+}
+
+// Sets size bytes at addr to zero.
+func (m *Memory) Clear(addr uint64, size int) error {
+	if err := m.check(addr, size); err != nil {
+		return err
+	}
+
+	clear(m.Bytes[addr:addr + uint64(size)])
+	return nil
+}
+
+func (m *Memory) check(addr uint64, size int) error {
+	if addr == 0 {
+		return fmt.Errorf("Null pointer dereference")
+	}
+
+	if addr > uint64(len(m.Bytes)) || uint64(len(m.Bytes)) - addr < uint64(size) {
+		return fmt.Errorf("Access of %v bytes at address %#x is out of bounds", size, addr)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var mathModule = withFiles(map[string]string{
+	"math.clv": `
+		uint64 PI = 3;
+		type Meters = uint64;
+		enum Axis : uint8 { X, Y }
+		type Radians uint64;
+		union Shape { Circle(uint64), Empty }
+	`,
+})
+
+func TestQualifiedNames(t *testing.T) {
+	result := compile(t, `
+		import "math.clv";
+		uint64 pi = math.PI;
+		math.Meters m = 2;
+		math.Axis a = math.Axis.Y;
+	`, mathModule)
+
+	if len(result.Diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics but received %v", result.Diagnostics)
+	}
+
+	if len(result.Modules) != 2 || result.Modules[0].Name != "math" {
+		t.Errorf("Expected the math module before main but received %v", result.Modules)
+	}
+}
+
+// Fails unless compiling src with the math module reports exactly the given codes in order.
+func expectModuleCodes(t *testing.T, src string, codes ...diagnostics.Code) {
+	t.Helper()
+
+	result := compile(t, src, mathModule)
+	received := []diagnostics.Code{}
+	for _, d := range result.Diagnostics {
+		received = append(received, d.Code)
+	}
+
+	if fmt.Sprint(received) != fmt.Sprint(codes) {
+		t.Fatalf("Expected codes %v but received %v", codes, result.Diagnostics)
+	}
+}
+
+func TestQualifiedNameErrors(t *testing.T) {
+	// Top-level symbols of a module are only visible through its name.
+	expectModuleCodes(t, `import "math.clv"; uint64 pi = PI;`, diagnostics.UndeclaredSymbol)
+	expectModuleCodes(t, `import "math.clv"; uint64 e = math.E;`, diagnostics.UnknownMember)
+	expectModuleCodes(t, `import "math.clv"; uint64 math = 1;`, diagnostics.Redeclaration)
+	expectModuleCodes(t, `uint64 pi = math.PI;`, diagnostics.UndeclaredSymbol)
+}
+
+// Types declared by a module keep their identity in the importing file and are shown qualified by
+// their module, so they are not mistaken for types of the same name declared there.
+func TestImportedTypeIdentity(t *testing.T) {
+	expectModuleCodes(t, `
+		import "math.clv";
+		math.Shape s = math.Shape.Circle(1);
+		math.Radians r = 2;
+		math.Shape* p = &s;
+		r = r + 1;
+	`)
+
+	result := compile(t, `
+		import "math.clv";
+		union Shape { Circle(uint64), Empty }
+		math.Shape imported;
+		Shape local = imported;
+	`, mathModule)
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != diagnostics.TypeMismatch {
+		t.Fatalf("Expected unions of different modules not to mix but received %v", result.Diagnostics)
+	}
+	if msg := result.Diagnostics[0].Message; !strings.Contains(msg, "math.Shape") {
+		t.Errorf("Expected the imported union to be shown as math.Shape but received %q", msg)
+	}
+
+	result = compile(t, `
+		import "math.clv";
+		type Radians uint64;
+		math.Radians a = 1;
+		Radians b = 2;
+		bool same = a == b;
+	`, mathModule)
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != diagnostics.InvalidOperand {
+		t.Fatalf("Expected distinct types of different modules not to mix but received %v", result.Diagnostics)
+	}
+	if msg := result.Diagnostics[0].Message; !strings.Contains(msg, "math.Radians and Radians") {
+		t.Errorf("Expected the imported type to be shown as math.Radians but received %q", msg)
+	}
+}
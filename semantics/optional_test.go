@@ -0,0 +1,69 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestOptionalWrapping(t *testing.T) {
+	stmts := check(t, `
+		uint32? a = 5;
+		uint32? b = none;
+		a = none;
+		b = 7;
+		static_assert sizeof(uint32?) == 8;
+		static_assert sizeof(uint8?) == 2;
+	`)
+
+	decl := stmts[0].(*parser.VarDeclStmt)
+	wrapped, isWrapped := decl.Right.Value().(*parser.OptionalExpression)
+	if !isWrapped || !wrapped.ExprType().Equals(semantics.Optional{ Base: semantics.Uint32{} }) {
+		t.Errorf("Expected 5 to be wrapped into UINT32? but received %v", decl.Right.Value().Print(0))
+	}
+}
+
+func TestIfLet(t *testing.T) {
+	stmts := check(t, `
+		uint32? a = 5;
+		uint32 sum;
+		if let v = a sum = v; else sum = 0;
+	`)
+
+	stmt := stmts[2].(*parser.IfLetStmt)
+	if stmt.Symbol.Ident != "v" || stmt.Symbol.Type.TypeID() != semantics.UINT32 {
+		t.Errorf("Expected v to be bound as UINT32 but received %+v", stmt.Symbol)
+	}
+}
+
+func TestOptionalErrors(t *testing.T) {
+	expectCodes(t, `uint32 x = none;`, diagnostics.TypeMismatch)
+	expectCodes(t, `uint32? a = 5; uint32 x = a;`, diagnostics.TypeMismatch)
+	expectCodes(t, `uint32 a = 5; if let v = a a = v;`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint32? a; if let v = a a = 1; uint32 w = v;`, diagnostics.UndeclaredSymbol)
+}
+
+func TestNestedOptionals(t *testing.T) {
+	expectCodes(t, `type Maybe = uint32?; Maybe? a;`, diagnostics.InvalidDeclaration)
+	expectCodes(t, `uint32? a; typeof(a)? b;`, diagnostics.InvalidDeclaration)
+	expectValid(t, `type Maybe = uint32?; Maybe a = none; uint32*? p;`)
+}
+
+func TestNoneComparison(t *testing.T) {
+	expectValid(t, `
+		uint32? a = 5;
+		bool b = a == none;
+		bool c = none != a;
+		comptime {
+			uint32? x = 3;
+			uint32? y = none;
+			assert x != none;
+			assert y == none;
+		}
+	`)
+	expectCodes(t, `comptime { uint32? y = none; assert y != none; }`, diagnostics.ComptimeEvaluation)
+	expectCodes(t, `uint32? a; bool b = a < none;`, diagnostics.InvalidOperand)
+	expectCodes(t, `bool b = none == none;`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint32 a; bool b = a == none;`, diagnostics.InvalidOperand)
+}
@@ -0,0 +1,38 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestProcessValues(t *testing.T) {
+	stmts := check(t, `
+		uint64 n = argc;
+		uint8*[] arguments = args;
+		uint8*[] environment = envp;
+		uint8* program = args[0];
+		exit(n - 1);
+	`)
+
+	want := []semantics.Type{
+		semantics.Uint64{},
+		semantics.Slice{ Base: semantics.Ptr{ ValueType: semantics.Uint8{} } },
+		semantics.Slice{ Base: semantics.Ptr{ ValueType: semantics.Uint8{} } },
+	}
+	for i, wantType := range want {
+		value := stmts[i].(*parser.VarDeclStmt).Right.Value()
+		if !value.ExprType().Equals(wantType) {
+			t.Errorf("Expected %v but received %v", wantType.TypeID(), value.ExprType().TypeID())
+		}
+	}
+}
+
+func TestExitErrors(t *testing.T) {
+	expectCodes(t, `exit(true);`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint64 t = spawn { exit(1); };`, diagnostics.Misplaced)
+	expectCodes(t, `exit(300);`, diagnostics.InvalidOperand)
+	expectCodes(t, `exit(200 + 56);`, diagnostics.InvalidOperand)
+	expectValid(t, `exit(255);`)
+}
@@ -1,33 +1,48 @@
 package semantics
 
 import (
+	"clovis/diagnostics"
 	"clovis/lexer"
 	"clovis/utils"
+	"errors"
 	"fmt"
+	"strings"
+	"unicode"
 )
 
-type SemanticError struct {
-	msg   string
-	token lexer.Token
+type Symbol struct {
+	Ident    string
+	Type     Type
+	Offset   int
+	Size     int
+	// Bytes skipped before the symbol so its offset satisfies the type's alignment.
+	Padding  int
+	Token    lexer.Token
+	// Whether the symbol names a user defined type instead of a variable.
+	IsType   bool
+	// Whether the symbol names an imported module. Its members are looked up with LookupQualified.
+	IsModule bool
+	// The module that declared the symbol. Empty for builtins and the main file.
+	Module   string
+	// The label of a shared or comptime variable, which lives in static storage instead of the stack.
+	Label    string
+	// Whether the variable is declared by a comptime block, so its value is known during compilation.
+	Comptime bool
+	// Whether the declaration had errors. Uses of the symbol fail without reporting more errors.
+	Failed   bool
 }
 
-func NewSemanticError(msg string, token lexer.Token) *SemanticError {
-	return &SemanticError{
-		msg: msg,
-		token: token,
-	}
-}
+// Returned for uses of a declaration that had errors. Those errors were reported with the
+// declaration, so its uses fail without reporting more.
+var ErrFailedDeclaration = errors.New("Use of a declaration that had errors")
 
-func (s *SemanticError) Error() string {
-	return fmt.Sprintf("Semantic error at line %v at col %v\n\t%v", s.token.Line, s.token.Col, s.msg)
-}
+// Returns the address expression of a variable, e.g. "rbp - 8".
+func (s Symbol) Addr() string {
+	if s.Label != "" {
+		return fmt.Sprintf("rel %v", s.Label)
+	}
 
-type Symbol struct {
-	Ident  string
-	Type   Type
-	Offset int
-	Size   int
-	Token  lexer.Token
+	return fmt.Sprintf("rbp - %v", s.Offset)
 }
 
 func (s Symbol) String() string {
@@ -40,15 +55,26 @@ func (s Symbol) String() string {
 // The SemanticChecker is used to analyze the statements and expressions
 // to ensure their correctness.
 type SemanticChecker struct {
-	Errors			[]error
+	Diagnostics     *diagnostics.Collector
 	symbolTable     utils.Stack[Symbol]
 	blockIndexTable utils.Stack[int]
 	nextAddr		int
+	// The module whose statements are currently checked and the path of its file.
+	module          string
+	file            string
+	// The frame sizes at the start of the enclosing spawn blocks, innermost last.
+	spawnFrames     utils.Stack[int]
+	// The number of variables in static storage. Keeps their labels unique.
+	staticCount     int
+	// The memory of the compile-time interpreter. Created on first use.
+	comptimeMemory  *Memory
 }
 
 func NewSemanticChecker() *SemanticChecker {
-	s := SemanticChecker{}
-	s.blockIndexTable.Push(0) // global scope currently
+	s := SemanticChecker{ Diagnostics: diagnostics.NewCollector() }
+	s.blockIndexTable.Push(0) // builtin scope
+	s.declareBuiltins()
+	s.PushBlock() // global scope currently
 	return &s
 }
 
@@ -61,39 +87,325 @@ func (s SemanticChecker) String() string {
 	)
 }
 
-func (s *SemanticChecker) AddError(msg string, token lexer.Token) error {
-	err := NewSemanticError(msg, token)
-	s.Errors = append(s.Errors, err)
-	return err
+// Sets the module whose top-level statements are checked next and the file they are in.
+// Symbols declared by one module are only visible to other modules through qualified names.
+func (s *SemanticChecker) SetModule(name string, file string) {
+	s.module = name
+	s.file = file
+}
+
+// Returns the declaration of a user defined type named by token in the file currently checked.
+func (s *SemanticChecker) DeclAt(token lexer.Token) Decl {
+	return Decl{ File: s.file, Offset: token.Offset, Module: s.module }
+}
+
+// Reports an error at token. The returned diagnostic can be given notes and hints.
+func (s *SemanticChecker) AddError(code diagnostics.Code, msg string, token lexer.Token) *diagnostics.Diagnostic {
+	d := diagnostics.New(code, token.Span(), msg)
+	d.File = s.file
+	return s.Diagnostics.Report(d)
 }
 
 func (s *SemanticChecker) PushSymbol(ident string, symbolType Type, token lexer.Token) error {
-	if s.TopBlockHasSymbol(ident) {
-		return s.AddError(
-			fmt.Sprintf("Redeclaration of symbol '%v'", ident),
-			token,
-		)
+	if err := s.checkRedeclaration(ident, token); err != nil {
+		return err
 	}
 
 	symbolSize := symbolType.Size()
+	offset := alignTo(s.nextAddr + symbolSize, symbolType.Align())
 	symbol := &Symbol{
 		Ident: ident,
 		Type: symbolType,
 		Token: token,
-		Offset: s.nextAddr + symbolSize,
+		Offset: offset,
 		Size: symbolSize,
+		Padding: offset - s.nextAddr - symbolSize,
+		Module: s.module,
 	}
-	s.nextAddr += symbolSize
+	s.nextAddr = offset
 	s.symbolTable.Push(*symbol)
 
 	return nil
 }
 
+// Declares ident as failed when err reports that its declaration had errors, so later uses
+// of the name are not reported as undeclared. Returns err.
+func (s *SemanticChecker) FailDeclaration(ident lexer.Token, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// The declaration may have failed after declaring the name, or because the name was taken.
+	if _, isDeclared := s.topBlockSymbol(ident.Value); !isDeclared {
+		s.symbolTable.Push(Symbol{
+			Ident: ident.Value,
+			Type: Undefined{},
+			Token: ident,
+			Module: s.module,
+			Failed: true,
+		})
+	}
+
+	return err
+}
+
+// Reports whether the name visible as ident was declared by a declaration that had errors.
+func (s *SemanticChecker) IsFailed(ident string) bool {
+	symbolTableData := s.symbolTable.Data()
+	for i := len(symbolTableData) - 1; i >= 0; i-- {
+		symbol := symbolTableData[i]
+		if symbol.Ident == ident && s.isVisible(symbol) {
+			return symbol.Failed
+		}
+	}
+
+	return false
+}
+
+// Declares a shared variable in the current block.
+// Shared variables live in static storage so every thread sees the same variable.
+func (s *SemanticChecker) PushSharedSymbol(ident string, symbolType Type, token lexer.Token) error {
+	return s.pushStaticSymbol(ident, symbolType, token, false)
+}
+
+// Declares a variable of a comptime block in the current block.
+// Its value is computed during compilation and placed in static storage.
+func (s *SemanticChecker) PushComptimeSymbol(ident string, symbolType Type, token lexer.Token) error {
+	return s.pushStaticSymbol(ident, symbolType, token, true)
+}
+
+func (s *SemanticChecker) pushStaticSymbol(ident string, symbolType Type, token lexer.Token, comptime bool) error {
+	if err := s.checkRedeclaration(ident, token); err != nil {
+		return err
+	}
+
+	kind := "shared"
+	if comptime {
+		kind = "comptime"
+	}
+
+	s.staticCount++
+	s.symbolTable.Push(Symbol{
+		Ident: ident,
+		Type: symbolType,
+		Token: token,
+		Module: s.module,
+		Label: fmt.Sprintf("__clovis_%v_%v_%v", kind, labelName(ident), s.staticCount),
+		Comptime: comptime,
+	})
+
+	return nil
+}
+
+// Returns ident as it can be written in an assembler label.
+// The assembler only takes ASCII, other characters are written as _uXXXX.
+func labelName(ident string) string {
+	b := strings.Builder{}
+	for _, r := range ident {
+		if r <= unicode.MaxASCII {
+			b.WriteRune(r)
+		} else {
+			fmt.Fprintf(&b, "_u%04X", r)
+		}
+	}
+
+	return b.String()
+}
+
+// Returns the memory the compile-time interpreter runs in.
+// It is kept for the whole compilation so comptime variables can be read by later evaluations.
+func (s *SemanticChecker) ComptimeMemory() *Memory {
+	if s.comptimeMemory == nil {
+		s.comptimeMemory = NewMemory()
+	}
+
+	return s.comptimeMemory
+}
+
+// Returns the size of the current stack frame's variables.
+func (s *SemanticChecker) FrameSize() int {
+	return s.nextAddr
+}
+
+// Declares a user defined type in the current block.
+// Types share their namespace with variables but take up no stack space.
+func (s *SemanticChecker) PushType(ident string, t Type, token lexer.Token) error {
+	if IsBuiltinTypeName(ident) {
+		return s.AddError(diagnostics.ReservedName, fmt.Sprintf("The name '%v' is reserved by the compiler", ident), token)
+	}
+
+	if err := s.checkRedeclaration(ident, token); err != nil {
+		return err
+	}
+
+	s.symbolTable.Push(Symbol{
+		Ident: ident,
+		Type: t,
+		Token: token,
+		IsType: true,
+		Module: s.module,
+	})
+
+	return nil
+}
+
+// Declares an external function in the current block.
+// Like types, functions take up no stack space.
+func (s *SemanticChecker) PushFunction(ident string, fn Function, token lexer.Token) error {
+	if err := s.checkRedeclaration(ident, token); err != nil {
+		return err
+	}
+
+	s.symbolTable.Push(Symbol{
+		Ident: ident,
+		Type: fn,
+		Token: token,
+		Module: s.module,
+	})
+
+	return nil
+}
+
+// Returns the external function visible under the given name.
+func (s *SemanticChecker) LookupFunction(ident string) (Function, bool) {
+	symbolTableData := s.symbolTable.Data()
+	for i := len(symbolTableData) - 1; i >= 0; i-- {
+		symbol := symbolTableData[i]
+		if symbol.Ident == ident && s.isVisible(symbol) {
+			fn, isFunction := symbol.Type.(Function)
+			return fn, isFunction
+		}
+	}
+
+	return Function{}, false
+}
+
+// Returns the types declared at the top level of the main file in declaration order.
+// Libraries export them to C.
+func (s *SemanticChecker) ExportedTypes() []Symbol {
+	exports := []Symbol{}
+	globalIndex := s.blockIndexTable.Data()[1]
+	for _, symbol := range s.symbolTable.Data()[globalIndex:] {
+		if symbol.IsType && symbol.Module == "" {
+			exports = append(exports, symbol)
+		}
+	}
+
+	return exports
+}
+
+// Makes an imported module visible under the given name in the current block.
+func (s *SemanticChecker) PushModule(ident string, token lexer.Token) error {
+	if err := s.checkRedeclaration(ident, token); err != nil {
+		return err
+	}
+
+	s.symbolTable.Push(Symbol{
+		Ident: ident,
+		Type: Undefined{},
+		Token: token,
+		IsModule: true,
+		Module: s.module,
+	})
+
+	return nil
+}
+
+// Reports whether an imported module is visible under the given name.
+func (s *SemanticChecker) LookupModule(ident string) bool {
+	symbolTableData := s.symbolTable.Data()
+	for i := len(symbolTableData) - 1; i >= 0; i-- {
+		symbol := symbolTableData[i]
+		if symbol.Ident == ident && s.isVisible(symbol) {
+			return symbol.IsModule
+		}
+	}
+
+	return false
+}
+
+// Returns the top-level symbol the given module declared under ident.
+func (s *SemanticChecker) LookupQualified(module string, ident string) (*Symbol, bool) {
+	symbolTableData := s.symbolTable.Data()
+	for i := len(symbolTableData) - 1; i >= 0; i-- {
+		symbol := symbolTableData[i]
+		if symbol.Ident == ident && symbol.Module == module && !symbol.IsModule {
+			return &symbol, true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns the symbol a module qualified name such as math.PI refers to.
+func (s *SemanticChecker) GetQualifiedSymbol(module lexer.Token, ident lexer.Token) (*Symbol, error) {
+	if !s.LookupModule(module.Value) {
+		return nil, s.AddError(
+			diagnostics.WrongSymbolKind,
+			fmt.Sprintf("'%v' is not an imported module", module.Value),
+			module,
+		)
+	}
+
+	symbol, exists := s.LookupQualified(module.Value, ident.Value)
+	if !exists {
+		return nil, s.AddError(
+			diagnostics.UnknownMember,
+			fmt.Sprintf("Module %v has no symbol '%v'", module.Value, ident.Value),
+			ident,
+		)
+	}
+
+	if symbol.Failed {
+		return nil, ErrFailedDeclaration
+	}
+
+	return symbol, nil
+}
+
+// Returns the user defined type visible under the given name.
+func (s *SemanticChecker) LookupType(ident string) (Type, bool) {
+	symbolTableData := s.symbolTable.Data()
+	for i := len(symbolTableData) - 1; i >= 0; i-- {
+		symbol := symbolTableData[i]
+		if symbol.Ident == ident && s.isVisible(symbol) {
+			return symbol.Type, symbol.IsType
+		}
+	}
+
+	return Undefined{}, false
+}
+
 func (s *SemanticChecker) TopSymbol() (Symbol, error) {
 	return s.symbolTable.Top()
 }
 
 
+// Marks the start of a spawn block and returns the size of the stack frame the thread copies.
+func (s *SemanticChecker) EnterSpawn() int {
+	s.spawnFrames.Push(s.nextAddr)
+	return s.nextAddr
+}
+
+func (s *SemanticChecker) ExitSpawn() {
+	s.spawnFrames.Pop()
+}
+
+// Reports whether a spawn block is currently checked.
+func (s *SemanticChecker) InSpawn() bool {
+	return s.spawnFrames.Size != 0
+}
+
+// Reports whether a variable lives in the stack frame copied by the innermost enclosing spawn block.
+func (s *SemanticChecker) IsCaptured(symbol Symbol) bool {
+	frame, err := s.spawnFrames.Top()
+	if err != nil {
+		return false
+	}
+
+	return symbol.Label == "" && !symbol.IsType && !symbol.IsModule && symbol.Offset <= frame
+}
+
 func (s *SemanticChecker) PushBlock() {
 	blockStartIndex := s.symbolTable.Size
 	s.blockIndexTable.Push(blockStartIndex)
@@ -111,45 +423,183 @@ func (s *SemanticChecker) PopBlock() int {
 	symbolTableData := s.symbolTable.Data()
 	for i := len(symbolTableData) - 1; i >= topBlockIndex; i-- {
 		symbol, _ := s.symbolTable.Pop()
-		size += symbol.Size
+		size += symbol.Size + symbol.Padding
 	}
+	// The block's slots are released so later symbols are placed above the stack pointer.
+	s.nextAddr -= size
 
 	return size
 }
 
+// Replaces the placeholder types produced by the parser (for example typeof)
+// with the concrete types they stand for.
+func (s *SemanticChecker) ResolveType(t Type) (Type, error) {
+	switch t := t.(type) {
+	case TypeOf:
+		if err := t.Expr.Semantics(s); err != nil {
+			return Undefined{}, err
+		}
+
+		if t.Expr.ExprType().TypeID() == UINT_LIT {
+			return Uint64{}, nil
+		}
+
+		return t.Expr.ExprType(), nil
+	case TypeRef:
+		var symbol *Symbol
+		var err error
+		if t.Module.HasVal() {
+			symbol, err = s.GetQualifiedSymbol(t.Module.Value(), t.Ident)
+		} else {
+			symbol, err = s.GetSymbol(t.Ident)
+		}
+		if err != nil {
+			return Undefined{}, err
+		}
+
+		if !symbol.IsType {
+			return Undefined{}, s.AddError(
+				diagnostics.WrongSymbolKind,
+				fmt.Sprintf("'%v' is not a type", t.Ident.Value),
+				t.Ident,
+			)
+		}
+
+		return symbol.Type, nil
+	case Ptr:
+		valueType, err := s.ResolveType(t.ValueType)
+		if err != nil {
+			return Undefined{}, err
+		}
+
+		return Ptr{ ValueType: valueType }, nil
+	case Array:
+		base, err := s.ResolveType(t.Base)
+		if err != nil {
+			return Undefined{}, err
+		}
+
+		return Array{ Base: base, Length: t.Length }, nil
+	case Slice:
+		base, err := s.ResolveType(t.Base)
+		if err != nil {
+			return Undefined{}, err
+		}
+
+		return Slice{ Base: base }, nil
+	case Optional:
+		base, err := s.ResolveType(t.Base)
+		if err != nil {
+			return Undefined{}, err
+		}
+
+		// An alias or typeof can stand for an optional, which must not be wrapped again.
+		if _, isOptional := base.(Optional); isOptional {
+			return Undefined{}, s.AddError(
+				diagnostics.InvalidDeclaration,
+				fmt.Sprintf("Optionals cannot be nested, '%v' is already optional", base.TypeID()),
+				typeToken(t.Base),
+			)
+		}
+
+		return Optional{ Base: base }, nil
+	}
+
+	return t, nil
+}
+
+// Returns the token naming a placeholder type, for reporting errors about it.
+func typeToken(t Type) lexer.Token {
+	switch t := t.(type) {
+	case TypeRef:
+		return t.Ident
+	case TypeOf:
+		return t.Token
+	}
+
+	return lexer.Token{}
+}
+
 func (s *SemanticChecker) GetSymbol(ident lexer.Token) (*Symbol, error) {
 	symbolTableData := s.symbolTable.Data()
 	for i := len(symbolTableData) - 1; i >= 0; i-- {
 		symbol := symbolTableData[i]
-		if symbol.Ident == ident.Value {
+		if symbol.Ident == ident.Value && s.isVisible(symbol) {
+			if symbol.Failed {
+				return nil, ErrFailedDeclaration
+			}
+
 			return &symbol, nil
 		}
 	}
 
 	return nil, s.AddError(
+		diagnostics.UndeclaredSymbol,
 		fmt.Sprintf("Undeclared symbol '%v'", ident.Value),
 		ident,
 	)
 }
 
-func (s SemanticChecker) TopBlockHasSymbol(ident string) bool {
+func (s SemanticChecker) topBlockSymbol(ident string) (Symbol, bool) {
 	topBlockIndex, err := s.blockIndexTable.Top()
 	if err != nil {
-		return false
+		return Symbol{}, false
 	}
 	
 	symbolTableData := s.symbolTable.Data()
 	for i := len(symbolTableData) - 1; i >= topBlockIndex; i-- {
 		symbol := symbolTableData[i]
-		if symbol.Ident == ident {
-			return true
+		if symbol.Ident == ident && s.isVisible(symbol) {
+			return symbol, true
 		}
 	}
 	
-	return false
+	return Symbol{}, false
+}
+
+// Reports an error when ident is already declared in the current block.
+func (s *SemanticChecker) checkRedeclaration(ident string, token lexer.Token) error {
+	previous, isDeclared := s.topBlockSymbol(ident)
+	if !isDeclared {
+		return nil
+	}
+
+	d := s.AddError(
+		diagnostics.Redeclaration,
+		fmt.Sprintf("Redeclaration of symbol '%v'", ident),
+		token,
+	)
+
+	// Builtins have no position in the source.
+	if previous.Token.Line != 0 {
+		d.WithNote(s.file, previous.Token.Span(), fmt.Sprintf("'%v' was first declared here", ident))
+	}
+
+	return d
+}
+
+// Reports whether a symbol can be referred to by its unqualified name from the current module.
+func (s SemanticChecker) isVisible(symbol Symbol) bool {
+	return symbol.Module == "" || symbol.Module == s.module
+}
+
+// Rounds x up to the next multiple of n.
+func alignTo(x int, n int) int {
+	if n <= 1 {
+		return x
+	}
+
+	remainder := x % n
+
+	if remainder == 0 {
+		return x
+	}
+
+	return x + (n - remainder)
 }
 
-func align16(x int) int {
+// Rounds x up to the next multiple of 16, the stack alignment required by the System V ABI.
+func Align16(x int) int {
     remainder := x % 16
 
     if remainder == 0 {
@@ -0,0 +1,75 @@
+package semantics_test
+
+import (
+	"clovis/compiler"
+	"clovis/diagnostics"
+	"clovis/parser"
+	"context"
+	"fmt"
+	"testing"
+)
+
+// Checks the program src. Options such as the imported files can be changed by configure.
+func compile(t *testing.T, src string, configure ...func(*compiler.Options)) *compiler.Result {
+	t.Helper()
+
+	opts := compiler.Options{
+		Main: "main.clv",
+		Sources: map[string]string{ "main.clv": src },
+		Emit: compiler.EmitAST,
+	}
+	for _, c := range configure {
+		c(&opts)
+	}
+
+	result, err := compiler.Compile(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	return result
+}
+
+// Checks src and fails unless it reports exactly the given codes in order.
+func expectCodes(t *testing.T, src string, codes ...diagnostics.Code) []*diagnostics.Diagnostic {
+	t.Helper()
+
+	result := compile(t, src)
+	received := []diagnostics.Code{}
+	for _, d := range result.Diagnostics {
+		received = append(received, d.Code)
+	}
+
+	if fmt.Sprint(received) != fmt.Sprint(codes) {
+		t.Fatalf("Expected codes %v but received %v", codes, result.Diagnostics)
+	}
+
+	return result.Diagnostics
+}
+
+// Fails unless src checks without diagnostics.
+func expectValid(t *testing.T, src string) {
+	t.Helper()
+	expectCodes(t, src)
+}
+
+// Returns the statements of the checked program src and fails if it has any diagnostic.
+func check(t *testing.T, src string) []parser.Statement {
+	t.Helper()
+
+	result := compile(t, src)
+	if len(result.Diagnostics) != 0 {
+		t.Fatalf("Expected no diagnostics but received %v", result.Diagnostics)
+	}
+
+	return result.Modules[len(result.Modules) - 1].Stmts
+}
+
+// Adds the imported files to the program.
+func withFiles(files map[string]string) func(*compiler.Options) {
+	return func(opts *compiler.Options) {
+		for path, src := range files {
+			opts.Sources[path] = src
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestSliceTypes(t *testing.T) {
+	stmts := check(t, `
+		uint16[8] buffer;
+		uint16[] head = buffer[0..4];
+		uint16[] tail = head[2..];
+		uint16[] all = buffer[..];
+		uint64 n = tail.len;
+		uint16 first = tail[0];
+		static_assert sizeof(head) == 16;
+	`)
+
+	slice := stmts[1].(*parser.VarDeclStmt).Right.Value().(*parser.SliceExpression)
+	if !slice.ExprType().Equals(semantics.Slice{ Base: semantics.Uint16{} }) {
+		t.Errorf("Expected a UINT16 slice but received %v", slice.ExprType().TypeID())
+	}
+}
+
+func TestSliceErrors(t *testing.T) {
+	expectCodes(t, `uint8 x; uint8[] s = x[0..1];`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint8[4] a; uint8[] s = a[0..5];`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint8[4] a; uint8[] s = a[true..2];`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint8[4] a; uint16[] s = a[0..2];`, diagnostics.TypeMismatch)
+	expectCodes(t, `uint8[4] a; uint8[] s = a[3..1];`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint8[4] a; uint8[] s = a[..]; uint8[] t = s[2..1];`, diagnostics.InvalidOperand)
+	expectValid(t, `uint8[4] a; uint8[] s = a[2..2];`)
+}
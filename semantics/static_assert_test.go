@@ -0,0 +1,31 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"strings"
+	"testing"
+)
+
+func TestStaticAssertConstants(t *testing.T) {
+	expectValid(t, `
+		uint8[8] large;
+		uint8[4] small;
+		static_assert large.len == small.len * 2, "large must hold two smalls";
+		static_assert sizeof(uint64) == 8;
+		static_assert (1 < 2 ? 3 : 4) == 3;
+		static_assert (255 as uint8 + 1 as uint8) == 0;
+		enum Color : uint8 { Red, Blue = 10 }
+		static_assert Color.Blue as uint8 == 10;
+	`)
+}
+
+func TestStaticAssertErrors(t *testing.T) {
+	d := expectCodes(t, `static_assert 1 == 2, "one is two";`, diagnostics.StaticAssertFailed)
+	if !strings.Contains(d[0].Message, "one is two") || d[0].Span.Start.Col != 1 {
+		t.Errorf("Expected the message at the keyword but received %v", d[0])
+	}
+
+	expectCodes(t, `uint64 x = 1; static_assert x == 1;`, diagnostics.NotConstant)
+	expectCodes(t, `static_assert 1;`, diagnostics.InvalidOperand)
+	expectCodes(t, `static_assert 1 / 0 == 0;`, diagnostics.NotConstant)
+}
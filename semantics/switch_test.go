@@ -0,0 +1,56 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"strings"
+	"testing"
+)
+
+func TestSwitchCases(t *testing.T) {
+	stmts := check(t, `
+		uint8 x = 3;
+		switch x {
+			case 1, 2: x = 0;
+			case 20..29: { x = 1; }
+			default: x = 2;
+		}
+	`)
+
+	stmt := stmts[1].(*parser.SwitchStmt)
+	if len(stmt.Arms) != 3 || !stmt.Arms[2].IsDefault {
+		t.Fatalf("Expected two case arms and a default arm but received %v", stmt.Print(0))
+	}
+
+	cases := stmt.Arms[1].Cases
+	if len(cases) != 1 || cases[0].LowValue != 20 || cases[0].HighValue != 29 {
+		t.Errorf("Expected the range 20..29 but received %v", stmt.Print(0))
+	}
+}
+
+func TestSwitchErrors(t *testing.T) {
+	expectCodes(t, `bool b; switch b { default: b = true; }`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint8 x; switch x { case 1: x = 1; case 1: x = 2; }`, diagnostics.InvalidArms)
+	expectCodes(t, `uint8 x; switch x { case 1..5: x = 1; case 5: x = 2; }`, diagnostics.InvalidArms)
+	expectCodes(t, `uint8 x; switch x { case 5..1: x = 1; }`, diagnostics.InvalidArms)
+	expectCodes(t, `uint8 x; switch x { default: x = 1; default: x = 2; }`, diagnostics.InvalidArms)
+	expectCodes(t, `uint8 x; switch x { case 300: x = 1; }`, diagnostics.InvalidArms)
+	expectCodes(t, `uint8 x; uint8 y; switch x { case y: x = 1; }`, diagnostics.NotConstant)
+}
+
+func TestSwitchEnumExhaustiveness(t *testing.T) {
+	d := expectCodes(t, `
+		enum Color : uint8 { Red, Green, Blue }
+		Color c;
+		switch c { case Color.Red: c = Color.Green; }
+	`, diagnostics.InvalidArms)
+	if !strings.Contains(d[0].Message, "Green, Blue") {
+		t.Errorf("Expected the missing members in %q", d[0].Message)
+	}
+
+	expectValid(t, `
+		enum Color : uint8 { Red, Green, Blue }
+		Color c;
+		switch c { case Color.Red, Color.Green..Color.Blue: c = Color.Green; }
+	`)
+}
@@ -0,0 +1,31 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestSyscallArguments(t *testing.T) {
+	stmts := check(t, `
+		uint8[5] buf;
+		uint64 written = syscall(1, 1, buf, buf.len);
+		uint64 pid = syscall(39);
+		bool b = true;
+		uint32 n = 2;
+		syscall(60, b, n);
+	`)
+
+	call := stmts[1].(*parser.VarDeclStmt).Right.Value().(*parser.SyscallExpression)
+	if len(call.Args) != 4 || call.ExprType().TypeID() != semantics.UINT64 {
+		t.Errorf("Expected a UINT64 syscall with 4 values but received %v", call.Print(0))
+	}
+}
+
+func TestSyscallErrors(t *testing.T) {
+	expectCodes(t, `uint64 r = syscall();`, diagnostics.ArgumentCount)
+	expectCodes(t, `uint64 r = syscall(1, 1, 2, 3, 4, 5, 6, 7);`, diagnostics.ArgumentCount)
+	expectCodes(t, `uint64 r = syscall(true);`, diagnostics.InvalidOperand)
+	expectCodes(t, `union U { A(uint64, uint64) } U u = U.A(1, 2); uint64 r = syscall(1, u);`, diagnostics.RegisterSize)
+}
@@ -0,0 +1,43 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestThreadTypes(t *testing.T) {
+	stmts := check(t, `
+		shared uint64 counter = 0;
+		uint32 local = 1;
+		Thread t = spawn {
+			uint64 before = atomic_add(&counter, 1);
+			uint32 mine = local;
+			uint32* p = &mine;
+		};
+		join(t);
+		uint64 now = atomic_load(&counter);
+		bool swapped = atomic_cas(&counter, 1, 2);
+		atomic_store(&counter, 0);
+	`)
+
+	spawn := stmts[2].(*parser.VarDeclStmt).Right.Value().(*parser.SpawnExpression)
+	if !spawn.ExprType().Equals(semantics.Thread) || spawn.FrameSize == 0 {
+		t.Errorf("Expected a Thread copying the enclosing frame but received %v", spawn.Print(0))
+	}
+
+	cas := stmts[5].(*parser.VarDeclStmt).Right.Value().(*parser.AtomicExpression)
+	if cas.ExprType().TypeID() != semantics.BOOL {
+		t.Errorf("Expected atomic_cas to be BOOL but received %v", cas.ExprType().TypeID())
+	}
+}
+
+func TestThreadErrors(t *testing.T) {
+	expectCodes(t, `uint64 x = 1; Thread t = spawn { uint64* p = &x; };`, diagnostics.Misplaced)
+	expectCodes(t, `Thread t = spawn { exit(1); };`, diagnostics.Misplaced)
+	expectCodes(t, `uint64 x = 1; join(x);`, diagnostics.InvalidOperand)
+	expectCodes(t, `uint64 x = atomic_load();`, diagnostics.ArgumentCount)
+	expectCodes(t, `bool b; bool c = atomic_load(&b);`, diagnostics.InvalidOperand)
+	expectCodes(t, `shared uint32 x = 0; uint32 y = atomic_add(&x, true);`, diagnostics.TypeMismatch)
+}
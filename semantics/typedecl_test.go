@@ -0,0 +1,77 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"clovis/semantics"
+	"testing"
+)
+
+func TestTypeAlias(t *testing.T) {
+	stmts := check(t, `
+		type Meters = uint32;
+		Meters m = 5;
+		uint32 raw = m;
+		Meters back = raw;
+	`)
+
+	stmt := stmts[0].(*parser.TypeDeclStmt)
+	if !stmt.IsAlias || stmt.Type.TypeID() != semantics.UINT32 {
+		t.Errorf("Expected Meters to alias UINT32 but received %v", stmt.Print(0))
+	}
+}
+
+func TestDistinctType(t *testing.T) {
+	stmts := check(t, `
+		type UserId uint64;
+		UserId id = 5;
+		uint64 raw = id as uint64;
+		UserId other = raw as UserId;
+		static_assert sizeof(UserId) == 8;
+	`)
+
+	named, isNamed := stmts[0].(*parser.TypeDeclStmt).Type.(semantics.Named)
+	if !isNamed || named.Name != "UserId" || named.Underlying.TypeID() != semantics.UINT64 {
+		t.Errorf("Expected the distinct type UserId over UINT64 but received %v", stmts[0].Print(0))
+	}
+
+	expectCodes(t, `type UserId uint64; UserId id = 5; uint64 raw = id;`, diagnostics.TypeMismatch)
+	expectCodes(t, `type A uint64; type B uint64; A a; B b = a;`, diagnostics.TypeMismatch)
+}
+
+func TestTypeDeclErrors(t *testing.T) {
+	expectCodes(t, `type T = Missing;`, diagnostics.UndeclaredSymbol)
+	expectCodes(t, `type T = uint8; type T = uint16;`, diagnostics.Redeclaration)
+	expectCodes(t, `uint8 T; T x;`, diagnostics.WrongSymbolKind)
+}
+
+// Distinct types are told apart by their declaration, not their name.
+func TestDistinctTypeIdentity(t *testing.T) {
+	expectCodes(t, `
+		type UserId uint64;
+		UserId outer = 5;
+		{
+			type UserId uint8;
+			UserId inner = outer;
+		}
+	`, diagnostics.TypeMismatch)
+
+	// Aliases keep the identity of the type they name.
+	expectValid(t, `type UserId uint64; type Id = UserId; UserId a = 5; Id b = a; UserId* p = &b;`)
+
+	ids := withFiles(map[string]string{ "ids.clv": `type UserId uint64;` })
+	result := compile(t, `import "ids.clv"; type UserId uint64; ids.UserId a = 5; UserId b = a;`, ids)
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Code != diagnostics.TypeMismatch {
+		t.Errorf("Expected distinct types of different modules not to mix but received %v", result.Diagnostics)
+	}
+}
+
+// Builtin type names are reserved so user types cannot stand in for them.
+func TestBuiltinTypeNamesReserved(t *testing.T) {
+	for _, name := range []string{ "UINT64", "BOOL", "Errno", "IoResult", "Thread" } {
+		expectCodes(t, "type " + name + " uint8;", diagnostics.ReservedName)
+	}
+
+	expectCodes(t, `union Thread { A }`, diagnostics.ReservedName)
+	expectValid(t, `Errno e = 4 as Errno; Thread* t;`)
+}
@@ -0,0 +1,28 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"testing"
+)
+
+func TestTypeInfoValues(t *testing.T) {
+	expectValid(t, `
+		uint8[3] bytes;
+		uint32* p;
+		static_assert sizeof(uint32) == 4;
+		static_assert alignof(uint16) == 2;
+		static_assert sizeof(bytes) == 3;
+		static_assert alignof(bytes) == 1;
+		static_assert sizeof(p) == 8;
+		static_assert sizeof(bytes[0]) == 1;
+		typeof(bytes) copy;
+		static_assert sizeof(copy) == sizeof(bytes);
+		typeof(p) q = p;
+	`)
+}
+
+func TestTypeInfoErrors(t *testing.T) {
+	expectCodes(t, `uint64 n = sizeof(missing);`, diagnostics.UndeclaredSymbol)
+	expectCodes(t, `typeof(missing) x;`, diagnostics.UndeclaredSymbol)
+	expectCodes(t, `uint32 n = sizeof(uint8);`, diagnostics.TypeMismatch)
+}
@@ -1,6 +1,10 @@
 package semantics
 
-import "fmt"
+import (
+	"clovis/lexer"
+	"clovis/utils"
+	"fmt"
+)
 
 // A unique type identifier represented as a string.
 // Often times the name of the given type.
@@ -9,11 +13,15 @@ const (
 	UNDEFINED TypeID = "UNDEFINED"
 	PTR TypeID = "PTR"
 	UINT_LIT TypeID = "UINT_LIT"
+	NONE_LIT TypeID = "NONE_LIT"
 	UINT64 TypeID = "UINT64"
 	UINT32 TypeID = "UINT32"
 	UINT16 TypeID = "UINT16"
 	UINT8 TypeID = "UINT8"
 	BOOL TypeID = "BOOL"
+	TYPEOF TypeID = "TYPEOF"
+	TYPE_REF TypeID = "TYPE_REF"
+	FUNCTION TypeID = "FUNCTION"
 )
 
 // Any type implementing this interface can be used as a type in the compiler.
@@ -22,6 +30,8 @@ type Type interface {
 	TypeID() TypeID
 	// Size of the type in bytes.
 	Size() int
+	// Alignment of the type in bytes.
+	Align() int
 	// Which part of the rax register the type can be or is stored in.
 	Register() string
 	// The x86_64 nasm assembly size specifier.
@@ -36,6 +46,27 @@ type Type interface {
 	CanUseUnaryOperator(op string) (bool, Type)
 }
 
+// Where a user defined type is declared. Types declared by different statements are
+// different types even when they share a name. Builtin types have no declaration.
+type Decl struct {
+	// The path of the declaring file.
+	File   string
+	// The byte offset of the declared name in the file.
+	Offset int
+	// The module declaring the type, empty for the main file.
+	Module string
+}
+
+// Returns the name a type declared here is shown as. Types of imported modules are qualified
+// by their module, as in math.Vec, so they cannot be mistaken for types of the same name.
+func (d Decl) Qualify(name string) TypeID {
+	if d.Module == "" {
+		return TypeID(name)
+	}
+
+	return TypeID(fmt.Sprintf("%v.%v", d.Module, name))
+}
+
 // This type is used during parsing where the specific type cannot be deduced yet.
 type Undefined struct {}
 
@@ -47,6 +78,10 @@ func (_ Undefined) Size() int {
 	return 8
 }
 
+func (_ Undefined) Align() int {
+	return 8
+}
+
 func (_ Undefined) Register() string {
 	return "rax"
 }
@@ -78,6 +113,10 @@ func (_ UintLiteral) Size() int {
 	return 8
 }
 
+func (_ UintLiteral) Align() int {
+	return 8
+}
+
 func (_ UintLiteral) Register() string {
 	return "rax"
 }
@@ -109,6 +148,46 @@ func (_ UintLiteral) CanUseUnaryOperator(op string) (bool, Type) {
 	return false, Undefined{}
 }
 
+// Represents the none literal. Only usable where an optional type is expected
+// and in comparisons with optionals.
+type NoneLiteral struct {}
+
+func (_ NoneLiteral) TypeID() TypeID {
+	return NONE_LIT
+}
+
+func (_ NoneLiteral) Size() int {
+	return 0
+}
+
+func (_ NoneLiteral) Align() int {
+	return 1
+}
+
+func (_ NoneLiteral) Register() string {
+	return "rax"
+}
+
+func (_ NoneLiteral) ASMSize() string {
+	return "QWORD"
+}
+
+func (_ NoneLiteral) Equals(other Type) bool {
+	return other.TypeID() == NONE_LIT
+}
+
+func (_ NoneLiteral) CanUseOperator(op string, operand Type) (bool, Type) {
+	if _, isOptional := operand.(Optional); isOptional {
+		return operand.CanUseOperator(op, NoneLiteral{})
+	}
+
+	return false, Undefined{}
+}
+
+func (_ NoneLiteral) CanUseUnaryOperator(op string) (bool, Type) {
+	return false, Undefined{}
+}
+
 // Unsigned 64 bit integer.
 type Uint64 struct {}
 
@@ -120,6 +199,10 @@ func (_ Uint64) Size() int {
 	return 8
 }
 
+func (_ Uint64) Align() int {
+	return 8
+}
+
 func (_ Uint64) Register() string {
 	return "rax"
 }
@@ -166,6 +249,10 @@ func (_ Uint32) Size() int {
 	return 4
 }
 
+func (_ Uint32) Align() int {
+	return 4
+}
+
 func (_ Uint32) Register() string {
 	return "eax"
 }
@@ -212,6 +299,10 @@ func (_ Uint16) Size() int {
 	return 2
 }
 
+func (_ Uint16) Align() int {
+	return 2
+}
+
 func (_ Uint16) Register() string {
 	return "ax"
 }
@@ -258,6 +349,10 @@ func (_ Uint8) Size() int {
 	return 1
 }
 
+func (_ Uint8) Align() int {
+	return 1
+}
+
 func (_ Uint8) Register() string {
 	return "al"
 }
@@ -304,6 +399,10 @@ func (_ Bool) Size() int {
 	return 1
 }
 
+func (_ Bool) Align() int {
+	return 1
+}
+
 func (_ Bool) Register() string {
 	return "al"
 }
@@ -350,6 +449,10 @@ func (_ Ptr) Size() int {
 	return 8
 }
 
+func (_ Ptr) Align() int {
+	return 8
+}
+
 func (_ Ptr) Register() string {
 	return "rax"
 }
@@ -359,11 +462,11 @@ func (_ Ptr) ASMSize() string {
 }
 
 func (p Ptr) Equals(other Type) bool {
-	return p.TypeID() == other.TypeID()
+	return Identical(p, other)
 }
 
 func (p Ptr) CanUseOperator(op string, operand Type) (bool, Type) {
-	if p.TypeID() != operand.TypeID() {
+	if !Identical(p, operand) {
 		return false, Undefined{}
 	}
 	
@@ -401,6 +504,10 @@ func (a Array) Size() int {
 	return a.Length * a.Base.Size()
 }
 
+func (a Array) Align() int {
+	return a.Base.Align()
+}
+
 func (_ Array) Register() string {
 	return "rax"
 }
@@ -411,7 +518,7 @@ func (_ Array) ASMSize() string {
 
 func (a Array) Equals(other Type) bool {
 	arrayType, isArray := other.(Array)
-	return (a.TypeID() == other.TypeID()) || (isArray && a.Base.Equals(arrayType.Base))
+	return Identical(a, other) || (isArray && a.Base.Equals(arrayType.Base))
 }
 
 func (a Array) CanUseOperator(op string, operand Type) (bool, Type) {
@@ -430,12 +537,586 @@ func (a Array) CanUseUnaryOperator(op string) (bool, Type) {
 	return false, Undefined{}
 }
 
+// A view into a run of elements: a pointer to the first element and a length.
+// Slices take up 16 bytes, the pointer followed by the length.
+// When evaluated the pointer is held in rax and the length in rdx.
+type Slice struct {
+	Base Type
+}
+
+func (s Slice) TypeID() TypeID {
+	return TypeID(fmt.Sprintf("%v_SLICE", s.Base.TypeID()))
+}
+
+func (_ Slice) Size() int {
+	return 16
+}
+
+func (_ Slice) Align() int {
+	return 8
+}
+
+func (_ Slice) Register() string {
+	return "rax"
+}
+
+func (_ Slice) ASMSize() string {
+	return "QWORD"
+}
+
+func (s Slice) Equals(other Type) bool {
+	return Identical(s, other)
+}
+
+func (s Slice) CanUseOperator(op string, operand Type) (bool, Type) {
+	if !s.Equals(operand) {
+		return false, Undefined{}
+	}
+
+	if op == "=" {
+		return true, s
+	}
+
+	return false, Undefined{}
+}
+
+func (s Slice) CanUseUnaryOperator(op string) (bool, Type) {
+	if op == "&" {
+		return true, Ptr{ ValueType: s }
+	}
+
+	return false, Undefined{}
+}
+
+// A value of the base type or no value at all.
+// Example:
+//	uint32? x = none;
+// A presence flag is stored first and the value follows at its alignment.
+// Like arrays, optionals evaluate to their address.
+type Optional struct {
+	Base Type
+}
+
+// Byte offset of the wrapped value from the start of the optional.
+func (o Optional) ValueOffset() int {
+	return alignTo(Bool{}.Size(), o.Base.Align())
+}
+
+func (o Optional) TypeID() TypeID {
+	return TypeID(fmt.Sprintf("%v_OPTIONAL", o.Base.TypeID()))
+}
+
+func (o Optional) Size() int {
+	return alignTo(o.ValueOffset() + o.Base.Size(), o.Align())
+}
+
+func (o Optional) Align() int {
+	return max(Bool{}.Align(), o.Base.Align())
+}
+
+func (_ Optional) Register() string {
+	return "rax"
+}
+
+func (_ Optional) ASMSize() string {
+	return "QWORD"
+}
+
+func (o Optional) Equals(other Type) bool {
+	return Identical(o, other)
+}
+
+func (o Optional) CanUseOperator(op string, operand Type) (bool, Type) {
+	// Comparing with none tests the presence flag.
+	if operand.TypeID() == NONE_LIT && (op == "==" || op == "!=") {
+		return true, Bool{}
+	}
+
+	if !o.Equals(operand) {
+		return false, Undefined{}
+	}
+
+	if op == "=" {
+		return true, o
+	}
+
+	return false, Undefined{}
+}
+
+func (o Optional) CanUseUnaryOperator(op string) (bool, Type) {
+	if op == "&" {
+		return true, Ptr{ ValueType: o }
+	}
+
+	return false, Undefined{}
+}
+
+// Returns whether a value of type from is implicitly wrapped when used where the optional type to is expected.
+// This is the case for none and values of the optional's base type.
+func WrapsInto(from Type, to Type) bool {
+	optional, isOptional := to.(Optional)
+	if !isOptional || optional.Equals(from) {
+		return false
+	}
+
+	return from.TypeID() == NONE_LIT || optional.Base.Equals(from)
+}
+
+// A distinct user defined type with the representation of another type.
+// Example:
+//	type UserId uint64;
+// Values of a named type only mix with values of the same type and unsigned integer literals,
+// converting from or to the underlying type needs an explicit cast.
+type Named struct {
+	Name       string
+	Decl       Decl
+	// The type the named type is represented as. Never a Named type itself.
+	Underlying Type
+}
+
+func (n Named) TypeID() TypeID {
+	return n.Decl.Qualify(n.Name)
+}
+
+func (n Named) Size() int {
+	return n.Underlying.Size()
+}
+
+func (n Named) Align() int {
+	return n.Underlying.Align()
+}
+
+func (n Named) Register() string {
+	return n.Underlying.Register()
+}
+
+func (n Named) ASMSize() string {
+	return n.Underlying.ASMSize()
+}
+
+func (n Named) Equals(other Type) bool {
+	if other.TypeID() == UINT_LIT {
+		return IsNumber(n.Underlying)
+	}
+
+	return Identical(n, other)
+}
+
+func (n Named) CanUseOperator(op string, operand Type) (bool, Type) {
+	if !n.Equals(operand) {
+		return false, Undefined{}
+	}
+
+	ok, result := n.Underlying.CanUseOperator(op, n.Underlying)
+	if !ok {
+		return false, Undefined{}
+	}
+
+	switch op {
+	case "==", "<", ">", "<=", ">=", "!=":
+		return true, result
+	}
+
+	return true, n
+}
+
+func (n Named) CanUseUnaryOperator(op string) (bool, Type) {
+	if op == "&" {
+		return true, Ptr{ ValueType: n }
+	}
+
+	return n.Underlying.CanUseUnaryOperator(op)
+}
+
+// A variant of a tagged union and the types of its payload fields.
+type UnionVariant struct {
+	Ident   string
+	Fields  []Type
+	// Byte offsets of the fields from the start of the union.
+	Offsets []int
+}
+
+// A tagged union (sum type) holding exactly one of its variants.
+// Example:
+//	union Shape { Circle(uint32), Rect(uint32, uint32) }
+// The discriminant is stored first and is the index of the active variant,
+// the payload follows and is sized to the largest variant.
+// Like arrays, unions evaluate to their address.
+type Union struct {
+	Name     string
+	Decl     Decl
+	Variants []UnionVariant
+}
+
+// Creates a union and lays out the payload fields of its variants after the discriminant.
+func NewUnion(name string, decl Decl, variants []UnionVariant) Union {
+	u := Union{ Name: name, Decl: decl, Variants: variants }
+	payloadOffset := alignTo(u.TagType().Size(), u.Align())
+
+	for i := range u.Variants {
+		variant := &u.Variants[i]
+		variant.Offsets = []int{}
+		offset := payloadOffset
+
+		for _, field := range variant.Fields {
+			offset = alignTo(offset, field.Align())
+			variant.Offsets = append(variant.Offsets, offset)
+			offset += field.Size()
+		}
+	}
+
+	return u
+}
+
+// The type of the discriminant.
+func (u Union) TagType() Type {
+	if len(u.Variants) <= 256 {
+		return Uint8{}
+	}
+
+	return Uint16{}
+}
+
+// Returns the variant with the given name and its discriminant.
+func (u Union) Variant(ident string) (UnionVariant, int, bool) {
+	for i, variant := range u.Variants {
+		if variant.Ident == ident {
+			return variant, i, true
+		}
+	}
+
+	return UnionVariant{}, 0, false
+}
+
+func (u Union) TypeID() TypeID {
+	return u.Decl.Qualify(u.Name)
+}
+
+func (u Union) Size() int {
+	size := u.TagType().Size()
+	for _, variant := range u.Variants {
+		if fieldCount := len(variant.Fields); fieldCount != 0 {
+			size = max(size, variant.Offsets[fieldCount - 1] + variant.Fields[fieldCount - 1].Size())
+		}
+	}
+
+	return alignTo(size, u.Align())
+}
+
+func (u Union) Align() int {
+	align := u.TagType().Align()
+	for _, variant := range u.Variants {
+		for _, field := range variant.Fields {
+			align = max(align, field.Align())
+		}
+	}
+
+	return align
+}
+
+func (_ Union) Register() string {
+	return "rax"
+}
+
+func (_ Union) ASMSize() string {
+	return "QWORD"
+}
+
+func (u Union) Equals(other Type) bool {
+	return Identical(u, other)
+}
+
+func (u Union) CanUseOperator(op string, operand Type) (bool, Type) {
+	if !u.Equals(operand) {
+		return false, Undefined{}
+	}
+
+	if op == "=" {
+		return true, u
+	}
+
+	return false, Undefined{}
+}
+
+func (u Union) CanUseUnaryOperator(op string) (bool, Type) {
+	if op == "&" {
+		return true, Ptr{ ValueType: u }
+	}
+
+	return false, Undefined{}
+}
+
+// Any expression whose type can be queried after it has been checked.
+// Declared here so types can refer to expressions without depending on the parser.
+type TypedExpression interface {
+	ExprType() Type
+	Semantics(s *SemanticChecker) error
+}
+
+// The type of an expression used in a type position.
+// Example:
+//	typeof(x) y = x;
+// This is a placeholder until SemanticChecker.ResolveType checks the expression.
+type TypeOf struct {
+	// The typeof keyword. Used for error handling.
+	Token lexer.Token
+	Expr  TypedExpression
+}
+
+func (_ TypeOf) TypeID() TypeID {
+	return TYPEOF
+}
+
+func (_ TypeOf) Size() int {
+	return 8
+}
+
+func (_ TypeOf) Align() int {
+	return 8
+}
+
+func (_ TypeOf) Register() string {
+	return "rax"
+}
+
+func (_ TypeOf) ASMSize() string {
+	return ""
+}
+
+func (_ TypeOf) Equals(other Type) bool {
+	return false
+}
+
+func (_ TypeOf) CanUseOperator(op string, operand Type) (bool, Type) {
+	return false, Undefined{}
+}
+
+func (_ TypeOf) CanUseUnaryOperator(op string) (bool, Type) {
+	return false, Undefined{}
+}
+
+// A user defined type referred to by its name.
+// This is a placeholder until SemanticChecker.ResolveType looks the name up.
+type TypeRef struct {
+	// Set when the type is qualified by the module declaring it, as in math.Vec.
+	Module utils.Optional[lexer.Token]
+	Ident  lexer.Token
+}
+
+func (_ TypeRef) TypeID() TypeID {
+	return TYPE_REF
+}
+
+func (_ TypeRef) Size() int {
+	return 8
+}
+
+func (_ TypeRef) Align() int {
+	return 8
+}
+
+func (_ TypeRef) Register() string {
+	return "rax"
+}
+
+func (_ TypeRef) ASMSize() string {
+	return ""
+}
+
+func (_ TypeRef) Equals(other Type) bool {
+	return false
+}
+
+func (_ TypeRef) CanUseOperator(op string, operand Type) (bool, Type) {
+	return false, Undefined{}
+}
+
+func (_ TypeRef) CanUseUnaryOperator(op string) (bool, Type) {
+	return false, Undefined{}
+}
+
+// The signature of an external function declared with extern.
+// Functions are not values, they can only be called.
+type Function struct {
+	Name     string
+	Params   []Type
+	// Undefined when the function returns nothing.
+	Return   Type
+	// Whether further arguments may follow the declared parameters, like in C's printf.
+	Variadic bool
+}
+
+func (_ Function) TypeID() TypeID {
+	return FUNCTION
+}
+
+func (_ Function) Size() int {
+	return 0
+}
+
+func (_ Function) Align() int {
+	return 1
+}
+
+func (_ Function) Register() string {
+	return ""
+}
+
+func (_ Function) ASMSize() string {
+	return ""
+}
+
+func (_ Function) Equals(other Type) bool {
+	return false
+}
+
+func (_ Function) CanUseOperator(op string, operand Type) (bool, Type) {
+	return false, Undefined{}
+}
+
+func (_ Function) CanUseUnaryOperator(op string) (bool, Type) {
+	return false, Undefined{}
+}
+
+// A named constant of an enum.
+type EnumMember struct {
+	Ident string
+	Value uint64
+}
+
+// An enumeration of named constants stored as an unsigned integer.
+// Example:
+//	enum Color : uint8 { Red, Green, Blue = 10 }
+// Enum values only mix with values of the same enum, converting needs an explicit cast.
+type Enum struct {
+	Name    string
+	Decl    Decl
+	// The unsigned integer type the values are stored as.
+	Backing Type
+	Members []EnumMember
+}
+
+func (e Enum) TypeID() TypeID {
+	return e.Decl.Qualify(e.Name)
+}
+
+func (e Enum) Size() int {
+	return e.Backing.Size()
+}
+
+func (e Enum) Align() int {
+	return e.Backing.Align()
+}
+
+func (e Enum) Register() string {
+	return e.Backing.Register()
+}
+
+func (e Enum) ASMSize() string {
+	return e.Backing.ASMSize()
+}
+
+func (e Enum) Equals(other Type) bool {
+	return Identical(e, other)
+}
+
+func (e Enum) CanUseOperator(op string, operand Type) (bool, Type) {
+	if !e.Equals(operand) {
+		return false, Undefined{}
+	}
+
+	switch op {
+	case "=":
+		return true, e
+	case "==", "!=":
+		return true, Bool{}
+	}
+
+	return false, Undefined{}
+}
+
+func (e Enum) CanUseUnaryOperator(op string) (bool, Type) {
+	if op == "&" {
+		return true, Ptr{ ValueType: e }
+	}
+
+	return false, Undefined{}
+}
+
+// Returns the member with the given name.
+func (e Enum) Member(ident string) (EnumMember, bool) {
+	for _, member := range e.Members {
+		if member.Ident == ident {
+			return member, true
+		}
+	}
+
+	return EnumMember{}, false
+}
+
 // ---------------------------------------------------
 //                  HELPER FUNCTIONS
 // ---------------------------------------------------
 
+// Returns the type a named type is represented as, other types are returned as is.
+func Underlying(t Type) Type {
+	if named, isNamed := t.(Named); isNamed {
+		return named.Underlying
+	}
+
+	return t
+}
+
+// Returns whether a and b are the very same type. Unlike Equals no literal converts to
+// another type, and user defined types are only identical to the declaration they come from.
+func Identical(a Type, b Type) bool {
+	switch a := a.(type) {
+	case Ptr:
+		other, isPtr := b.(Ptr)
+		return isPtr && Identical(a.ValueType, other.ValueType)
+	case Array:
+		other, isArray := b.(Array)
+		return isArray && a.Length == other.Length && Identical(a.Base, other.Base)
+	case Slice:
+		other, isSlice := b.(Slice)
+		return isSlice && Identical(a.Base, other.Base)
+	case Optional:
+		other, isOptional := b.(Optional)
+		return isOptional && Identical(a.Base, other.Base)
+	case Enum:
+		other, isEnum := b.(Enum)
+		return isEnum && a.Name == other.Name && a.Decl == other.Decl
+	case Named:
+		other, isNamed := b.(Named)
+		return isNamed && a.Name == other.Name && a.Decl == other.Decl
+	case Union:
+		other, isUnion := b.(Union)
+		return isUnion && a.Name == other.Name && a.Decl == other.Decl
+	}
+
+	return a.TypeID() == b.TypeID()
+}
+
+// Reports whether name is taken by a builtin type, either as its TypeID or as the name programs
+// use for it. Checks such as whether a condition is a BOOL compare TypeIDs, and builtin types
+// have no declaration to tell them apart, so user defined types cannot be named like them.
+func IsBuiltinTypeName(name string) bool {
+	switch TypeID(name) {
+	case UNDEFINED, PTR, UINT_LIT, NONE_LIT, UINT64, UINT32, UINT16, UINT8, BOOL, TYPEOF, TYPE_REF, FUNCTION:
+		return true
+	}
+
+	for _, t := range builtinTypes {
+		if string(t.TypeID()) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 func IsNumber(t Type) bool {
-	switch t.TypeID() {
+	switch Underlying(t).TypeID() {
 	case UINT64:
 		fallthrough
 	case UINT32:
@@ -450,3 +1131,43 @@ func IsNumber(t Type) bool {
 
 	return false
 }
+
+// Returns whether a value of type from can be explicitly cast to type to.
+// Unsigned integers can be cast between each other and enums to and from their backing type.
+// Named types can be cast to and from anything their underlying type can.
+func CanCast(from Type, to Type) bool {
+	if from.Equals(to) {
+		return true
+	}
+
+	if _, isNamed := from.(Named); isNamed {
+		return CanCast(Underlying(from), to)
+	}
+
+	if _, isNamed := to.(Named); isNamed {
+		return CanCast(from, Underlying(to))
+	}
+
+	if IsNumber(from) && IsNumber(to) && to.TypeID() != UINT_LIT {
+		return true
+	}
+
+	if enum, isEnum := from.(Enum); isEnum {
+		return to.TypeID() == enum.Backing.TypeID()
+	}
+
+	if enum, isEnum := to.(Enum); isEnum {
+		return from.TypeID() == enum.Backing.TypeID() || from.TypeID() == UINT_LIT
+	}
+
+	return false
+}
+
+// Returns the largest value an unsigned integer of the given size in bytes can hold.
+func MaxUint(size int) uint64 {
+	if size >= 8 {
+		return ^uint64(0)
+	}
+
+	return (uint64(1) << (size * 8)) - 1
+}
@@ -0,0 +1,70 @@
+package semantics_test
+
+import (
+	"clovis/diagnostics"
+	"clovis/parser"
+	"strings"
+	"testing"
+)
+
+func TestUnionConstruction(t *testing.T) {
+	stmts := check(t, `
+		union Shape { Circle(uint64), Rect(uint32, uint32), Empty }
+		Shape a = Shape.Circle(3);
+		Shape b = Shape.Rect(1, 2);
+		Shape c = Shape.Empty();
+		Shape d = Shape.Empty;
+		a = Shape.Empty;
+	`)
+
+	variant := stmts[4].(*parser.VarDeclStmt).Right.Value().(*parser.MemberExpression)
+	if !variant.Variant || variant.Value != 2 {
+		t.Errorf("Expected Shape.Empty to be the variant with tag 2 but received %+v", variant)
+	}
+}
+
+func TestUnionConstructionErrors(t *testing.T) {
+	const shape = `union Shape { Circle(uint64), Empty } `
+	expectCodes(t, shape + `Shape s = Shape.Square(1);`, diagnostics.UnknownMember)
+	expectCodes(t, shape + `Shape s = Shape.Square;`, diagnostics.UnknownMember)
+	expectCodes(t, shape + `Shape s = Shape.Circle(1, 2);`, diagnostics.ArgumentCount)
+	expectCodes(t, shape + `Shape s = Shape.Circle(true);`, diagnostics.TypeMismatch)
+	expectCodes(t, shape + `Shape s; bool b = s == Shape.Circle(1);`, diagnostics.Misplaced)
+
+	d := expectCodes(t, shape + `Shape s = Shape.Circle;`, diagnostics.ArgumentCount)
+	if len(d[0].Hints) == 0 || !strings.Contains(d[0].Hints[0], "Shape.Circle(...)") {
+		t.Errorf("Expected a hint to pass the fields but received %v", d[0].Hints)
+	}
+}
+
+func TestMatchArms(t *testing.T) {
+	stmts := check(t, `
+		union Shape { Circle(uint64), Rect(uint32, uint32), Empty }
+		Shape s = Shape.Rect(2, 3);
+		uint64 area;
+		match s {
+			case Circle(r): area = r * r * 3;
+			case Rect(w, _): area = w as uint64;
+			case Empty: area = 0;
+		}
+	`)
+
+	stmt := stmts[3].(*parser.MatchStmt)
+	if len(stmt.Arms) != 3 || stmt.Arms[1].Tag != 1 || len(stmt.Arms[1].Symbols) != 1 {
+		t.Errorf("Expected the Rect arm to bind w only but received %v", stmt.Print(0))
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	const shape = `union Shape { Circle(uint64), Empty } Shape s = Shape.Empty; `
+	d := expectCodes(t, shape + `match s { case Circle(r): s = Shape.Empty; }`, diagnostics.InvalidArms)
+	if !strings.Contains(d[0].Message, "missing Empty") {
+		t.Errorf("Expected the missing variant in %q", d[0].Message)
+	}
+
+	expectCodes(t, shape + `match s { case Circle(r): s = s; case Circle(q): s = s; default: s = s; }`, diagnostics.InvalidArms)
+	expectCodes(t, shape + `match s { case Circle: s = s; default: s = s; }`, diagnostics.ArgumentCount)
+	expectCodes(t, shape + `match s { case Square: s = s; default: s = s; }`, diagnostics.UnknownMember)
+	expectCodes(t, `uint8 x; match x { default: x = 1; }`, diagnostics.InvalidOperand)
+	expectValid(t, shape + `match s { case Circle(r): s = s; default: s = s; }`)
+}